@@ -0,0 +1,292 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	mssqldb "github.com/microsoft/go-mssqldb"
+)
+
+// whoQuery 列出用户会话及其当前请求，kill 和 blockers 命令也基于它
+const whoQuery = `SELECT CASE WHEN s.session_id = @@SPID THEN '*' ELSE '' END AS [me], s.session_id AS [spid],
+	s.login_name AS [login], s.host_name AS [host],
+	CASE WHEN LEN(s.program_name) > 30 THEN LEFT(s.program_name, 27) + '...' ELSE s.program_name END AS [program],
+	DB_NAME(COALESCE(r.database_id, s.database_id)) AS [database],
+	COALESCE(r.status, s.status) AS [status], r.command, r.wait_type,
+	NULLIF(r.blocking_session_id, 0) AS [blocked_by],
+	s.cpu_time AS [cpu], s.reads, s.writes, s.last_request_start_time AS [last_batch]
+FROM sys.dm_exec_sessions AS s
+LEFT JOIN sys.dm_exec_requests AS r ON r.session_id = s.session_id
+WHERE s.is_user_process = 1`
+
+// showWho 处理 who [active] 命令，显示用户会话，active 只显示正在执行请求的会话，当前会话以 * 标记
+func (c *CLI) showWho(arg string) {
+	query := whoQuery
+	switch strings.ToLower(arg) {
+	case "":
+	case "active":
+		query += " AND r.session_id IS NOT NULL"
+	default:
+		fmt.Fprintf(c.term, "Usage: who [active]\n")
+		return
+	}
+	c.runCatalogQuery(query + "\nORDER BY s.session_id")
+}
+
+// blockerThreshold 直接或间接阻塞的会话达到此数量的头阻塞者高亮显示
+const blockerThreshold = 5
+
+// blockedSession 阻塞链中的一个会话
+type blockedSession struct {
+	spid      int
+	blockedBy int
+	login     string
+	host      string
+	status    string
+	waitTime  int64
+	waitType  string
+	resource  string
+	statement string
+	locks     int64
+}
+
+// blockersQuery 查询被阻塞的会话以及阻塞它们的会话；空闲的阻塞者没有请求，语句取自连接最近执行的批处理
+const blockersQuery = `WITH involved AS (
+	SELECT session_id FROM sys.dm_exec_requests WHERE blocking_session_id <> 0
+	UNION
+	SELECT blocking_session_id FROM sys.dm_exec_requests WHERE blocking_session_id <> 0
+)
+SELECT s.session_id, COALESCE(r.blocking_session_id, 0), s.login_name, COALESCE(s.host_name, ''),
+	COALESCE(r.status, s.status), COALESCE(r.wait_time, 0), COALESCE(r.wait_type, ''), COALESCE(r.wait_resource, ''),
+	COALESCE(CASE WHEN r.sql_handle IS NOT NULL THEN SUBSTRING(t.text, r.statement_start_offset / 2 + 1,
+		CASE r.statement_end_offset WHEN -1 THEN LEN(t.text) ELSE (r.statement_end_offset - r.statement_start_offset) / 2 + 1 END)
+	ELSE t.text END, ''),
+	(SELECT COUNT_BIG(*) FROM sys.dm_tran_locks AS l WHERE l.request_session_id = s.session_id AND l.request_status = 'GRANT')
+FROM involved AS i
+JOIN sys.dm_exec_sessions AS s ON s.session_id = i.session_id
+LEFT JOIN sys.dm_exec_requests AS r ON r.session_id = s.session_id
+LEFT JOIN sys.dm_exec_connections AS c ON c.session_id = s.session_id
+OUTER APPLY sys.dm_exec_sql_text(COALESCE(r.sql_handle, c.most_recent_sql_handle)) AS t
+ORDER BY s.session_id`
+
+// showBlockers 处理 blockers 命令，在客户端构建阻塞树并缩进显示：头阻塞者在顶层，被阻塞的会话
+// 缩进在其下并显示等待时间和等待资源；循环等待（正在形成的死锁）单独标出
+func (c *CLI) showBlockers(arg string) {
+	if arg != "" {
+		fmt.Fprintf(c.term, "Usage: blockers\n")
+		return
+	}
+	ctx, cancel := c.statementContext()
+	defer cancel()
+	defer c.flushOutput()
+
+	rows, err := c.conn.QueryContext(ctx, blockersQuery)
+	if err != nil {
+		c.printError(err)
+		return
+	}
+	defer rows.Close()
+
+	sessions := make(map[int]*blockedSession)
+	var order []int
+	for rows.Next() {
+		s := &blockedSession{}
+		if err := rows.Scan(&s.spid, &s.blockedBy, &s.login, &s.host, &s.status, &s.waitTime,
+			&s.waitType, &s.resource, &s.statement, &s.locks); err != nil {
+			c.printError(err)
+			return
+		}
+		sessions[s.spid] = s
+		order = append(order, s.spid)
+	}
+	if err := rows.Err(); err != nil {
+		c.printError(err)
+		return
+	}
+
+	endPaging := c.beginPaging()
+	defer endPaging()
+	c.outMu.Lock()
+	defer c.outMu.Unlock()
+
+	if len(sessions) == 0 {
+		fmt.Fprintf(c.out, "No blocking.\n\n")
+		return
+	}
+
+	children := make(map[int][]int)
+	for _, spid := range order {
+		if b := sessions[spid].blockedBy; b != 0 {
+			children[b] = append(children[b], spid)
+		}
+	}
+
+	visited := make(map[int]bool)
+	var printTree func(spid, depth int)
+	printTree = func(spid, depth int) {
+		visited[spid] = true
+		fmt.Fprintf(c.out, "%s%s\n", strings.Repeat("  ", depth), c.blockerLine(sessions[spid], depth, countBlocked(spid, children)))
+		for _, child := range children[spid] {
+			if !visited[child] {
+				printTree(child, depth+1)
+			}
+		}
+	}
+	for _, spid := range order {
+		if s := sessions[spid]; s.blockedBy == 0 || sessions[s.blockedBy] == nil {
+			printTree(spid, 0)
+		}
+	}
+
+	// 从头阻塞者无法到达的会话处于循环等待中或被循环中的会话阻塞，沿阻塞者向上找出循环
+	for _, spid := range order {
+		pos := make(map[int]int)
+		var path []int
+		for next := spid; sessions[next] != nil && !visited[next]; next = sessions[next].blockedBy {
+			if i, ok := pos[next]; ok {
+				cycle := path[i:]
+				names := make([]string, 0, len(cycle)+1)
+				for _, member := range cycle {
+					visited[member] = true
+					names = append(names, strconv.Itoa(member))
+				}
+				names = append(names, names[0])
+				fmt.Fprintf(c.out, "%s\n", c.display.colorize("Blocking cycle (deadlock in progress): "+strings.Join(names, " -> "), ansiRed))
+				for _, member := range cycle {
+					printTree(member, 0)
+				}
+				break
+			}
+			pos[next] = len(path)
+			path = append(path, next)
+		}
+	}
+	fmt.Fprintln(c.out)
+}
+
+// countBlocked 返回直接或间接被某个会话阻塞的会话数
+func countBlocked(spid int, children map[int][]int) int {
+	seen := map[int]bool{spid: true}
+	queue := append([]int(nil), children[spid]...)
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+		if seen[next] {
+			continue
+		}
+		seen[next] = true
+		queue = append(queue, children[next]...)
+	}
+	return len(seen) - 1
+}
+
+// blockerLine 格式化阻塞树中的一行：头阻塞者显示状态、持有的锁和阻塞数，被阻塞者显示等待信息
+func (c *CLI) blockerLine(s *blockedSession, depth, blocked int) string {
+	line := fmt.Sprintf("%d  %s", s.spid, s.login)
+	if s.host != "" {
+		line += "@" + s.host
+	}
+	if depth == 0 {
+		line += fmt.Sprintf("  %s, %d locks held, blocking %d", s.status, s.locks, blocked)
+		if blocked >= blockerThreshold {
+			line = c.display.colorize(line, ansiRed)
+		} else {
+			line = c.display.colorize(line, ansiBold)
+		}
+	} else {
+		line += fmt.Sprintf("  waiting %s on %s %s", time.Duration(s.waitTime)*time.Millisecond, s.waitType, s.resource)
+	}
+	if stmt := shortStatement(s.statement); stmt != "" {
+		line += "  " + c.display.colorize(stmt, ansiDim)
+	}
+	return line
+}
+
+// shortStatement 将语句压缩为一行并截断
+func shortStatement(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if len(text) > 80 {
+		text = text[:77] + "..."
+	}
+	return text
+}
+
+// killSession 处理 kill <spid> [--force] 命令：先显示目标会话的登录名、主机、程序和当前语句，
+// 确认后执行 KILL，再用 KILL WITH STATUSONLY 报告回滚进度；--force 跳过确认，供脚本使用
+func (c *CLI) killSession(arg string) {
+	fields := strings.Fields(arg)
+	force := len(fields) == 2 && fields[1] == "--force"
+	if len(fields) == 0 || len(fields) > 2 || (len(fields) == 2 && !force) {
+		fmt.Fprintf(c.term, "Usage: kill <spid> [--force]\n")
+		return
+	}
+	spid, err := strconv.Atoi(fields[0])
+	if err != nil || spid <= 0 {
+		fmt.Fprintf(c.term, "Usage: kill <spid> [--force]\n")
+		return
+	}
+	defer c.flushOutput()
+
+	var own int
+	var login, host, program, status, statement string
+	ctx, cancel := c.statementContext()
+	err = c.conn.QueryRowContext(ctx, `SELECT @@SPID, s.login_name, COALESCE(s.host_name, ''), COALESCE(s.program_name, ''),
+	COALESCE(r.status, s.status), COALESCE(t.text, '')
+FROM sys.dm_exec_sessions AS s
+LEFT JOIN sys.dm_exec_requests AS r ON r.session_id = s.session_id
+LEFT JOIN sys.dm_exec_connections AS c ON c.session_id = s.session_id
+OUTER APPLY sys.dm_exec_sql_text(COALESCE(r.sql_handle, c.most_recent_sql_handle)) AS t
+WHERE s.session_id = @spid`, sql.Named("spid", spid)).Scan(&own, &login, &host, &program, &status, &statement)
+	cancel()
+	switch {
+	case err == sql.ErrNoRows:
+		fmt.Fprintf(c.out, "Session %d not found.\n\n", spid)
+		return
+	case err != nil:
+		c.printError(err)
+		return
+	case own == spid:
+		fmt.Fprintf(c.out, "%s\n\n", c.display.colorize("Cannot kill your own session; use 'reconnect' or 'exit' instead.", ansiRed))
+		return
+	}
+
+	// 会话详情用于确认，直接输出到终端
+	fmt.Fprintf(c.term, "Session %d: %s@%s, %s, %s\n", spid, login, host, program, status)
+	if stmt := shortStatement(statement); stmt != "" {
+		fmt.Fprintf(c.term, "  %s\n", c.display.colorize(stmt, ansiDim))
+	}
+	if !force && !c.confirm(fmt.Sprintf("Kill session %d?", spid)) {
+		fmt.Fprintf(c.term, "Cancelled.\n")
+		return
+	}
+
+	// KILL 不接受变量，spid 已解析为整数
+	ctx, cancel = c.statementContext()
+	defer cancel()
+	if _, err := c.conn.ExecContext(ctx, "KILL "+strconv.Itoa(spid)); err != nil {
+		c.printError(err)
+		return
+	}
+
+	// 回滚进度以信息消息返回；会话已结束时报告 Msg 6106 或 6120，均视为已终止
+	var progress []string
+	statusCtx := context.WithValue(ctx, messageSinkKey{}, func(msg string) {
+		progress = append(progress, msg)
+	})
+	_, err = c.conn.ExecContext(statusCtx, "KILL "+strconv.Itoa(spid)+" WITH STATUSONLY")
+	var sqlErr mssqldb.Error
+	switch {
+	case err == nil && len(progress) > 0:
+		fmt.Fprintf(c.out, "Session %d killed; %s\n\n", spid, strings.Join(progress, " "))
+	case err == nil, errors.As(err, &sqlErr) && (sqlErr.Number == 6106 || sqlErr.Number == 6120):
+		fmt.Fprintf(c.out, "Session %d killed.\n\n", spid)
+	default:
+		fmt.Fprintf(c.out, "Session %d killed; rollback status unavailable.\n", spid)
+		c.printError(err)
+	}
+}
@@ -0,0 +1,215 @@
+package mssql
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// goPattern 匹配批处理分隔符行 GO [count]
+var goPattern = regexp.MustCompile(`(?i)^\s*GO(?:\s+(\S.*?))?\s*$`)
+
+// parseGo 解析批处理分隔符行，返回是否为分隔符和重复次数；GO 后面不是正整数时返回错误
+func parseGo(line string) (ok bool, count int, err error) {
+	m := goPattern.FindStringSubmatch(line)
+	if m == nil {
+		return false, 0, nil
+	}
+	if m[1] == "" {
+		return true, 1, nil
+	}
+	count, err = strconv.Atoi(m[1])
+	if err != nil || count < 1 {
+		return true, 0, fmt.Errorf("invalid batch separator '%s': GO takes an optional positive repeat count, e.g. GO 10", strings.TrimSpace(line))
+	}
+	return true, count, nil
+}
+
+// repeatBatch 将批处理执行 count 次（GO <count>），各次的结果不输出，定期显示进度并在结束时汇总影响的行数和耗时；
+// 出错时停止并返回该错误，Ctrl+C 在当前这次执行完成后停止
+func (c *CLI) repeatBatch(sqlStr string, count int) error {
+	defer c.flushOutput()
+
+	sqlStr, err := c.expandVariables(sqlStr)
+	if err != nil {
+		c.printError(err)
+		return err
+	}
+	params, ok := c.bindParams(sqlStr)
+	if !ok {
+		return errParamsCancelled
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+	defer signal.Stop(sigs)
+
+	interval := count / 10
+	if interval < 1 {
+		interval = 1
+	}
+	startTime := time.Now()
+	var affected int64
+	var lastErr error
+	done := 0
+	for done < count {
+		ctx, cancel := c.statementContext()
+		result, err := c.conn.ExecContext(ctx, sqlStr, params...)
+		cancel()
+		if err != nil {
+			c.printError(err)
+			lastErr = err
+			break
+		}
+		if n, err := result.RowsAffected(); err == nil {
+			affected += n
+			c.recordRowsAffected(n)
+		}
+		if done == 0 {
+			c.trackSession(sqlStr)
+		}
+		done++
+
+		select {
+		case <-sigs:
+			fmt.Fprintf(c.term, "Interrupted.\n")
+			count = done
+		default:
+		}
+		if count >= 10 && done%interval == 0 && done < count && c.showInfo() {
+			fmt.Fprintf(c.term, "%s\n", c.display.colorize(fmt.Sprintf("-- %d/%d iterations", done, count), ansiDim))
+		}
+	}
+
+	if !c.showInfo() {
+		return lastErr
+	}
+	elapsed := time.Since(startTime)
+	summary := fmt.Sprintf("Batch executed %s times, %s rows affected", groupDigits(strconv.Itoa(done)), groupDigits(strconv.FormatInt(affected, 10)))
+	if c.timingEnabled && done > 0 {
+		summary += fmt.Sprintf(" (%s total, %s per iteration)", elapsed.Round(time.Millisecond), (elapsed / time.Duration(done)).Round(time.Microsecond))
+	}
+	fmt.Fprintf(c.out, "%s\n\n", summary)
+	return lastErr
+}
+
+// sqlLexer 逐行跟踪 T-SQL 的词法状态，用于判断一行的开头或结尾是否位于字符串、带引号的标识符或注释之中
+type sqlLexer struct {
+	quote        byte // 未闭合的 '、" 或 [，0 表示不在其中
+	commentDepth int  // 未闭合的 /* */ 注释层数，T-SQL 的块注释可以嵌套
+}
+
+// inside 判断当前位置是否位于字符串、带引号的标识符或块注释之中
+func (l *sqlLexer) inside() bool {
+	return l.quote != 0 || l.commentDepth > 0
+}
+
+// scan 扫描一行并更新状态，返回将字符串、标识符和注释内容替换为空格后的代码
+func (l *sqlLexer) scan(line string) string {
+	return l.blank(line, true)
+}
+
+// scanComments 与 scan 相同，但只替换注释，字符串和标识符的内容保持原样
+func (l *sqlLexer) scanComments(line string) string {
+	return l.blank(line, false)
+}
+
+// blank 扫描一行并更新状态，将注释替换为空格，quoted 为 true 时字符串和标识符的内容也替换
+func (l *sqlLexer) blank(line string, quoted bool) string {
+	code := []byte(line)
+	for i := 0; i < len(code); i++ {
+		ch := code[i]
+		var next byte
+		if i+1 < len(code) {
+			next = code[i+1]
+		}
+		switch {
+		case l.commentDepth > 0:
+			code[i] = ' '
+			switch {
+			case ch == '/' && next == '*':
+				l.commentDepth++
+				code[i+1] = ' '
+				i++
+			case ch == '*' && next == '/':
+				l.commentDepth--
+				code[i+1] = ' '
+				i++
+			}
+		case l.quote != 0:
+			closer := l.quote
+			if closer == '[' {
+				closer = ']'
+			}
+			if ch == closer {
+				// 重复的结束符是转义
+				if next == closer {
+					if quoted {
+						code[i], code[i+1] = ' ', ' '
+					}
+					i++
+					continue
+				}
+				l.quote = 0
+				continue
+			}
+			if quoted {
+				code[i] = ' '
+			}
+		case ch == '-' && next == '-':
+			for j := i; j < len(code); j++ {
+				code[j] = ' '
+			}
+			return string(code)
+		case ch == '/' && next == '*':
+			l.commentDepth++
+			code[i], code[i+1] = ' ', ' '
+			i++
+		case ch == '\'' || ch == '"' || ch == '[':
+			l.quote = ch
+		}
+	}
+	return string(code)
+}
+
+// moduleStartPattern 匹配必须单独成为一个批处理的 CREATE/ALTER 语句
+var moduleStartPattern = regexp.MustCompile(`(?is)^\s*(?:CREATE|ALTER|CREATE\s+OR\s+ALTER)\s+(?:PROC|PROCEDURE|FUNCTION|TRIGGER|VIEW)\b`)
+
+// declareVariablePattern 在代码中匹配变量声明
+var declareVariablePattern = regexp.MustCompile(`(?i)\bDECLARE\s+@`)
+
+// blockPattern 匹配块的开始和结束关键字，nextWordPattern 匹配其后的一个单词
+var (
+	blockPattern    = regexp.MustCompile(`(?i)\b(?:BEGIN|CASE|END)\b`)
+	nextWordPattern = regexp.MustCompile(`^\s+(\w+)`)
+)
+
+// needsGo 判断已输入的代码是否只能以 GO 结束：行尾的分号不结束变量作用于整个批处理的语句、
+// 存储过程等模块的定义以及未闭合的 BEGIN ... END 块
+func needsGo(code string) bool {
+	if moduleStartPattern.MatchString(code) || declareVariablePattern.MatchString(code) {
+		return true
+	}
+	depth := 0
+	for _, loc := range blockPattern.FindAllStringIndex(code, -1) {
+		keyword := strings.ToUpper(code[loc[0]:loc[1]])
+		var next string
+		if m := nextWordPattern.FindStringSubmatch(code[loc[1]:]); m != nil {
+			next = strings.ToUpper(m[1])
+		}
+		switch {
+		case keyword == "BEGIN" && (next == "TRAN" || next == "TRANSACTION" || next == "DISTRIBUTED" ||
+			next == "DIALOG" || next == "CONVERSATION"):
+		case keyword == "END" && next == "CONVERSATION":
+		case keyword == "END":
+			depth--
+		default:
+			depth++
+		}
+	}
+	return depth > 0
+}
@@ -0,0 +1,33 @@
+package mssql
+
+import "testing"
+
+func TestParseGo(t *testing.T) {
+	tests := []struct {
+		line      string
+		wantOK    bool
+		wantCount int
+		wantErr   bool
+	}{
+		{line: "GO", wantOK: true, wantCount: 1},
+		{line: "go", wantOK: true, wantCount: 1},
+		{line: "  GO  ", wantOK: true, wantCount: 1},
+		{line: "GO 5", wantOK: true, wantCount: 5},
+		{line: "go 5 ", wantOK: true, wantCount: 5},
+		{line: "Go\t12", wantOK: true, wantCount: 12},
+		{line: "GO 5x", wantOK: true, wantErr: true},
+		{line: "GO -1", wantOK: true, wantErr: true},
+		{line: "GO 0", wantOK: true, wantErr: true},
+		{line: "GO 1 2", wantOK: true, wantErr: true},
+		{line: "GOTO done", wantOK: false},
+		{line: "GO;", wantOK: false},
+		{line: "SELECT 1 GO", wantOK: false},
+		{line: "", wantOK: false},
+	}
+	for _, tt := range tests {
+		ok, count, err := parseGo(tt.line)
+		if ok != tt.wantOK || (err != nil) != tt.wantErr || (!tt.wantErr && count != tt.wantCount) {
+			t.Errorf("parseGo(%q) = %v, %d, %v; want %v, %d, error %v", tt.line, ok, count, err, tt.wantOK, tt.wantCount, tt.wantErr)
+		}
+	}
+}
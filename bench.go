@@ -0,0 +1,191 @@
+package mssql
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// benchUsage bench 命令的用法
+const benchUsage = "Usage: bench [-w <warmup>] [-s] <N> <statement>\n"
+
+// benchRun 执行一次语句并丢弃结果，返回返回的行数和从发送请求到读完所有结果集的耗时
+func (c *CLI) benchRun(ctx context.Context, sqlStr string, params []interface{}) (int64, time.Duration, error) {
+	start := time.Now()
+	rows, err := c.conn.QueryContext(ctx, sqlStr, params...)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer rows.Close()
+	var n int64
+	for {
+		for rows.Next() {
+			n++
+		}
+		if !rows.NextResultSet() {
+			break
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, 0, err
+	}
+	return n, time.Since(start), nil
+}
+
+// percentile 返回已排序耗时中第 p 百分位的值（最近秩法）
+func percentile(sorted []time.Duration, p int) time.Duration {
+	i := (len(sorted)*p + 99) / 100
+	if i < 1 {
+		i = 1
+	}
+	return sorted[i-1]
+}
+
+// benchCommand 处理 bench [-w <warmup>] [-s] <N> <statement> 命令：先执行 warmup 次不计时的预热，再执行 N 次并
+// 统计最小、最大、平均、中位数和 p95 耗时、每次返回的行数和总耗时。结果不显示，只读取完所有行，计时只包含
+// 服务器往返；-s 在计时结束后再执行一次并正常显示结果。Ctrl+C 中止当前执行，只统计已完成的次数
+func (c *CLI) benchCommand(arg string) {
+	// next 取出 arg 中的下一个单词，语句部分保留原有的换行和空白
+	next := func() string {
+		arg = strings.TrimLeft(arg, " \t\r\n")
+		i := strings.IndexAny(arg, " \t\r\n")
+		if i < 0 {
+			i = len(arg)
+		}
+		word := arg[:i]
+		arg = arg[i:]
+		return word
+	}
+	warmup, show := 0, false
+	word := next()
+	for strings.HasPrefix(word, "-") {
+		switch word {
+		case "-s":
+			show = true
+		case "-w":
+			n, err := strconv.Atoi(next())
+			if err != nil || n < 0 {
+				fmt.Fprint(c.term, benchUsage)
+				return
+			}
+			warmup = n
+		default:
+			fmt.Fprint(c.term, benchUsage)
+			return
+		}
+		word = next()
+	}
+	count, err := strconv.Atoi(word)
+	sqlStr := strings.TrimSpace(arg)
+	if err != nil || count < 1 || sqlStr == "" {
+		fmt.Fprint(c.term, benchUsage)
+		return
+	}
+	sqlStr, err = c.expandVariables(sqlStr)
+	if err != nil {
+		c.printError(err)
+		return
+	}
+	params, ok := c.bindParams(sqlStr)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+	defer signal.Stop(sigs)
+	go func() {
+		select {
+		case <-sigs:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	// run 在语句超时和 Ctrl+C 两者的限制下执行一次
+	run := func() (int64, time.Duration, error) {
+		runCtx, runCancel := c.statementContext()
+		defer runCancel()
+		stop := context.AfterFunc(ctx, runCancel)
+		defer stop()
+		return c.benchRun(runCtx, sqlStr, params)
+	}
+
+	startTime := time.Now()
+	for i := 0; i < warmup && ctx.Err() == nil; i++ {
+		if _, _, err := run(); err != nil {
+			if ctx.Err() == nil {
+				c.printError(err)
+			}
+			return
+		}
+	}
+
+	var times []time.Duration
+	var rowCounts []int64
+	for len(times) < count && ctx.Err() == nil {
+		n, d, err := run()
+		if err != nil {
+			if ctx.Err() == nil {
+				c.printError(err)
+				return
+			}
+			break
+		}
+		times = append(times, d)
+		rowCounts = append(rowCounts, n)
+	}
+	elapsed := time.Since(startTime)
+	if ctx.Err() != nil {
+		fmt.Fprintf(c.term, "Interrupted after %d of %d runs.\n", len(times), count)
+	}
+	defer c.flushOutput()
+	if len(times) == 0 {
+		return
+	}
+	c.benchReport(times, rowCounts, warmup, elapsed)
+
+	if show && ctx.Err() == nil {
+		c.runStatement(sqlStr, time.Now(), c.expanded, params...)
+	}
+}
+
+// benchReport 输出 bench 的统计结果
+func (c *CLI) benchReport(times []time.Duration, rowCounts []int64, warmup int, elapsed time.Duration) {
+	var total time.Duration
+	for _, d := range times {
+		total += d
+	}
+	sorted := append([]time.Duration(nil), times...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	minRows, maxRows := rowCounts[0], rowCounts[0]
+	for _, n := range rowCounts {
+		minRows = min(minRows, n)
+		maxRows = max(maxRows, n)
+	}
+	rowsText := groupDigits(strconv.FormatInt(minRows, 10))
+	if maxRows != minRows {
+		rowsText += " - " + groupDigits(strconv.FormatInt(maxRows, 10))
+	}
+
+	round := func(d time.Duration) interface{} { return d.Round(time.Microsecond).String() }
+	data := [][]interface{}{{len(times), round(sorted[0]), round(sorted[len(sorted)-1]),
+		round(total / time.Duration(len(times))), round(percentile(sorted, 50)), round(percentile(sorted, 95)), rowsText}}
+	c.renderValues([]string{"runs", "min", "max", "mean", "median", "p95", "rows_per_run"}, data, time.Now())
+
+	if c.showInfo() {
+		summary := fmt.Sprintf("Total elapsed %s", elapsed.Round(time.Millisecond))
+		if warmup > 0 {
+			summary += fmt.Sprintf(", including %d warm-up runs", warmup)
+		}
+		fmt.Fprintf(c.out, "%s\n\n", summary)
+	}
+}
@@ -0,0 +1,392 @@
+package mssql
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// catalogCommands 查看数据库对象的命令，参数为命令名之后的内容
+var catalogCommands = map[string]func(c *CLI, arg string){
+	"tables":     (*CLI).listTables,
+	`\dt`:        (*CLI).listTables,
+	"desc":       (*CLI).describeObject,
+	"databases":  (*CLI).listDatabases,
+	"views":      (*CLI).listViews,
+	"viewdef":    (*CLI).showDefinition,
+	"def":        (*CLI).showDefinition,
+	"find":       (*CLI).findObjects,
+	"schemas":    (*CLI).listSchemas,
+	"sizes":      (*CLI).listSizes,
+	"dbsize":     (*CLI).showDatabaseSize,
+	"users":      (*CLI).listUsers,
+	"roles":      (*CLI).listRoles,
+	"logins":     (*CLI).listLogins,
+	"who":        (*CLI).showWho,
+	"blockers":   (*CLI).showBlockers,
+	"kill":       (*CLI).killSession,
+	"indexstats": (*CLI).showIndexStats,
+	"setopts":    (*CLI).showSetOptions,
+	"triggers":   (*CLI).listTriggers,
+	"jobs":       (*CLI).listJobs,
+	"jobhistory": (*CLI).showJobHistory,
+	"dbinfo":     (*CLI).showDatabaseInfo,
+	"temptables": (*CLI).listTempTables,
+	"tempdb":     (*CLI).showTempdb,
+	`\dn`:        (*CLI).listSchemas,
+	"procs":      (*CLI).listProcedures,
+	"funcs":      (*CLI).listFunctions,
+	"indexes":    (*CLI).listIndexes,
+	"fks":        (*CLI).listForeignKeys,
+	"script":     (*CLI).scriptTable,
+	`\l`:         (*CLI).listDatabases,
+	`\d`:         (*CLI).describeObject,
+}
+
+// handleCatalogCommand 处理查看数据库对象的命令，返回是否已处理
+func (c *CLI) handleCatalogCommand(cmd string) bool {
+	name, arg, _ := strings.Cut(strings.TrimSpace(cmd), " ")
+	run, ok := catalogCommands[strings.ToLower(name)]
+	if !ok {
+		return false
+	}
+	run(c, strings.TrimSpace(arg))
+	return true
+}
+
+// catalogArgs 拆分命令参数中的 -x 选项和其余的参数
+func catalogArgs(arg string) (flags map[string]bool, rest []string) {
+	flags = make(map[string]bool)
+	for _, field := range strings.Fields(arg) {
+		if len(field) > 1 && field[0] == '-' {
+			flags[strings.ToLower(field[1:])] = true
+			continue
+		}
+		rest = append(rest, field)
+	}
+	return flags, rest
+}
+
+// namePattern 将 [schema.]name 形式的 LIKE 模式拆分为架构和名称模式，省略的部分匹配全部
+func namePattern(pattern string) (schema, name string) {
+	schema, name = "%", "%"
+	if pattern == "" {
+		return schema, name
+	}
+	if s, n, ok := strings.Cut(pattern, "."); ok {
+		if s != "" {
+			schema = s
+		}
+		if n != "" {
+			name = n
+		}
+		return schema, name
+	}
+	return schema, pattern
+}
+
+// runCatalogQuery 执行查看数据库对象的查询，结果按当前输出格式显示
+func (c *CLI) runCatalogQuery(query string, args ...interface{}) {
+	startTime := time.Now()
+	ctx, cancel := c.statementContext()
+	defer cancel()
+	defer c.flushOutput()
+	c.executeQuery(ctx, query, startTime, c.expanded, args...)
+}
+
+// listTables 处理 tables [-v] [[schema.]pattern] 命令，列出当前数据库中的表，-v 同时列出视图
+// 模式使用 LIKE 语法，不区分大小写
+func (c *CLI) listTables(arg string) {
+	flags, rest := catalogArgs(arg)
+	if len(rest) > 1 {
+		fmt.Fprintf(c.term, "Usage: tables [-v] [[schema.]pattern]\n")
+		return
+	}
+	schema, name := namePattern(strings.Join(rest, ""))
+	types := "'U'"
+	if flags["v"] {
+		types = "'U', 'V'"
+	}
+	c.runCatalogQuery(`SELECT s.name AS [schema], o.name,
+	CASE o.type WHEN 'U' THEN 'table' ELSE 'view' END AS [type],
+	(SELECT SUM(p.rows) FROM sys.partitions AS p
+		WHERE p.object_id = o.object_id AND p.index_id IN (0, 1)) AS [rows],
+	o.create_date AS created, o.modify_date AS modified
+FROM sys.objects AS o
+JOIN sys.schemas AS s ON s.schema_id = o.schema_id
+WHERE o.type IN (`+types+`) AND o.is_ms_shipped = 0
+	AND LOWER(s.name) LIKE LOWER(@schema) AND LOWER(o.name) LIKE LOWER(@name)
+ORDER BY s.name, o.name`, sql.Named("schema", schema), sql.Named("name", name))
+}
+
+// listDatabases 处理 databases [pattern] 命令，列出数据库的状态、恢复模式、兼容级别、排序规则和大小，
+// 当前数据库以 * 标记；没有权限查看文件的数据库大小显示为 NULL
+func (c *CLI) listDatabases(arg string) {
+	_, rest := catalogArgs(arg)
+	if len(rest) > 1 {
+		fmt.Fprintf(c.term, "Usage: databases [pattern]\n")
+		return
+	}
+	pattern := "%"
+	if len(rest) == 1 {
+		pattern = rest[0]
+	}
+	c.runCatalogQuery(`SELECT CASE WHEN d.name = DB_NAME() THEN '*' ELSE '' END AS [current], d.name,
+	d.state_desc AS [state], d.recovery_model_desc AS [recovery_model],
+	d.compatibility_level, d.collation_name AS [collation],
+	CAST(SUM(CAST(mf.size AS bigint)) * 8 / 1024.0 AS decimal(18, 2)) AS [size_mb]
+FROM sys.databases AS d
+LEFT JOIN sys.master_files AS mf ON mf.database_id = d.database_id
+WHERE LOWER(d.name) LIKE LOWER(@name)
+GROUP BY d.name, d.state_desc, d.recovery_model_desc, d.compatibility_level, d.collation_name
+ORDER BY d.name`, sql.Named("name", pattern))
+}
+
+// viewsQuery 列出视图的查询，@schema、@name 为 LIKE 模式
+const viewsQuery = `SELECT s.name AS [schema], v.name,
+	CASE OBJECTPROPERTY(v.object_id, 'IsSchemaBound') WHEN 1 THEN 'YES' ELSE 'NO' END AS [schemabound],
+	v.create_date AS created, v.modify_date AS modified
+FROM sys.views AS v
+JOIN sys.schemas AS s ON s.schema_id = v.schema_id
+WHERE v.is_ms_shipped = 0
+	AND LOWER(s.name) LIKE LOWER(@schema) AND LOWER(v.name) LIKE LOWER(@name)
+ORDER BY s.name, v.name`
+
+// listViews 处理 views [-d] [[schema.]pattern] 命令，列出当前数据库中的视图，-d 同时输出视图定义
+func (c *CLI) listViews(arg string) {
+	flags, rest := catalogArgs(arg)
+	if len(rest) > 1 {
+		fmt.Fprintf(c.term, "Usage: views [-d] [[schema.]pattern]\n")
+		return
+	}
+	schema, name := namePattern(strings.Join(rest, ""))
+	c.runCatalogQuery(viewsQuery, sql.Named("schema", schema), sql.Named("name", name))
+	if !flags["d"] {
+		return
+	}
+
+	ctx, cancel := c.statementContext()
+	defer cancel()
+	defer c.flushOutput()
+	data, err := c.queryValues(ctx, `SELECT s.name, v.name, OBJECT_DEFINITION(v.object_id)
+FROM sys.views AS v
+JOIN sys.schemas AS s ON s.schema_id = v.schema_id
+WHERE v.is_ms_shipped = 0
+	AND LOWER(s.name) LIKE LOWER(@schema) AND LOWER(v.name) LIKE LOWER(@name)
+ORDER BY s.name, v.name`, sql.Named("schema", schema), sql.Named("name", name))
+	if err != nil {
+		c.printError(err)
+		return
+	}
+	for _, row := range data {
+		c.printDefinition(fmt.Sprintf("%v.%v", row[0], row[1]), row[2], false)
+	}
+}
+
+// showDefinition 处理 def [-n] <name> 命令（别名 viewdef），输出视图、存储过程、函数或触发器的定义，
+// -n 显示行号，便于定位错误信息中的行
+func (c *CLI) showDefinition(arg string) {
+	flags, rest := catalogArgs(arg)
+	if len(rest) != 1 {
+		fmt.Fprintf(c.term, "Usage: def [-n] <[schema.]name>\n")
+		return
+	}
+	ctx, cancel := c.statementContext()
+	defer cancel()
+	defer c.flushOutput()
+
+	obj, err := c.resolveObject(ctx, rest[0])
+	if err != nil {
+		c.printError(err)
+		return
+	}
+	var definition sql.NullString
+	var encrypted sql.NullInt64
+	if err := c.conn.QueryRowContext(ctx, "SELECT OBJECT_DEFINITION(@id), OBJECTPROPERTY(@id, 'IsEncrypted')",
+		sql.Named("id", obj.id)).Scan(&definition, &encrypted); err != nil {
+		c.printError(err)
+		return
+	}
+	switch {
+	case definition.Valid:
+		c.printDefinition(obj.fullName(), definition.String, flags["n"])
+	case encrypted.Int64 == 1:
+		c.printError(fmt.Errorf("the definition of %s is encrypted", obj.fullName()))
+	default:
+		c.printError(fmt.Errorf("%s has no definition (not a module, or no VIEW DEFINITION permission)", obj.fullName()))
+	}
+}
+
+// printDefinition 原样输出对象定义，保留原有的换行，不经过格式化器以免被截断；
+// 较长的定义按 pager/more 设置分页
+func (c *CLI) printDefinition(name string, definition interface{}, numbered bool) {
+	text, ok := definition.(string)
+	if !ok {
+		text = "-- (definition not available)"
+	}
+	text = strings.TrimRight(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	if numbered {
+		lines := strings.Split(text, "\n")
+		width := len(strconv.Itoa(len(lines)))
+		for i, line := range lines {
+			lines[i] = fmt.Sprintf("%*d  %s", width, i+1, line)
+		}
+		text = strings.Join(lines, "\n")
+	}
+
+	endPaging := c.beginPaging()
+	defer endPaging()
+	c.outMu.Lock()
+	defer c.outMu.Unlock()
+	if c.display.headers && !c.display.quiet {
+		fmt.Fprintf(c.out, "%s\n", c.display.colorize("-- "+name, ansiDim))
+	}
+	fmt.Fprintf(c.out, "%s\n\n", text)
+}
+
+// moduleSource 返回列出程序对象时查询的目录视图和过滤条件，-s 时包括系统对象
+func moduleSource(flags map[string]bool) (view, filter string) {
+	if flags["s"] {
+		return "sys.all_objects", "1 = 1"
+	}
+	return "sys.objects", "o.is_ms_shipped = 0"
+}
+
+// listProcedures 处理 procs [-s] [[schema.]pattern] 命令，列出存储过程，-s 包括系统存储过程
+func (c *CLI) listProcedures(arg string) {
+	flags, rest := catalogArgs(arg)
+	if len(rest) > 1 {
+		fmt.Fprintf(c.term, "Usage: procs [-s] [[schema.]pattern]\n")
+		return
+	}
+	schema, name := namePattern(strings.Join(rest, ""))
+	view, filter := moduleSource(flags)
+	c.runCatalogQuery(`SELECT s.name AS [schema], o.name,
+	CASE o.type WHEN 'P' THEN 'sql' WHEN 'X' THEN 'extended' WHEN 'PC' THEN 'clr' ELSE o.type END AS [type],
+	o.create_date AS created, o.modify_date AS modified
+FROM `+view+` AS o
+JOIN sys.schemas AS s ON s.schema_id = o.schema_id
+WHERE o.type IN ('P', 'PC', 'X') AND `+filter+`
+	AND LOWER(s.name) LIKE LOWER(@schema) AND LOWER(o.name) LIKE LOWER(@name)
+ORDER BY s.name, o.name`, sql.Named("schema", schema), sql.Named("name", name))
+}
+
+// listFunctions 处理 funcs [-s] [[schema.]pattern] 命令，列出函数及其返回类型，-s 包括系统函数
+func (c *CLI) listFunctions(arg string) {
+	flags, rest := catalogArgs(arg)
+	if len(rest) > 1 {
+		fmt.Fprintf(c.term, "Usage: funcs [-s] [[schema.]pattern]\n")
+		return
+	}
+	schema, name := namePattern(strings.Join(rest, ""))
+	view, filter := moduleSource(flags)
+	c.runCatalogQuery(`SELECT s.name AS [schema], o.name,
+	CASE o.type WHEN 'FN' THEN 'scalar' WHEN 'IF' THEN 'inline table' WHEN 'TF' THEN 'table'
+		WHEN 'FS' THEN 'clr scalar' WHEN 'FT' THEN 'clr table' ELSE o.type END AS [type],
+	CASE WHEN o.type IN ('IF', 'TF', 'FT') THEN 'TABLE' ELSE
+		(SELECT `+sqlTypeExpr("p.user_type_id", "p.max_length", "p.precision", "p.scale")+`
+		FROM sys.all_parameters AS p WHERE p.object_id = o.object_id AND p.parameter_id = 0) END AS [returns],
+	o.create_date AS created, o.modify_date AS modified
+FROM `+view+` AS o
+JOIN sys.schemas AS s ON s.schema_id = o.schema_id
+WHERE o.type IN ('FN', 'IF', 'TF', 'FS', 'FT') AND `+filter+`
+	AND LOWER(s.name) LIKE LOWER(@schema) AND LOWER(o.name) LIKE LOWER(@name)
+ORDER BY s.name, o.name`, sql.Named("schema", schema), sql.Named("name", name))
+}
+
+// findTypes find --type 接受的对象类型
+var findTypes = map[string]string{
+	"table":     "table",
+	"view":      "view",
+	"proc":      "procedure",
+	"procedure": "procedure",
+	"func":      "function",
+	"function":  "function",
+	"trigger":   "trigger",
+	"column":    "column",
+}
+
+// findObjects 处理 find [--columns-only] [--type <type>] <pattern> 命令，按名称在表、视图、
+// 存储过程、函数、触发器和列中查找；模式不含 % 时按包含匹配
+func (c *CLI) findObjects(arg string) {
+	usage := "Usage: find [--columns-only] [--type table|view|proc|func|trigger|column] <pattern>\n"
+	var pattern string
+	var typeFilter interface{}
+	fields := strings.Fields(arg)
+	for i := 0; i < len(fields); i++ {
+		field := strings.ToLower(fields[i])
+		switch {
+		case field == "--columns-only":
+			typeFilter = "column"
+		case field == "--type" || strings.HasPrefix(field, "--type="):
+			value, ok := strings.CutPrefix(field, "--type=")
+			if !ok {
+				if i+1 >= len(fields) {
+					fmt.Fprint(c.term, usage)
+					return
+				}
+				i++
+				value = strings.ToLower(fields[i])
+			}
+			t, known := findTypes[value]
+			if !known {
+				fmt.Fprintf(c.term, "Unknown object type '%s'\n", value)
+				return
+			}
+			typeFilter = t
+		case pattern == "":
+			pattern = fields[i]
+		default:
+			fmt.Fprint(c.term, usage)
+			return
+		}
+	}
+	if pattern == "" {
+		fmt.Fprint(c.term, usage)
+		return
+	}
+	if !strings.Contains(pattern, "%") {
+		pattern = "%" + pattern + "%"
+	}
+
+	c.runCatalogQuery(`SELECT [type], [name], [table] FROM (
+	SELECT CASE o.type WHEN 'U' THEN 'table' WHEN 'V' THEN 'view' WHEN 'TR' THEN 'trigger'
+			WHEN 'P' THEN 'procedure' ELSE 'function' END AS [type],
+		s.name + '.' + o.name AS [name], CAST(NULL AS nvarchar(257)) AS [table]
+	FROM sys.objects AS o
+	JOIN sys.schemas AS s ON s.schema_id = o.schema_id
+	WHERE o.type IN ('U', 'V', 'P', 'FN', 'IF', 'TF', 'TR') AND o.is_ms_shipped = 0
+		AND LOWER(o.name) LIKE LOWER(@pattern)
+	UNION ALL
+	SELECT 'column', col.name, s.name + '.' + o.name
+	FROM sys.columns AS col
+	JOIN sys.objects AS o ON o.object_id = col.object_id
+	JOIN sys.schemas AS s ON s.schema_id = o.schema_id
+	WHERE o.type IN ('U', 'V') AND o.is_ms_shipped = 0
+		AND LOWER(col.name) LIKE LOWER(@pattern)
+) AS found
+WHERE @type IS NULL OR [type] = @type
+ORDER BY [type], [name], [table]`, sql.Named("pattern", pattern), sql.Named("type", typeFilter))
+}
+
+// listSchemas 处理 schemas [pattern] 命令，列出架构的所有者和对象数，登录用户的默认架构以 * 标记
+func (c *CLI) listSchemas(arg string) {
+	_, rest := catalogArgs(arg)
+	if len(rest) > 1 {
+		fmt.Fprintf(c.term, "Usage: schemas [pattern]\n")
+		return
+	}
+	pattern := "%"
+	if len(rest) == 1 {
+		pattern = rest[0]
+	}
+	c.runCatalogQuery(`SELECT CASE WHEN s.name = SCHEMA_NAME() THEN '*' ELSE '' END AS [default], s.name,
+	p.name AS [owner],
+	(SELECT COUNT(*) FROM sys.objects AS o WHERE o.schema_id = s.schema_id AND o.is_ms_shipped = 0) AS [objects]
+FROM sys.schemas AS s
+LEFT JOIN sys.database_principals AS p ON p.principal_id = s.principal_id
+WHERE LOWER(s.name) LIKE LOWER(@name)
+ORDER BY s.name`, sql.Named("name", pattern))
+}
@@ -3,13 +3,18 @@ package mssql
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"io"
+	"os"
+	"os/signal"
+	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
-	_ "github.com/denisenkom/go-mssqldb"
+	mssqldb "github.com/microsoft/go-mssqldb"
 )
 
 // Terminal 终端接口，用于输入输出
@@ -20,17 +25,70 @@ type Terminal interface {
 
 // CLI SQL Server 交互式命令行客户端
 type CLI struct {
-	term          Terminal
-	host          string
-	port          int
-	username      string
-	password      string
-	database      string
-	db            *sql.DB
-	reader        *Reader
-	serverInfo    ServerInfo
-	timingEnabled bool
-	maxRows       int
+	term             Terminal
+	out              *outputWriter // 结果输出目标，默认为 term
+	host             string
+	port             int
+	instance         string // 命名实例，端口为 0 时由 SQL Browser 解析端口
+	dac              bool   // 是否为专用管理员连接（DAC）
+	dacPort          int    // 解析出的 DAC 端口
+	localDBPipe      string // LocalDB 实例当前的命名管道，每次连接前查询
+	username         string
+	password         string
+	database         string
+	db               *sql.DB
+	conn             *sql.Conn // 会话连接，所有语句都在该连接上执行，保证 USE、SET 和临时表在语句间保持
+	sessionSets      []string  // 已执行的 SET 语句，重新连接后重放
+	reader           *Reader
+	serverInfo       ServerInfo
+	timingEnabled    bool
+	maxRows          int
+	outputFormat     string // 当前输出格式名称
+	formatter        Formatter
+	display          displaySettings
+	colorMode        string                   // on, off, auto
+	outMu            sync.Mutex               // 保护服务器消息与结果输出的并发写入
+	lastReturnStatus int                      // 最近一次 EXEC 的存储过程返回值
+	expanded         bool                     // 是否以纵向（每行 列: 值）方式显示结果
+	prettyXML        bool                     // 是否合并并格式化 FOR XML 结果
+	prettyJSON       bool                     // 是否合并并格式化 FOR JSON 结果
+	pager            string                   // 分页命令，为空时不分页
+	more             bool                     // 是否启用内置 --More-- 分页
+	moreLines        int                      // 无法获取终端尺寸时的屏幕行数
+	paging           *moreWriter              // 当前结果使用的内置分页
+	widthOverride    int                      // width 命令设置的表格宽度，0 表示按终端自动检测
+	countLimit       int64                    // 截断后统计总行数的上限，0 不限制，-1 不统计
+	outFile          *outputFile              // output 命令打开的输出文件
+	config           Config                   // 创建时的连接配置
+	timeout          time.Duration            // 语句超时，0 表示不限制
+	sessionName      string                   // 当前命名会话的名称，没有添加过会话时为空
+	sessions         map[string]*namedSession // 未激活的命名会话
+	connectedAt      time.Time                // 会话连接建立的时间
+	keepAlive        time.Duration            // 空闲多久后发送保活查询，0 表示关闭
+	keepAliveStop    chan struct{}            // 停止保活协程
+	keepAliveMu      sync.Mutex               // 保护 idle、lastActive，保活查询期间持有
+	idle             bool                     // 是否在主提示符处等待输入
+	lastActive       time.Time                // 最近一次输入或保活查询的时间
+	defaultSchema    string                   // 当前数据库中登录用户的默认架构，解析不带架构的对象名时使用
+	batchRepeat      int                      // 刚读入的批处理的执行次数，由 GO <n> 指定
+	pendingLines     []string                 // 一次读入的多行中尚未处理的行
+	tranCount        int                      // 上一条语句执行后的 @@TRANCOUNT，大于 0 时提示符显示 *
+	spinnerShown     bool                     // 终端上正显示语句的等待状态行，由 outMu 保护
+	workDir          string                   // :cd 设置的工作目录，:r 的相对路径据此解析，为空时使用进程的当前目录
+	includeDepth     int                      // 正在执行的 :r 嵌套层数
+	onErrorExit      bool                     // on_error stop（:on error exit）：脚本中的批处理出错后停止
+	scriptFailures   []string                 // 当前脚本中出错的批处理的位置，执行结束后汇总
+	variables        map[string]scriptVar     // :setvar 定义的脚本变量，键为小写的变量名
+	params           map[string]paramValue    // 语句参数的值，键为小写的参数名
+	safeLimit        int                      // safelimit <n>：没有行数限制的普通 SELECT 只取前 n 行，0 表示关闭
+	deadlockRetries  int                      // retry deadlock <n>：死锁或锁超时后重试的次数，0 表示不重试
+	statsEnabled     bool                     // stats on：会话打开了 STATISTICS IO 和 STATISTICS TIME
+	lastStatement    string                   // 上一条执行的语句，watch !! 使用
+	paramHintSQL     string                   // paramHintCache 对应的语句
+	paramHintCache   map[string]string        // 上一条带参数的语句的参数类型
+	nonInteractive   bool                     // RunScript 执行期间：不提示输入、不分页、不显示等待状态
+	runCtx           context.Context          // RunScript 的 context，语句的 context 由它派生
+	scriptResult     *ScriptResult            // RunScript 执行期间累计的错误和影响的行数
 }
 
 // ServerInfo SQL Server 服务器信息
@@ -38,75 +96,185 @@ type ServerInfo struct {
 	Version       string
 	ProductLevel  string
 	Edition       string
+	EngineEdition int // SERVERPROPERTY('EngineEdition')：4 为 Express，5 为 Azure SQL Database
 	ServerName    string
+	Login         string // 服务器认定的登录名 SUSER_SNAME()
+	Encrypted     string // 当前连接是否加密（sys.dm_exec_connections.encrypt_option），无权限查询时为空
 }
 
 // Config SQL Server 连接配置
 type Config struct {
-	Host             string
-	Port             int
-	Username         string
-	Password         string
-	Database         string
-	Instance         string        // SQL Server 实例名
-	Encrypt          string        // disable, false, true
-	TrustServerCert  bool          // 是否信任服务器证书
-	ConnectTimeout   int           // 连接超时（秒）
-	ConnectionString string        // 自定义连接字符串
-	MaxOpenConns     int           // 最大打开连接数
-	MaxIdleConns     int           // 最大空闲连接数
-	ConnMaxLifetime  time.Duration // 连接最大生命周期
-	ApplicationName  string        // 应用名称
+	Host                  string
+	Port                  int
+	Username              string
+	Password              string
+	Database              string
+	Instance              string        // SQL Server 实例名
+	Encrypt               string        // disable, false, true
+	TrustServerCert       bool          // 是否信任服务器证书
+	CertificateFile       string        // 用于校验服务器证书的 CA 证书文件
+	HostNameInCertificate string        // 服务器证书中的主机名，与连接的主机名不同时设置
+	ConnectTimeout        int           // 连接超时（秒），默认 10
+	StatementTimeout      int           // 语句超时（秒），默认 60，-1 表示不限制
+	ConnectionString      string        // 自定义连接字符串
+	MaxOpenConns          int           // 最大打开连接数
+	MaxIdleConns          int           // 最大空闲连接数
+	ConnMaxLifetime       time.Duration // 连接最大生命周期
+	ApplicationName       string        // 应用名称，默认 mssql-cli
+	WorkstationID         string        // 工作站名称，默认为本机主机名
+	ReadOnly              bool          // 以只读意向连接（ApplicationIntent=ReadOnly），用于可用性组只读副本
+	FailoverPartner       string        // 数据库镜像的故障转移伙伴
+	FailoverPort          int           // 故障转移伙伴的端口，0 表示与主服务器相同
+	MultiSubnetFailover   bool          // 可用性组跨子网监听器，并行连接所有地址
+	ReconnectAttempts     int           // 连接断开后重新连接的次数，默认 3，-1 表示不重新连接
+	ReconnectBackoff      time.Duration // 重新连接的初始间隔，每次失败后翻倍，默认 1 秒
+	ConnectRetries        int           // 首次连接的最多尝试次数，默认 1（不重试）
+	ConnectRetryBackoff   time.Duration // 首次连接重试的初始间隔，每次翻倍，最长 30 秒，默认 2 秒
+	ConnectRetryDeadline  time.Duration // 首次连接重试的总时限，0 表示不限制
+	DAC                   bool          // 使用专用管理员连接，也可以在主机名前加 admin: 前缀
+	PasswordFile          string        // 密码文件路径或 fd:N，在参数和环境变量都没有提供密码时读取
+	KeepAlive             time.Duration // 空闲多久后在会话连接上执行 SELECT 1，0 表示关闭
+	TrustedConnection     bool          // 使用集成身份验证（Windows 上为 SSPI，其他平台为 Kerberos），Username 为空时自动启用
+	Krb5ConfigFile        string        // Kerberos 配置文件，默认为 $KRB5_CONFIG 或 /etc/krb5.conf
+	Krb5KeytabFile        string        // 以 Username 登录 Kerberos 使用的 keytab 文件
+	Krb5CredCacheFile     string        // Kerberos 凭据缓存（kinit 生成），默认为 $KRB5CCNAME
+	Krb5Realm             string        // Kerberos 领域，默认取 Username 的 @ 之后部分或配置文件中的 default_realm
+	HideHeaders           bool          // 不输出表头
+	HideFooter            bool          // 不输出行数统计和耗时
+	Quiet                 bool          // 安静模式，只输出结果数据和错误
+	// 脚本变量
+	Variables        map[string]string // 预设的脚本变量，语句中的 $(name) 替换为其值
+	StrictVariables  bool              // 未定义的 $(name) 直接报错，不读取同名环境变量
+	OnErrorExit      bool              // 脚本中的批处理出错后停止并回滚未提交的事务，相当于 on_error stop
+	AllowScriptShell bool              // 允许脚本文件和 RunScript 中的 :!! 命令执行 shell 命令
 	// 其他参数
 	Params map[string]string
 }
 
 // NewCLI 创建新的 SQL Server CLI 实例
 func NewCLI(term Terminal, host string, port int, username, password, database string) *CLI {
-	return &CLI{
-		term:     term,
-		host:     host,
-		port:     port,
-		username: username,
-		password: password,
-		database: database,
-		reader:   NewReader(term),
-		maxRows:  1000,
+	host, dac := parseAdminHost(host)
+	c, err := NewCLIFromConnString(term, connString(host, port, username, password, database))
+	if err != nil {
+		// 端口超出范围等无法生成合法连接串的参数，留到 Connect 时报告错误
+		return NewCLIWithConfig(term, &Config{
+			Host:     host,
+			Port:     port,
+			Username: username,
+			Password: password,
+			Database: database,
+			DAC:      dac,
+		})
+	}
+	if dac {
+		c.dac = true
+		c.applyDACDefaults()
 	}
+	return c
 }
 
 // NewCLIWithConfig 使用配置创建 SQL Server CLI 实例
 func NewCLIWithConfig(term Terminal, config *Config) *CLI {
-	return &CLI{
-		term:     term,
-		host:     config.Host,
-		port:     config.Port,
-		username: config.Username,
-		password: config.Password,
-		database: config.Database,
-		reader:   NewReader(term),
-		maxRows:  1000,
+	out := &outputWriter{w: term}
+	c := &CLI{
+		term:         term,
+		out:          out,
+		config:       *config,
+		host:         config.Host,
+		port:         config.Port,
+		username:     config.Username,
+		password:     config.Password,
+		database:     config.Database,
+		instance:     config.Instance,
+		reader:       NewReader(term),
+		maxRows:      1000,
+		outputFormat: "table",
+		formatter:    &tableFormatter{w: out},
+		display:      newDisplaySettings(),
+		colorMode:    "auto",
+		prettyXML:    true,
+		prettyJSON:   true,
+		moreLines:    defaultScreenHeight,
+		countLimit:   defaultCountLimit,
+	}
+	// 主机名可以写成 SERVER\INSTANCE 的形式
+	if host, instance, ok := strings.Cut(c.host, `\`); ok && c.instance == "" {
+		c.host, c.instance = host, instance
+	}
+	// (localdb) 没有实例名时连接自动实例
+	if c.isLocalDB() && c.instance == "" {
+		c.instance = defaultLocalDBInstance
+	}
+	// admin: 前缀表示使用专用管理员连接
+	c.host, c.dac = parseAdminHost(c.host)
+	if c.dac = c.dac || config.DAC; c.dac {
+		c.applyDACDefaults()
+	}
+	switch {
+	case config.StatementTimeout > 0:
+		c.timeout = time.Duration(config.StatementTimeout) * time.Second
+	case config.StatementTimeout == 0:
+		c.timeout = defaultStatementTimeout
 	}
+	c.display.headers = !config.HideHeaders
+	c.display.footer = !config.HideFooter
+	c.display.quiet = config.Quiet
+	c.keepAlive = config.KeepAlive
+	c.onErrorExit = config.OnErrorExit
+	for name, value := range config.Variables {
+		c.setVariable(name, value)
+	}
+	c.applyColorMode()
+	return c
 }
 
 // Connect 连接到 SQL Server
 func (c *CLI) Connect() error {
-	connStr := fmt.Sprintf("server=%s;port=%d;user id=%s;password=%s;database=%s;connection timeout=10",
-		c.host, c.port, c.username, c.password, c.database)
-
-	var err error
-	c.db, err = sql.Open("sqlserver", connStr)
-	if err != nil {
+	if err := c.resolveCredentials(); err != nil {
+		return err
+	}
+	if err := c.checkEncrypt(); err != nil {
+		return err
+	}
+	if err := c.checkReadOnly(); err != nil {
+		return err
+	}
+	if err := c.checkLocalDB(); err != nil {
 		return err
 	}
 
-	c.db.SetMaxOpenConns(10)
-	c.db.SetMaxIdleConns(5)
-	c.db.SetConnMaxLifetime(time.Hour)
+	if c.dac {
+		port, err := c.resolveDACPort()
+		if err != nil {
+			return err
+		}
+		c.dacPort = port
+		fmt.Fprintf(c.term, "Warning: only one dedicated admin connection is allowed per instance; disconnect when done\n")
+	} else if c.instance != "" && c.port != 0 && !c.isLocalDB() {
+		fmt.Fprintf(c.term, "Warning: both instance %s and port %d given; connecting to port %d\n", c.instance, c.port, c.port)
+	}
 
-	if err := c.db.Ping(); err != nil {
-		c.db.Close()
-		return err
+	installMessageLogger()
+
+	// 没有提供密码时交互输入，密码错误时重新输入
+	prompted := c.needsPassword()
+	if prompted {
+		if err := c.promptPassword(); err != nil {
+			return err
+		}
+	}
+	for attempt := 1; ; attempt++ {
+		err := c.connectWithRetry()
+		if err == nil {
+			break
+		}
+		if !prompted || !isLoginFailed(err) || attempt >= passwordAttempts {
+			return err
+		}
+		fmt.Fprintf(c.term, "%v\n", err)
+		if err := c.promptPassword(); err != nil {
+			return err
+		}
 	}
 
 	c.fetchServerInfo()
@@ -115,28 +283,108 @@ func (c *CLI) Connect() error {
 	return nil
 }
 
+// openDB 建立连接池并验证连接，成功后替换当前的连接池
+func (c *CLI) openDB(ctx context.Context) error {
+	if c.isLocalDB() {
+		if err := c.resolveLocalDBPipe(); err != nil {
+			return err
+		}
+	}
+	db, err := sql.Open("sqlserver", c.connectionString())
+	if err != nil {
+		return connectError(c.redact(err))
+	}
+
+	db.SetMaxOpenConns(10)
+	db.SetMaxIdleConns(5)
+	db.SetConnMaxLifetime(time.Hour)
+	if c.dac {
+		// 每个实例只允许一个 DAC 会话，连接池中不能有第二个连接
+		db.SetMaxOpenConns(1)
+		db.SetConnMaxLifetime(0)
+	}
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		if c.isLocalDB() {
+			return c.localDBError(c.redact(err))
+		}
+		return connectError(c.redact(err))
+	}
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		db.Close()
+		return connectError(c.redact(err))
+	}
+
+	c.closeDB()
+	c.db, c.conn = db, conn
+	c.connectedAt = time.Now()
+	return nil
+}
+
+// closeDB 关闭会话连接和连接池
+func (c *CLI) closeDB() error {
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn = nil
+	}
+	if c.db == nil {
+		return nil
+	}
+	err := c.db.Close()
+	c.db = nil
+	return err
+}
+
 // fetchServerInfo 获取服务器信息
 func (c *CLI) fetchServerInfo() {
-	c.db.QueryRow("SELECT @@VERSION").Scan(&c.serverInfo.Version)
-	c.db.QueryRow("SELECT @@SERVERNAME").Scan(&c.serverInfo.ServerName)
-	c.db.QueryRow("SELECT SERVERPROPERTY('ProductLevel')").Scan(&c.serverInfo.ProductLevel)
-	c.db.QueryRow("SELECT SERVERPROPERTY('Edition')").Scan(&c.serverInfo.Edition)
+	ctx := context.Background()
+	c.conn.QueryRowContext(ctx, "SELECT @@VERSION").Scan(&c.serverInfo.Version)
+	c.conn.QueryRowContext(ctx, "SELECT @@SERVERNAME").Scan(&c.serverInfo.ServerName)
+	c.conn.QueryRowContext(ctx, "SELECT SERVERPROPERTY('ProductLevel')").Scan(&c.serverInfo.ProductLevel)
+	c.conn.QueryRowContext(ctx, "SELECT SERVERPROPERTY('Edition')").Scan(&c.serverInfo.Edition)
+	c.conn.QueryRowContext(ctx, "SELECT CAST(SERVERPROPERTY('EngineEdition') AS int)").Scan(&c.serverInfo.EngineEdition)
+	c.conn.QueryRowContext(ctx, "SELECT SUSER_SNAME()").Scan(&c.serverInfo.Login)
+	c.conn.QueryRowContext(ctx, "SELECT encrypt_option FROM sys.dm_exec_connections WHERE session_id = @@SPID").Scan(&c.serverInfo.Encrypted)
+	c.conn.QueryRowContext(ctx, "SELECT ISNULL(SCHEMA_NAME(), '')").Scan(&c.defaultSchema)
 }
 
 // showWelcome 显示欢迎信息
 func (c *CLI) showWelcome() {
+	if c.display.quiet {
+		return
+	}
 	fmt.Fprintf(c.term, "Microsoft SQL Server\n")
-	fmt.Fprintf(c.term, "Server: %s:%d\n", c.host, c.port)
+	fmt.Fprintf(c.term, "Server: %s\n", c.serverAddress())
+	if c.dac {
+		fmt.Fprintf(c.term, "%s\n", c.display.colorize(fmt.Sprintf("Dedicated admin connection (port %d)", c.dacPort), ansiRed))
+	}
+	// 故障转移后实际应答的服务器可能不是连接的地址
+	if c.serverInfo.ServerName != "" && !c.answeredBy(c.serverInfo.ServerName) {
+		fmt.Fprintf(c.term, "Answered by: %s\n", c.serverInfo.ServerName)
+	}
 	fmt.Fprintf(c.term, "Edition: %s %s\n", c.serverInfo.Edition, c.serverInfo.ProductLevel)
+	if auth := c.authDescription(); auth != "" {
+		fmt.Fprintf(c.term, "Login: %s (%s)\n", c.serverInfo.Login, auth)
+	} else {
+		fmt.Fprintf(c.term, "Login: %s\n", c.serverInfo.Login)
+	}
+	if c.serverInfo.Encrypted != "" {
+		fmt.Fprintf(c.term, "Encrypted: %s\n", strings.ToLower(c.serverInfo.Encrypted))
+	}
 	fmt.Fprintf(c.term, "\n")
 }
 
 // Start 启动交互式命令行
 func (c *CLI) Start() error {
+	c.startKeepAlive()
+	defer c.stopKeepAlive()
 	for {
 		// 设置提示符
 		prompt := c.getPrompt()
 		c.reader.SetPrompt(prompt)
+		c.setIdle(true)
 
 		sqlStr := c.readMultiLine()
 		if sqlStr == "" {
@@ -145,28 +393,67 @@ func (c *CLI) Start() error {
 
 		sqlStr = strings.TrimSpace(sqlStr)
 
-		if c.handleSpecialCommand(sqlStr) {
-			if strings.ToLower(sqlStr) == "exit" || strings.ToLower(sqlStr) == "quit" {
-				return nil
-			}
+		isExit := strings.EqualFold(sqlStr, "exit") || strings.EqualFold(sqlStr, "quit")
+		// 退出时未提交的事务会被回滚，先让用户决定
+		if isExit && !c.resolveOpenTransaction() {
 			continue
 		}
 
-		c.executeSQL(sqlStr)
+		switch {
+		case c.handleSpecialCommand(sqlStr):
+			if isExit {
+				return nil
+			}
+		case c.batchRepeat > 1:
+			c.repeatBatch(sqlStr, c.batchRepeat)
+		default:
+			c.executeSQL(sqlStr)
+		}
+		// 语句、存储过程或切换会话都可能改变事务状态，提示符据此显示 *
+		c.tranCount = c.openTransactions()
 	}
 }
 
 // getPrompt 获取提示符
 func (c *CLI) getPrompt() string {
-	return fmt.Sprintf("%s> ", c.database)
+	current := c.database
+	// 默认架构不是 dbo 时显示在提示符中，提醒不带架构的名称按该架构解析
+	if c.defaultSchema != "" && !strings.EqualFold(c.defaultSchema, "dbo") {
+		current += "." + c.defaultSchema
+	}
+	// 有未提交的事务时在数据库名后显示 *
+	if c.tranCount > 0 {
+		current += "*"
+	}
+	prompt := fmt.Sprintf("%s> ", c.display.colorize(current, ansiGreen))
+	if c.config.ReadOnly {
+		prompt = "(readonly) " + prompt
+	}
+	if c.dac {
+		prompt = "(DAC) " + prompt
+	}
+	if c.instance != "" {
+		prompt = "[" + c.instance + "] " + prompt
+	}
+	if c.sessionName != "" {
+		prompt = c.sessionName + ":" + prompt
+	}
+	return prompt
 }
 
 // readMultiLine 读取多行 SQL
+//
+// 以 GO 行或行尾的分号结束。字符串和注释中的 GO 和分号不起作用；声明了变量、定义存储过程等模块
+// 或 BEGIN 块未闭合的批处理只能以 GO 结束，因为这些语句必须在同一个批处理中执行。
+// 一次读入多行时（如粘贴），GO 之后的行留待下一次读取，各批处理依次执行。
 func (c *CLI) readMultiLine() string {
 	var lines []string
+	var lexer sqlLexer
+	var code strings.Builder
+	c.batchRepeat = 1
 
 	for {
-		line, err := c.reader.ReadLine()
+		line, err := c.nextLine()
 		if err != nil {
 			if err == io.EOF {
 				return ""
@@ -178,27 +465,45 @@ func (c *CLI) readMultiLine() string {
 		if trimmed == "" && len(lines) == 0 {
 			return ""
 		}
+		// 开始输入语句后暂停保活，避免保活输出打断续行提示符
+		c.setIdle(false)
 
 		// 如果是第一行，检查是否是特殊命令（不需要分隔符）
 		if len(lines) == 0 {
 			cmdLower := strings.ToLower(trimmed)
-			if cmdLower == "exit" || cmdLower == "quit" || cmdLower == "help" {
+			// sqlcmd 风格的 : 命令和 ! 开头的 shell 命令以行为单位，不需要分隔符
+			if cmdLower == "exit" || cmdLower == "quit" || cmdLower == "help" || strings.HasPrefix(cmdLower, ":") || strings.HasPrefix(cmdLower, "!") {
 				return trimmed
 			}
 		}
 
+		// SQL Server 使用 GO 作为批处理分隔符，GO <n> 将批处理执行 n 次；只在字符串和注释之外识别
+		if !lexer.inside() {
+			if isGo, count, err := parseGo(trimmed); isGo {
+				if err != nil {
+					fmt.Fprintf(c.term, "Error: %v\n", err)
+					c.pendingLines = nil
+					return ""
+				}
+				c.batchRepeat = count
+				break
+			}
+		}
+
 		lines = append(lines, line)
+		lineCode := strings.TrimSpace(lexer.scan(line))
+		code.WriteString(lineCode + "\n")
 
-		// SQL Server 使用 GO 作为批处理分隔符
-		if strings.ToUpper(trimmed) == "GO" {
-			// 移除最后的 GO
-			lines = lines[:len(lines)-1]
-			break
-		}
+		if !lexer.inside() {
+			// 或者以分号结束
+			if strings.HasSuffix(lineCode, ";") && !needsGo(code.String()) {
+				break
+			}
 
-		// 或者以分号结束
-		if strings.HasSuffix(trimmed, ";") {
-			break
+			// \G 结尾表示以纵向格式显示本条语句的结果，\gdesc 结尾只显示结果的列信息
+			if strings.HasSuffix(trimmed, `\G`) || strings.HasSuffix(strings.ToLower(trimmed), `\gdesc`) {
+				break
+			}
 		}
 
 		// 设置多行提示符
@@ -210,6 +515,20 @@ func (c *CLI) readMultiLine() string {
 	return result
 }
 
+// nextLine 返回下一行输入：先取上次读入的多行中剩余的行，没有时从终端读取
+func (c *CLI) nextLine() (string, error) {
+	if len(c.pendingLines) == 0 {
+		line, err := c.reader.ReadLine()
+		if err != nil {
+			return "", err
+		}
+		c.pendingLines = strings.Split(strings.ReplaceAll(line, "\r\n", "\n"), "\n")
+	}
+	line := c.pendingLines[0]
+	c.pendingLines = c.pendingLines[1:]
+	return line, nil
+}
+
 // handleSpecialCommand 处理特殊命令
 func (c *CLI) handleSpecialCommand(cmd string) bool {
 	cmdLower := strings.ToLower(strings.TrimSpace(cmd))
@@ -234,15 +553,295 @@ func (c *CLI) handleSpecialCommand(cmd string) bool {
 		return true
 	}
 
+	if cmdLower == "expanded" {
+		c.expanded = !c.expanded
+		if c.expanded {
+			fmt.Fprintf(c.term, "Expanded display is on\n")
+		} else {
+			fmt.Fprintf(c.term, "Expanded display is off\n")
+		}
+		return true
+	}
+
+	if cmdLower == "prettyxml on" || cmdLower == "prettyxml off" {
+		c.prettyXML = cmdLower == "prettyxml on"
+		if c.prettyXML {
+			fmt.Fprintf(c.term, "XML results will be formatted\n")
+		} else {
+			fmt.Fprintf(c.term, "XML results will be shown as raw table\n")
+		}
+		return true
+	}
+
+	if cmdLower == "prettyjson on" || cmdLower == "prettyjson off" {
+		c.prettyJSON = cmdLower == "prettyjson on"
+		if c.prettyJSON {
+			fmt.Fprintf(c.term, "JSON results will be formatted\n")
+		} else {
+			fmt.Fprintf(c.term, "JSON results will be shown as raw chunks\n")
+		}
+		return true
+	}
+
+	if cmdLower == "alignnum on" || cmdLower == "alignnum off" {
+		c.display.alignNumbers = cmdLower == "alignnum on"
+		if c.display.alignNumbers {
+			fmt.Fprintf(c.term, "Numeric columns will be right-aligned\n")
+		} else {
+			fmt.Fprintf(c.term, "Numeric columns will be left-aligned\n")
+		}
+		return true
+	}
+
+	if strings.HasPrefix(cmdLower, "dateformat ") || cmdLower == "dateformat" {
+		c.setDateFormat(strings.TrimSpace(cmd[len("dateformat"):]))
+		return true
+	}
+
+	if cmdLower == "hexfull on" || cmdLower == "hexfull off" {
+		c.setFullHex(cmdLower == "hexfull on")
+		return true
+	}
+
+	if strings.HasPrefix(cmdLower, "numformat ") || cmdLower == "numformat" {
+		c.setNumberFormat(cmd[len("numformat"):])
+		return true
+	}
+
+	if strings.HasPrefix(cmdLower, "color ") || cmdLower == "color" {
+		c.setColor(strings.TrimSpace(cmdLower[len("color"):]))
+		return true
+	}
+
+	if strings.HasPrefix(cmdLower, "pager ") || cmdLower == "pager" {
+		c.setPager(strings.TrimSpace(cmd[len("pager"):]))
+		return true
+	}
+
+	if strings.HasPrefix(cmdLower, "more ") || cmdLower == "more" {
+		c.setMore(strings.TrimSpace(cmd[len("more"):]))
+		return true
+	}
+
+	if strings.HasPrefix(cmdLower, "output ") || cmdLower == "output" {
+		c.setOutput(strings.TrimSpace(cmd[len("output"):]))
+		return true
+	}
+
+	if strings.HasPrefix(cmdLower, "safelimit ") || cmdLower == "safelimit" {
+		c.setSafeLimit(strings.TrimSpace(cmd[len("safelimit"):]))
+		return true
+	}
+
+	if strings.HasPrefix(cmdLower, "countrows ") || cmdLower == "countrows" {
+		c.setCountRows(strings.TrimSpace(cmd[len("countrows"):]))
+		return true
+	}
+
+	if strings.HasPrefix(cmdLower, "width ") || cmdLower == "width" {
+		c.setWidth(strings.TrimSpace(cmd[len("width"):]))
+		return true
+	}
+
+	if strings.HasPrefix(cmdLower, "!") {
+		c.shellCommand(strings.TrimSpace(strings.TrimSpace(cmd)[1:]))
+		return true
+	}
+
+	// : 开头的 sqlcmd 命令中也可以引用脚本变量，例如 :r $(ScriptDir)/init.sql
+	if strings.HasPrefix(cmd, ":") {
+		expanded, err := c.expandVariables(cmd)
+		if err != nil {
+			c.printError(err)
+			return true
+		}
+		cmd = expanded
+	}
+	switch name, arg := splitCommand(cmd); name {
+	case ":setvar":
+		c.setVarCommand(arg)
+		return true
+	case ":listvar":
+		c.listVariables()
+		return true
+	case ":!!":
+		c.shellCommand(arg)
+		return true
+	case ":r", "source":
+		c.includeCommand(arg)
+		return true
+	case ":cd":
+		c.changeDir(arg)
+		return true
+	case ":pwd":
+		fmt.Fprintf(c.term, "%s\n", c.currentDir())
+		return true
+	case ":on":
+		if option, value := splitCommand(arg); option == "error" {
+			c.setOnError(value)
+			return true
+		}
+	case "on_error":
+		c.setOnError(arg)
+		return true
+	}
+
+	if strings.HasPrefix(cmdLower, "watch ") || cmdLower == "watch" {
+		c.watchCommand(strings.TrimSpace(cmd[len("watch"):]))
+		return true
+	}
+
+	if strings.HasPrefix(cmdLower, "validate ") || cmdLower == "validate" {
+		c.validateCommand(strings.TrimSpace(cmd[len("validate"):]))
+		return true
+	}
+
+	if strings.HasPrefix(cmdLower, "stats ") || cmdLower == "stats" {
+		c.setStats(strings.TrimSpace(cmd[len("stats"):]))
+		return true
+	}
+
+	if strings.HasPrefix(cmdLower, "explain ") || cmdLower == "explain" {
+		c.explainCommand(strings.TrimSpace(cmd[len("explain"):]))
+		return true
+	}
+
+	if strings.HasPrefix(cmdLower, "bench ") || cmdLower == "bench" {
+		c.benchCommand(strings.TrimSpace(cmd[len("bench"):]))
+		return true
+	}
+
+	if strings.HasPrefix(cmdLower, "bind ") || cmdLower == "bind" {
+		c.bindCommand(strings.TrimSpace(cmd[len("bind"):]))
+		return true
+	}
+
+	if strings.HasPrefix(cmdLower, "unbind ") || cmdLower == "unbind" {
+		c.unbindCommand(strings.TrimSpace(cmd[len("unbind"):]))
+		return true
+	}
+
+	if strings.HasPrefix(cmdLower, "retry ") || cmdLower == "retry" {
+		c.setRetry(strings.TrimSpace(cmd[len("retry"):]))
+		return true
+	}
+
+	if strings.HasPrefix(cmdLower, "autocommit ") || cmdLower == "autocommit" {
+		c.setAutocommit(strings.TrimSpace(cmd[len("autocommit"):]))
+		return true
+	}
+
+	if cmdLower == "commit" || cmdLower == "rollback" {
+		c.endTransaction(cmdLower == "commit")
+		return true
+	}
+
+	if strings.HasPrefix(cmdLower, "timeout ") || cmdLower == "timeout" {
+		c.setTimeout(strings.TrimSpace(cmd[len("timeout"):]))
+		return true
+	}
+
+	if strings.HasPrefix(cmdLower, "maxrows ") || cmdLower == "maxrows" {
+		c.setMaxRows(strings.TrimSpace(cmd[len("maxrows"):]))
+		return true
+	}
+
+	if m := describePattern.FindStringSubmatch(cmd); m != nil {
+		c.describeQuery(m[1])
+		return true
+	}
+	if strings.HasSuffix(cmdLower, `\gdesc`) {
+		c.describeQuery(cmd[:len(cmd)-len(`\gdesc`)])
+		return true
+	}
+
+	if c.handleCatalogCommand(cmd) {
+		return true
+	}
+
+	if cmdLower == "status" {
+		c.showStatus()
+		return true
+	}
+
+	if strings.HasPrefix(cmdLower, "connect ") || cmdLower == "connect" {
+		c.setConnect(strings.TrimSpace(cmd[len("connect"):]))
+		return true
+	}
+
+	if strings.HasPrefix(cmdLower, "session ") || cmdLower == "session" {
+		c.sessionCommand(cmd[len("session"):])
+		return true
+	}
+
+	if strings.HasPrefix(cmdLower, "keepalive ") || cmdLower == "keepalive" {
+		c.setKeepAlive(strings.TrimSpace(cmd[len("keepalive"):]))
+		return true
+	}
+
+	if cmdLower == "reconnect" {
+		c.reconnectCommand()
+		return true
+	}
+
+	if cmdLower == "quiet on" || cmdLower == "quiet off" {
+		c.display.quiet = cmdLower == "quiet on"
+		fmt.Fprintf(c.term, "Quiet mode %s\n", onOff(c.display.quiet))
+		return true
+	}
+
+	if cmdLower == "types on" || cmdLower == "types off" {
+		c.display.showTypes = cmdLower == "types on"
+		fmt.Fprintf(c.term, "Column types %s\n", onOff(c.display.showTypes))
+		return true
+	}
+
+	if cmdLower == "headers on" || cmdLower == "headers off" {
+		c.display.headers = cmdLower == "headers on"
+		fmt.Fprintf(c.term, "Headers %s\n", onOff(c.display.headers))
+		return true
+	}
+
+	if cmdLower == "footer on" || cmdLower == "footer off" {
+		c.display.footer = cmdLower == "footer on"
+		fmt.Fprintf(c.term, "Footer %s\n", onOff(c.display.footer))
+		return true
+	}
+
+	if cmdLower == "truncate on" || cmdLower == "truncate off" {
+		c.display.truncate = cmdLower == "truncate on"
+		if c.display.truncate {
+			fmt.Fprintf(c.term, "Long values will be truncated to %d characters\n", maxCellWidth)
+		} else {
+			fmt.Fprintf(c.term, "Long values will be shown in full\n")
+		}
+		return true
+	}
+
+	if cmdLower == "stream on" || cmdLower == "stream off" {
+		c.display.stream = cmdLower == "stream on"
+		if c.display.stream {
+			fmt.Fprintf(c.term, "Streaming table output on (column widths fixed after the first %d rows)\n", streamSampleRows)
+		} else {
+			fmt.Fprintf(c.term, "Streaming table output off\n")
+		}
+		return true
+	}
+
 	if cmdLower == "clear" || cmdLower == "cls" {
 		fmt.Fprintf(c.term, "\033[2J\033[H")
 		return true
 	}
 
+	if strings.HasPrefix(cmdLower, "format ") || cmdLower == "format" {
+		c.setFormat(strings.TrimSpace(cmd[len("format"):]))
+		return true
+	}
+
 	// SQL Server 特有命令
 	if strings.HasPrefix(cmdLower, "use ") {
 		parts := strings.Fields(cmd)
-		if len(parts) >= 2 {
+		if len(parts) >= 2 && c.resolveOpenTransaction() {
 			c.useDatabase(parts[1])
 		}
 		return true
@@ -259,167 +858,394 @@ func (c *CLI) executeSQL(sqlStr string) {
 	if sqlStr == "" {
 		return
 	}
+	c.lastStatement = sqlStr
+	sqlStr, err := c.expandVariables(sqlStr)
+	if err != nil {
+		c.printError(err)
+		return
+	}
+
+	// \G 后缀仅对本条语句启用纵向显示
+	vertical := c.expanded
+	if strings.HasSuffix(sqlStr, `\G`) {
+		sqlStr = strings.TrimSpace(strings.TrimSuffix(sqlStr, `\G`))
+		vertical = true
+	}
+
+	params, ok := c.bindParams(sqlStr)
+	if !ok {
+		return
+	}
+	if params != nil {
+		// 不计入输入参数值所用的时间
+		startTime = time.Now()
+	}
+
+	defer c.flushOutput()
+
+	// safelimit 只对能确定结构的普通 SELECT 加上行数限制
+	limited := false
+	if c.safeLimit > 0 {
+		sqlStr, limited = applySafeLimit(sqlStr, c.safeLimit)
+	}
+
+	err = c.runStatement(sqlStr, startTime, vertical, params...)
+	err = c.retryLocked(sqlStr, vertical, params, err)
+	if err == nil && limited && c.showInfo() {
+		note := fmt.Sprintf("-- Limited to %d rows by safelimit (turn off with 'safelimit off')", c.safeLimit)
+		fmt.Fprintf(c.out, "%s\n\n", c.display.colorize(note, ansiDim))
+	}
+	if err == nil || !isConnectionError(err) {
+		return
+	}
+
+	// 连接断开：重新连接，只读查询自动重试一次，修改数据的语句交给用户决定是否重新提交
+	fmt.Fprintf(c.term, "Connection lost. Reconnecting...\n")
+	if err := c.reconnect(); err != nil {
+		fmt.Fprintf(c.term, "Reconnect failed: %v\n\n", err)
+		return
+	}
+	if !isReadOnlyStatement(sqlStr) {
+		fmt.Fprintf(c.term, "Connection restored. The statement was not retried; resubmit it if needed.\n\n")
+		return
+	}
+	fmt.Fprintf(c.term, "Connection restored. Retrying...\n")
+	c.runStatement(sqlStr, time.Now(), vertical, params...)
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+// runStatement 执行一条语句并输出结果，params 为语句参数的 sql.Named 实参
+func (c *CLI) runStatement(sqlStr string, startTime time.Time, vertical bool, params ...interface{}) error {
+	ctx, cancel := c.statementContext()
 	defer cancel()
 
-	if isQuery(sqlStr) {
-		c.executeQuery(ctx, sqlStr, startTime)
-	} else {
-		c.executeCommand(ctx, sqlStr, startTime)
+	// EXEC 语句通过 ReturnStatus 参数获取存储过程的返回值
+	args := params
+	var status mssqldb.ReturnStatus
+	isExec := execPattern.MatchString(sqlStr)
+	if isExec {
+		args = append(args, &status)
+	}
+
+	// 所有语句都按查询执行：EXEC、DBCC、带 OUTPUT 子句的 DML 等都可能返回结果集，
+	// 没有结果集时显示影响的行数
+	err := c.executeQuery(ctx, sqlStr, startTime, vertical, args...)
+
+	// 带参数的语句通过 sp_executesql 执行，其中的 SET 不影响会话
+	if err == nil && len(params) == 0 {
+		c.trackSession(sqlStr)
 	}
+
+	if err == nil && isExec {
+		c.lastReturnStatus = int(status)
+		c.recordReturnStatus()
+		if c.showInfo() {
+			fmt.Fprintf(c.out, "Return status = %d\n\n", status)
+		}
+	}
+	return err
 }
 
-// executeQuery 执行查询语句
-func (c *CLI) executeQuery(ctx context.Context, sqlStr string, startTime time.Time) {
-	rows, err := c.db.QueryContext(ctx, sqlStr)
-	if err != nil {
-		c.printError(err)
-		return
+// defaultStatementTimeout 默认的语句超时
+const defaultStatementTimeout = 60 * time.Second
+
+// statementContext 返回执行单条语句使用的 context，带超时并接收服务器消息
+func (c *CLI) statementContext() (context.Context, context.CancelFunc) {
+	parent := context.Background()
+	if c.runCtx != nil {
+		parent = c.runCtx
+	}
+	if c.timeout <= 0 {
+		ctx, cancel := context.WithCancel(parent)
+		return c.withMessages(ctx), cancel
 	}
-	defer rows.Close()
+	ctx, cancel := context.WithTimeout(parent, c.timeout)
+	return c.withMessages(ctx), cancel
+}
 
-	cols, _ := rows.Columns()
-	colTypes, _ := rows.ColumnTypes()
+// timeoutText 返回语句超时的显示文本
+func (c *CLI) timeoutText() string {
+	if c.timeout <= 0 {
+		return "none"
+	}
+	return c.timeout.String()
+}
 
-	c.displayTable(rows, cols, colTypes, startTime)
+// setTimeout 设置语句超时（秒），off 或 0 表示不限制
+func (c *CLI) setTimeout(arg string) {
+	if arg == "" {
+		fmt.Fprintf(c.term, "Statement timeout: %s\n", c.timeoutText())
+		return
+	}
+	if strings.EqualFold(arg, "off") {
+		arg = "0"
+	}
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 0 {
+		fmt.Fprintf(c.term, "Usage: timeout <seconds>|off\n")
+		return
+	}
+	c.timeout = time.Duration(n) * time.Second
+	fmt.Fprintf(c.term, "Statement timeout set to %s\n", c.timeoutText())
 }
 
-// displayTable 以表格形式显示结果
-func (c *CLI) displayTable(rows *sql.Rows, cols []string, colTypes []*sql.ColumnType, startTime time.Time) {
-	colWidths := make([]int, len(cols))
-	for i, col := range cols {
-		colWidths[i] = len(col)
-		if colWidths[i] < 4 {
-			colWidths[i] = 4
-		}
-		if colWidths[i] > 50 {
-			colWidths[i] = 50
+// executeQuery 执行语句并输出返回的所有结果集，没有结果集时输出影响的行数
+func (c *CLI) executeQuery(ctx context.Context, sqlStr string, startTime time.Time, vertical bool, args ...interface{}) error {
+	// STATISTICS IO / TIME 的消息收集起来，在所有结果之后以表格显示
+	var stats statsCollector
+	ctx = stats.withStatistics(ctx)
+	defer c.printStatistics(&stats)
+
+	// 批处理和存储过程可能返回多个结果集，逐个输出；用户在分页提示处放弃时取消查询，让服务器停止发送数据
+	affected, hasResults, cancelled, err := c.runQuery(ctx, sqlStr, startTime, func(rows *sql.Rows, cols []string, colTypes []*sql.ColumnType) bool {
+		f := c.resultFormatter(sqlStr, cols, colTypes, vertical)
+		endPaging := c.beginPaging()
+		defer endPaging()
+		return c.renderRows(f, rows, cols, colTypes, startTime)
+	}, args...)
+	if err != nil {
+		c.printError(err)
+		return err
+	}
+	if cancelled {
+		fmt.Fprintf(c.term, "%s\n\n", c.display.colorize("-- Query cancelled", ansiDim))
+		return nil
+	}
+	c.recordRowsAffected(affected)
+	switch verb := dmlOutputVerb(sqlStr); {
+	case !hasResults:
+		c.formatter.EndResult(ResultSummary{
+			RowCount: affected,
+			Elapsed:  time.Since(startTime),
+			Timing:   c.timingEnabled,
+		})
+	case verb != "" && c.showInfo():
+		// OUTPUT 返回的行可能被 maxrows 截断，影响的行数以服务器报告的为准
+		rows := "rows"
+		if affected == 1 {
+			rows = "row"
 		}
+		fmt.Fprintf(c.out, "%s\n\n", c.display.colorize(fmt.Sprintf("%s affected %d %s", verb, affected, rows), ansiDim))
 	}
+	return nil
+}
 
-	var allRows [][]string
-	for rows.Next() {
-		vals := make([]interface{}, len(cols))
-		valPtrs := make([]interface{}, len(cols))
-		for i := range vals {
-			valPtrs[i] = &vals[i]
-		}
-		rows.Scan(valPtrs...)
-
-		rowStrs := make([]string, len(vals))
-		for i, v := range vals {
-			if v == nil {
-				rowStrs[i] = "NULL"
-			} else {
-				switch val := v.(type) {
-				case []byte:
-					rowStrs[i] = string(val)
-				case time.Time:
-					rowStrs[i] = val.Format("2006-01-02 15:04:05")
-				default:
-					rowStrs[i] = fmt.Sprintf("%v", v)
-				}
-			}
+// resultFormatter 选择当前结果集使用的格式化器
+func (c *CLI) resultFormatter(sqlStr string, cols []string, colTypes []*sql.ColumnType, vertical bool) Formatter {
+	// 每个结果集重新检测终端宽度，以便窗口大小变化后生效
+	c.display.width = c.screenWidth()
+
+	var f Formatter
+	switch {
+	case vertical:
+		f = &verticalFormatter{w: c.out}
+	case c.outputFormat == "table" && c.prettyXML && isXMLResult(sqlStr, cols, colTypes):
+		f = &xmlDocumentFormatter{w: c.out}
+	case c.outputFormat == "table" && c.prettyJSON && isJSONResult(sqlStr, cols):
+		f = &jsonDocumentFormatter{w: c.out}
+	default:
+		f = c.formatter
+	}
 
-			if len(rowStrs[i]) > colWidths[i] {
-				if len(rowStrs[i]) > 50 {
-					colWidths[i] = 50
-					rowStrs[i] = rowStrs[i][:47] + "..."
-				} else {
-					colWidths[i] = len(rowStrs[i])
-				}
-			}
-		}
-		allRows = append(allRows, rowStrs)
+	if da, ok := f.(displayAware); ok {
+		da.setDisplay(&c.display)
+	}
+	if sf, ok := f.(statementFormatter); ok {
+		sf.setStatement(sqlStr)
+	}
+	return f
+}
 
-		if len(allRows) >= c.maxRows {
-			break
+// renderRows 将结果集逐行交给格式化器输出，返回 true 表示应取消查询
+func (c *CLI) renderRows(f Formatter, rows *sql.Rows, cols []string, colTypes []*sql.ColumnType, startTime time.Time) bool {
+	if err := f.BeginResult(cols, colTypes); err != nil {
+		fmt.Fprintf(c.out, "Error: %v\n\n", err)
+		return false
+	}
+
+	// 文档格式合并所有行，不受 maxrows 限制
+	limit := c.maxRows
+	if _, isDocument := f.(documentFormatter); isDocument {
+		limit = 0
+	}
+	var writeErr error
+	quit := false
+	rowCount, truncated, err := scanRows(rows, len(cols), limit, func(vals []interface{}) bool {
+		c.outMu.Lock()
+		writeErr = f.WriteRow(vals)
+		c.outMu.Unlock()
+		if writeErr != nil {
+			return false
 		}
+		quit = c.pagingQuit()
+		return !quit
+	})
+	switch {
+	case writeErr != nil:
+		fmt.Fprintf(c.out, "Error: %v\n\n", writeErr)
+		return false
+	case quit:
+		return true
+	case err != nil:
+		c.printError(err)
 	}
 
-	c.printSeparator(colWidths)
-	fmt.Fprintf(c.term, "| ")
-	for i, col := range cols {
-		fmt.Fprintf(c.term, "%-*s | ", colWidths[i], col)
+	// 超出行数限制后继续读取剩余行以统计总数；未能读完时需取消查询，
+	// 避免连接上残留未读取的结果
+	var remaining int64
+	complete := true
+	if truncated {
+		remaining, complete = c.countRemaining(rows)
+		// scanRows 判断截断时已读取了下一行
+		if complete {
+			remaining++
+		}
 	}
-	fmt.Fprintf(c.term, "\n")
-	c.printSeparator(colWidths)
 
-	for _, row := range allRows {
-		fmt.Fprintf(c.term, "| ")
-		for i, val := range row {
-			fmt.Fprintf(c.term, "%-*s | ", colWidths[i], val)
+	c.outMu.Lock()
+	defer c.outMu.Unlock()
+	f.EndResult(ResultSummary{
+		RowCount: rowCount,
+		Elapsed:  time.Since(startTime),
+		Timing:   c.timingEnabled,
+	})
+	if truncated && c.showInfo() {
+		shown := groupDigits(strconv.FormatInt(rowCount, 10))
+		total := groupDigits(strconv.FormatInt(rowCount+remaining, 10))
+		var warning string
+		switch {
+		case complete:
+			warning = fmt.Sprintf("Result truncated: showing first %s of %s rows", shown, total)
+		case remaining > 0:
+			warning = fmt.Sprintf("Result truncated: showing first %s of more than %s rows", shown, total)
+		default:
+			warning = fmt.Sprintf("Result truncated at %s rows", shown)
 		}
-		fmt.Fprintf(c.term, "\n")
+		warning += " (use 'maxrows 0' for all rows)"
+		fmt.Fprintf(c.out, "%s\n\n", c.display.colorize(warning, ansiRed))
 	}
-	c.printSeparator(colWidths)
+	return !complete
+}
 
-	rowCount := len(allRows)
-	if rowCount == 0 {
-		fmt.Fprintf(c.term, "(0 rows affected)\n")
-	} else if rowCount == 1 {
-		fmt.Fprintf(c.term, "(1 row affected)\n")
-	} else {
-		fmt.Fprintf(c.term, "(%d rows affected)\n", rowCount)
+// defaultCountLimit 截断后继续统计的默认最大行数
+const defaultCountLimit = 1000000
+
+// countRemaining 统计剩余的行数，不保存数据；达到 countLimit、关闭统计、
+// 上下文结束或按下 Ctrl+C 时停止，complete 表示是否已读完结果集
+func (c *CLI) countRemaining(rows *sql.Rows) (n int64, complete bool) {
+	if c.countLimit < 0 {
+		return 0, false
 	}
 
-	if c.timingEnabled {
-		elapsed := time.Since(startTime).Seconds()
-		fmt.Fprintf(c.term, "Time: %.3f sec\n", elapsed)
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+	defer signal.Stop(sigs)
+
+	for rows.Next() {
+		n++
+		if c.countLimit > 0 && n >= c.countLimit {
+			return n, false
+		}
+		select {
+		case <-sigs:
+			return n, false
+		default:
+		}
 	}
-	fmt.Fprintf(c.term, "\n")
+	return n, rows.Err() == nil
 }
 
-// printSeparator 打印表格分隔线
-func (c *CLI) printSeparator(colWidths []int) {
-	fmt.Fprintf(c.term, "+")
-	for _, width := range colWidths {
-		fmt.Fprintf(c.term, "%s+", strings.Repeat("-", width+2))
+// setCountRows 设置截断后是否继续统计总行数：on、off 或统计的最大行数
+func (c *CLI) setCountRows(arg string) {
+	switch strings.ToLower(arg) {
+	case "":
+	case "on":
+		c.countLimit = defaultCountLimit
+	case "off":
+		c.countLimit = -1
+	default:
+		n, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil || n < 0 {
+			fmt.Fprintf(c.term, "Usage: countrows on|off|<limit>\n")
+			return
+		}
+		c.countLimit = n
+	}
+
+	switch {
+	case c.countLimit < 0:
+		fmt.Fprintf(c.term, "Row counting after truncation: off\n")
+	case c.countLimit == 0:
+		fmt.Fprintf(c.term, "Row counting after truncation: on (unlimited)\n")
+	default:
+		fmt.Fprintf(c.term, "Row counting after truncation: on (up to %s rows)\n", groupDigits(strconv.FormatInt(c.countLimit, 10)))
 	}
-	fmt.Fprintf(c.term, "\n")
 }
 
-// executeCommand 执行非查询语句
-func (c *CLI) executeCommand(ctx context.Context, sqlStr string, startTime time.Time) {
-	result, err := c.db.ExecContext(ctx, sqlStr)
-	if err != nil {
-		c.printError(err)
+// setMaxRows 设置每个结果集最多显示的行数，0 表示不限制
+func (c *CLI) setMaxRows(arg string) {
+	if arg == "" {
+		if c.maxRows == 0 {
+			fmt.Fprintf(c.term, "Max rows: unlimited\n")
+		} else {
+			fmt.Fprintf(c.term, "Max rows: %d\n", c.maxRows)
+		}
 		return
 	}
 
-	affected, _ := result.RowsAffected()
-	elapsed := time.Since(startTime).Seconds()
-
-	if affected == 0 {
-		fmt.Fprintf(c.term, "(0 rows affected)\n")
-	} else if affected == 1 {
-		fmt.Fprintf(c.term, "(1 row affected)\n")
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 0 {
+		fmt.Fprintf(c.term, "Invalid row limit: %s (usage: maxrows <n>, 0 for unlimited)\n", arg)
+		return
+	}
+	c.maxRows = n
+	if n == 0 {
+		fmt.Fprintf(c.term, "Max rows set to unlimited\n")
 	} else {
-		fmt.Fprintf(c.term, "(%d rows affected)\n", affected)
+		fmt.Fprintf(c.term, "Max rows set to %d\n", n)
 	}
+}
 
-	if c.timingEnabled {
-		fmt.Fprintf(c.term, "Time: %.3f sec\n", elapsed)
+// showInfo 判断是否输出提示类信息，面向机器处理的格式下不输出
+func (c *CLI) showInfo() bool {
+	if c.display.quiet {
+		return false
 	}
-	fmt.Fprintf(c.term, "\n")
+	switch c.outputFormat {
+	case "json", "tsv", "insert", "template":
+		return false
+	}
+	return true
 }
 
 // useDatabase 切换数据库
 func (c *CLI) useDatabase(dbName string) {
-	_, err := c.db.Exec(fmt.Sprintf("USE [%s]", dbName))
+	stmt := fmt.Sprintf("USE [%s]", strings.ReplaceAll(dbName, "]", "]]"))
+	_, err := c.conn.ExecContext(context.Background(), stmt)
 	if err != nil {
 		fmt.Fprintf(c.term, "Error: %v\n", err)
 		return
 	}
+	// 与执行 USE 语句相同，同时更新当前数据库中的默认架构
 	c.database = dbName
-	fmt.Fprintf(c.term, "Changed database context to '%s'.\n", dbName)
+	c.trackSession(stmt)
+	if !c.display.quiet {
+		fmt.Fprintf(c.term, "Changed database context to '%s'.\n", c.database)
+	}
 }
 
 // printError 打印错误信息
 func (c *CLI) printError(err error) {
-	fmt.Fprintf(c.term, "Msg 50000, Level 16, State 1\n")
-	fmt.Fprintf(c.term, "%s\n\n", err.Error())
+	// 语句因超时被取消时给出具体的提示，而不是驱动的 context deadline exceeded
+	if errors.Is(err, context.DeadlineExceeded) {
+		msg := fmt.Sprintf("Statement cancelled after %s (change with 'timeout <seconds>' or 'timeout off')", c.timeoutText())
+		fmt.Fprintf(c.out, "%s\n\n", c.display.colorize(msg, ansiRed))
+		return
+	}
+	fmt.Fprintf(c.out, "%s\n\n", c.display.colorize(errorHeader(err)+"\n"+err.Error(), ansiRed))
+	if c.config.ReadOnly && isReadOnlyError(err) {
+		fmt.Fprintf(c.out, "This session uses read-only application intent and is routed to a read-only replica.\n"+
+			"Reconnect without ReadOnly to modify data.\n\n")
+	}
 }
 
 // showHelp 显示帮助信息
@@ -432,11 +1258,121 @@ General:
   help                    Show this help
   exit, quit              Exit
   clear, cls              Clear screen
+  !<command>, :!! <command>
+                          Run a shell command; ! alone starts a subshell
+  status                  Show connection and session details
+  connect <host[,port]> [user] [db]
+                          Connect to another server (prompts for the password)
+  reconnect               Re-open the connection to the current server
+  keepalive <minutes>|off Send SELECT 1 after <minutes> of inactivity
+  session add <name> host=...,user=...,database=...
+                          Open another named session and switch to it
+  session list|use <name>|close <name>
+                          List, switch between or close named sessions
   timing                  Toggle timing
+  format                  List available output formats
+  format <name>           Set output format (table, plain, vertical, csv, json, html, tsv)
+  format plain -w         Borderless output without trailing padding
+  format insert [table] [-identity]
+                          Render rows as INSERT statements
+  format template <tmpl>  Render each row with a Go text/template (file or inline),
+                          e.g. format template {{.name}}: {{default "-" .email}}
+                          funcs: quote, csv, default; unnamed columns are colN
+  alignnum on|off         Right-align numeric columns in table output
+  dateformat <layout>     Set datetime layout (Go layout, iso8601, full, short, default)
+  hexfull on|off          Show binary values in full instead of truncated
+  numformat [options]     Number display: off | grouping on|off |
+                          money <decimals>|off | float <digits>|off
+  color on|off|auto       ANSI colors (auto: only when output is a terminal)
+  pager [on|off|<cmd>]    Page long results (default $PAGER or less -S)
+  more on|off|<lines>     Built-in --More-- paging; <lines> sets the screen height
+                          used when the terminal size is unknown
+  width <n>|auto          Fit tables to <n> columns (auto: terminal width)
+  maxrows <n>             Limit rows shown per result, 0 for unlimited (current: %d)
+  timeout <seconds>|off   Statement timeout, off for no limit (current: %s)
+  quiet on|off            Only print result data and errors (no banner, row
+                          counts, timing or server messages)
+  headers on|off          Show column headers and header separators
+  footer on|off           Show "(N rows affected)" and timing lines
+  types on|off            Show column types (nullable marked with ?) under headers
+  truncate on|off         Truncate long table values to 50 characters (default on)
+  output [-a] [-tee] <file>
+                          Write results to a file (-a append, -tee also show them)
+  output off              Stop writing results to a file
+  countrows on|off|<n>    Count all rows of a truncated result (up to <n> rows)
+  safelimit <n>|off       Fetch at most n rows for plain SELECT statements (TOP or OFFSET/FETCH)
+  stream on|off           Stream table rows as they arrive (widths fixed early)
+  expanded                Toggle expanded (vertical) display
+  <statement>\G           Display one statement's result vertically
+  stats on|off            Show STATISTICS IO and TIME as a table after each statement
+  explain <statement>     Show the estimated plan as an operator tree without running the statement
+  explain xml <statement> Print the estimated plan XML
+  explain file <path> <statement>
+                          Save the estimated plan as a .sqlplan file
+  explain analyze <statement>
+                          Run the statement and show the actual plan (also with xml or file)
+  describe <query>        Show the columns a query would return without running it
+  <query>\gdesc           Same as describe <query>
+  prettyxml on|off        Format FOR XML results (off shows raw table)
+  prettyjson on|off       Format FOR JSON results (off shows raw chunks)
   GO                      Execute batch (SQL Server style)
+  GO <n>                  Execute the batch n times with progress and a summary
+  :r <file>, source <file>
+                          Execute a script file of GO-separated batches
+  validate <statement>, validate :r <file>
+                          Compile without executing (SET NOEXEC ON) and report errors
+  :cd [dir], :pwd         Change or show the directory for relative :r paths
+  on_error stop|continue  Stop a script at the first error and roll back,
+                          or run every batch and list failures (default)
+  :on error exit|ignore   Same as on_error stop|continue
+  :setvar <name> [value]  Define a scripting variable referenced as $(name); no value removes it
+  :listvar                List scripting variables
+  watch [--no-clear] <seconds> <statement>|!!
+                          Re-run a statement every n seconds until Ctrl+C
+  bench [-w <n>] [-s] <N> <statement>
+                          Run a statement N times and report latency statistics
+  bind @name=value        Bind a value to a statement parameter (NULL for null); bind alone lists
+  unbind [@name]          Clear bound parameter values so the next run prompts again
 
 Database:
   USE <database>          Change database
+  autocommit on|off       off: SET IMPLICIT_TRANSACTIONS ON, changes need an explicit commit
+  commit, rollback        Commit or roll back the open transaction
+  retry deadlock <n>|off  Retry a single statement up to n times after a deadlock or lock timeout
+
+Catalog (patterns use LIKE syntax, optionally schema.pattern):
+  tables, \dt [-v] [pat]  List tables (-v also lists views)
+  desc, \d <name>         Show columns, indexes and constraints of a table, or the
+                          parameters of a procedure or function
+  databases, \l [pat]     List databases with state, recovery model and size
+  views [-d] [pat]        List views (-d also prints their definitions)
+  def [-n] <name>         Print the source of a view, procedure, function or trigger
+                          (-n numbers the lines; viewdef is an alias)
+  procs [-s] [pat]        List stored procedures (-s includes system procedures)
+  funcs [-s] [pat]        List functions with their return type (-s includes system ones)
+  indexes <table>         List a table's indexes with key and included columns
+  fks <table>             List foreign keys from and to a table
+  schemas [pat]           List schemas with their owner and object count
+  sizes [pat]             Table sizes and row counts, largest first
+  dbsize [-a | <db>]      Database files with size, used space and autogrowth
+  users                   Database users with their type, default schema and roles
+  roles                   Database roles with their members
+  logins                  Server logins with disabled/locked status and server roles
+  who [active]            User sessions (active: only those running a request)
+  blockers                Blocking chains as a tree with wait times and statements
+  kill <spid> [--force]   Kill a session after confirmation (--force skips it)
+  indexstats [table]      Index fragmentation and usage with maintenance suggestions
+  setopts                 Current session SET options (marks those set by the client)
+  triggers [-d] [table]   DML triggers (and database DDL triggers); -d with definitions
+  jobs                    SQL Agent jobs with last outcome, running state and next run
+  jobhistory <job> [N]    Last N executions of a job with step messages (default 10)
+  dbinfo [database]       Database properties and last backup times
+  temptables              This session's #temp tables and global ##temp tables
+  tempdb                  tempdb files, version store and top sessions by allocation
+  script <table>          Generate CREATE TABLE, index and foreign key statements
+  find [--columns-only] [--type <type>] <pattern>
+                          Find tables, views, procedures, functions, triggers and
+                          columns by name
 
 Query Commands:
   SELECT ...              Query data
@@ -466,36 +1402,49 @@ System Views:
   SELECT * FROM sys.tables
   SELECT * FROM sys.columns WHERE object_id = OBJECT_ID('table')
 
+Credentials (first match wins):
+  1. Host, user and password passed to NewCLI / Config
+  2. MSSQL_CLI_SERVER, MSSQL_CLI_USER, MSSQL_CLI_PASSWORD environment variables
+  3. Config.PasswordFile (a file path or fd:N, surrounding whitespace trimmed)
+  4. Interactive hidden password prompt
+
 For more information: https://docs.microsoft.com/sql/
 `
-	fmt.Fprintf(c.term, help)
+	fmt.Fprintf(c.term, help, c.maxRows, c.timeoutText())
 }
 
 // Close 关闭数据库连接
 func (c *CLI) Close() error {
-	if c.db != nil {
-		return c.db.Close()
+	err := c.closeOutput()
+	c.closeSessions()
+	if dbErr := c.closeDB(); dbErr != nil {
+		return dbErr
 	}
-	return nil
+	return err
 }
 
-// isQuery 判断是否是查询语句
-func isQuery(sqlStr string) bool {
-	upper := strings.ToUpper(strings.TrimSpace(sqlStr))
+// execPattern 匹配 EXEC / EXECUTE 语句
+var execPattern = regexp.MustCompile(`(?i)^\s*EXEC(UTE)?\b`)
 
-	queryPrefixes := []string{
-		"SELECT", "SHOW", "WITH", "EXPLAIN",
-		"EXEC sp_help", "EXEC sp_databases", "EXEC sp_tables",
-		"EXEC sp_columns", "EXEC sp_who",
-	}
+// dmlPattern 匹配 INSERT、UPDATE、DELETE、MERGE 语句（可以带 WITH 公用表表达式），outputPattern 匹配 OUTPUT 子句
+var (
+	dmlPattern    = regexp.MustCompile(`(?is)^\s*(?:WITH\b.*?\)\s*)?(INSERT|UPDATE|DELETE|MERGE)\b`)
+	outputPattern = regexp.MustCompile(`(?i)\bOUTPUT\b`)
+)
 
-	for _, prefix := range queryPrefixes {
-		if strings.HasPrefix(upper, prefix) {
-			return true
-		}
+// dmlOutputVerb 对带 OUTPUT 子句的 DML 语句返回其动词，否则返回空串；字符串和注释中的关键字不计
+func dmlOutputVerb(sqlStr string) string {
+	var lexer sqlLexer
+	lines := strings.Split(sqlStr, "\n")
+	for i, line := range lines {
+		lines[i] = lexer.scan(line)
 	}
-
-	return false
+	code := strings.Join(lines, "\n")
+	m := dmlPattern.FindStringSubmatch(code)
+	if m == nil || !outputPattern.MatchString(code) {
+		return ""
+	}
+	return strings.ToUpper(m[1])
 }
 
 // ParseInt 安全地解析整数
@@ -503,4 +1452,3 @@ func parseInt(s string) int {
 	i, _ := strconv.Atoi(s)
 	return i
 }
-
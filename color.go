@@ -0,0 +1,67 @@
+package mssql
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/chzyer/readline"
+)
+
+// ANSI 颜色控制码
+const (
+	ansiReset = "\033[0m"
+	ansiBold  = "\033[1m"
+	ansiDim   = "\033[2m"
+	ansiRed   = "\033[31m"
+	ansiGreen = "\033[32m"
+)
+
+// ansiPattern 匹配 ANSI 转义序列
+var ansiPattern = regexp.MustCompile("\033\\[[0-9;]*m")
+
+// stripANSI 去除字符串中的 ANSI 转义序列
+func stripANSI(s string) string {
+	return ansiPattern.ReplaceAllString(s, "")
+}
+
+// isTerminal 判断输出是否为终端
+func isTerminal(v interface{}) bool {
+	f, ok := v.(interface{ Fd() uintptr })
+	return ok && readline.IsTerminal(int(f.Fd()))
+}
+
+// colorize 在启用颜色时用指定的控制码包裹字符串
+func (d *displaySettings) colorize(s, code string) string {
+	if d == nil || !d.color {
+		return s
+	}
+	return code + s + ansiReset
+}
+
+// setColor 设置颜色模式：on、off 或 auto（仅在终端上启用）
+func (c *CLI) setColor(mode string) {
+	switch mode {
+	case "":
+		fmt.Fprintf(c.term, "Color: %s (%s)\n", c.colorMode, onOff(c.display.color))
+		return
+	case "on", "off", "auto":
+		c.colorMode = mode
+	default:
+		fmt.Fprintf(c.term, "Usage: color on|off|auto\n")
+		return
+	}
+	c.applyColorMode()
+	fmt.Fprintf(c.term, "Color %s\n", onOff(c.display.color))
+}
+
+// applyColorMode 根据颜色模式和终端类型计算是否输出颜色
+func (c *CLI) applyColorMode() {
+	switch c.colorMode {
+	case "on":
+		c.display.color = true
+	case "off":
+		c.display.color = false
+	default:
+		c.display.color = isTerminal(c.term)
+	}
+}
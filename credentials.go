@@ -0,0 +1,104 @@
+package mssql
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// 提供连接参数的环境变量
+const (
+	envServer   = "MSSQL_CLI_SERVER"   // host、host,port 或 host\instance
+	envUser     = "MSSQL_CLI_USER"     // 登录名
+	envPassword = "MSSQL_CLI_PASSWORD" // 密码
+)
+
+// lookupEnv 读取环境变量，可以替换以便使用模拟的环境
+var lookupEnv = os.LookupEnv
+
+// resolveCredentials 补充未显式提供的服务器、登录名和密码
+// 优先级：显式参数 > 环境变量 > 密码文件 > 交互输入
+func (c *CLI) resolveCredentials() error {
+	if server, ok := lookupEnv(envServer); ok && c.host == "" && server != "" {
+		host, port, hasPort := strings.Cut(server, ",")
+		if hasPort {
+			p, err := strconv.Atoi(strings.TrimSpace(port))
+			if err != nil || p <= 0 || p > 65535 {
+				return fmt.Errorf("invalid port in %s", envServer)
+			}
+			c.port = p
+		}
+		c.host = strings.TrimSpace(host)
+		if host, instance, ok := strings.Cut(c.host, `\`); ok && c.instance == "" {
+			c.host, c.instance = host, instance
+		}
+		// 连接串中没有服务器地址，改为由各字段生成
+		c.config.ConnectionString = ""
+	}
+	if user, ok := lookupEnv(envUser); ok && c.username == "" {
+		c.username = user
+	}
+	if c.username == "" || c.password != "" {
+		return nil
+	}
+	if password, ok := lookupEnv(envPassword); ok && password != "" {
+		c.password = password
+		return nil
+	}
+	if c.config.PasswordFile != "" {
+		password, err := readPasswordFile(c.config.PasswordFile)
+		if err != nil {
+			return err
+		}
+		c.password = password
+	}
+	return nil
+}
+
+// readPasswordFile 从文件或 fd:N 形式的文件描述符读取密码，去掉首尾空白
+func readPasswordFile(name string) (string, error) {
+	var data []byte
+	var err error
+	if fd, ok := strings.CutPrefix(name, "fd:"); ok {
+		n, convErr := strconv.Atoi(fd)
+		if convErr != nil || n < 0 {
+			return "", fmt.Errorf("invalid password file descriptor '%s'", name)
+		}
+		f := os.NewFile(uintptr(n), name)
+		if f == nil {
+			return "", fmt.Errorf("invalid password file descriptor '%s'", name)
+		}
+		defer f.Close()
+		data, err = io.ReadAll(f)
+	} else {
+		data, err = os.ReadFile(name)
+	}
+	if err != nil {
+		return "", fmt.Errorf("cannot read password file: %v", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// redactedError 隐藏错误信息中的密码，保留原始错误以便 errors.As 判断错误号
+type redactedError struct {
+	err    error
+	secret string
+}
+
+func (e *redactedError) Error() string {
+	return strings.ReplaceAll(e.err.Error(), e.secret, "****")
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// redact 隐藏错误信息中的当前密码，驱动可能在错误中回显连接串
+func (c *CLI) redact(err error) error {
+	if err == nil || c.password == "" {
+		return err
+	}
+	return &redactedError{err: err, secret: c.password}
+}
@@ -0,0 +1,136 @@
+package mssql
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeEnv 在测试期间用 env 替换环境变量
+func fakeEnv(t *testing.T, env map[string]string) {
+	t.Helper()
+	saved := lookupEnv
+	lookupEnv = func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+	t.Cleanup(func() { lookupEnv = saved })
+}
+
+func TestResolveCredentials(t *testing.T) {
+	passwordFile := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(passwordFile, []byte("  from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		config       Config
+		env          map[string]string
+		wantHost     string
+		wantPort     int
+		wantInstance string
+		wantUser     string
+		wantPassword string
+		wantErr      bool
+	}{
+		{
+			name:         "explicit values win over the environment",
+			config:       Config{Host: "db1", Port: 1433, Username: "sa", Password: "explicit", PasswordFile: passwordFile},
+			env:          map[string]string{envServer: "db2,2000", envUser: "env-user", envPassword: "env-password"},
+			wantHost:     "db1",
+			wantPort:     1433,
+			wantUser:     "sa",
+			wantPassword: "explicit",
+		},
+		{
+			name:         "environment fills missing values",
+			env:          map[string]string{envServer: "db2, 2000", envUser: "env-user", envPassword: "env-password"},
+			wantHost:     "db2",
+			wantPort:     2000,
+			wantUser:     "env-user",
+			wantPassword: "env-password",
+		},
+		{
+			name:         "environment server with instance",
+			env:          map[string]string{envServer: `db2\SQLEXPRESS`},
+			wantHost:     "db2",
+			wantInstance: "SQLEXPRESS",
+		},
+		{
+			name:         "environment password wins over password file",
+			config:       Config{Host: "db1", Username: "sa", PasswordFile: passwordFile},
+			env:          map[string]string{envPassword: "env-password"},
+			wantHost:     "db1",
+			wantUser:     "sa",
+			wantPassword: "env-password",
+		},
+		{
+			name:         "password file when the environment has no password",
+			config:       Config{Host: "db1", Username: "sa", PasswordFile: passwordFile},
+			env:          map[string]string{envPassword: ""},
+			wantHost:     "db1",
+			wantUser:     "sa",
+			wantPassword: "from-file",
+		},
+		{
+			name:         "environment user gets the password file",
+			config:       Config{Host: "db1", PasswordFile: passwordFile},
+			env:          map[string]string{envUser: "env-user"},
+			wantHost:     "db1",
+			wantUser:     "env-user",
+			wantPassword: "from-file",
+		},
+		{
+			name:     "no login means no password lookup",
+			config:   Config{Host: "db1", PasswordFile: passwordFile},
+			env:      map[string]string{envPassword: "env-password"},
+			wantHost: "db1",
+		},
+		{
+			name:     "nothing set leaves the password for the prompt",
+			config:   Config{Host: "db1", Username: "sa"},
+			env:      map[string]string{},
+			wantHost: "db1",
+			wantUser: "sa",
+		},
+		{
+			name:    "invalid port in the environment",
+			env:     map[string]string{envServer: "db2,http"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeEnv(t, tt.env)
+			c := NewCLIWithConfig(&testTerm{}, &tt.config)
+			err := c.resolveCredentials()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("resolveCredentials() = nil, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveCredentials() = %v", err)
+			}
+			if c.host != tt.wantHost || c.port != tt.wantPort || c.instance != tt.wantInstance {
+				t.Errorf("server = %q,%d\\%q, want %q,%d\\%q", c.host, c.port, c.instance, tt.wantHost, tt.wantPort, tt.wantInstance)
+			}
+			if c.username != tt.wantUser || c.password != tt.wantPassword {
+				t.Errorf("login = %q/%q, want %q/%q", c.username, c.password, tt.wantUser, tt.wantPassword)
+			}
+		})
+	}
+}
+
+func TestResolveCredentialsEnvServerReplacesConnectionString(t *testing.T) {
+	fakeEnv(t, map[string]string{envServer: "db2"})
+	c := NewCLIWithConfig(&testTerm{}, &Config{ConnectionString: "server=db1;database=app"})
+	if err := c.resolveCredentials(); err != nil {
+		t.Fatal(err)
+	}
+	if c.config.ConnectionString != "" || c.host != "db2" {
+		t.Errorf("host = %q, connection string = %q, want db2 and no connection string", c.host, c.config.ConnectionString)
+	}
+}
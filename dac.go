@@ -0,0 +1,104 @@
+package mssql
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/microsoft/go-mssqldb/msdsn"
+)
+
+// defaultDACPort 默认实例的专用管理员连接端口
+const defaultDACPort = 1434
+
+// dacMaxRows DAC 会话默认的最大显示行数，DAC 的资源配额很小
+const dacMaxRows = 100
+
+// parseAdminHost 解析 admin:host 形式的主机名，返回去掉前缀的主机名
+func parseAdminHost(host string) (string, bool) {
+	if len(host) > len("admin:") && strings.EqualFold(host[:len("admin:")], "admin:") {
+		return host[len("admin:"):], true
+	}
+	return host, false
+}
+
+// applyDACDefaults 为 DAC 会话设置保守的默认值：少量行、不缓存整个结果、不统计截断的行
+func (c *CLI) applyDACDefaults() {
+	c.maxRows = dacMaxRows
+	c.countLimit = -1
+	c.display.stream = true
+}
+
+// resolveDACPort 确定 DAC 端口：显式端口优先，否则向 SQL Browser 查询，默认实例回退到 1434
+func (c *CLI) resolveDACPort() (int, error) {
+	if c.port != 0 {
+		return c.port, nil
+	}
+	instance := c.instance
+	if instance == "" {
+		instance = "MSSQLSERVER"
+	}
+	port, err := browseDACPort(c.host, instance, 5*time.Second)
+	if err != nil {
+		if c.instance == "" {
+			return defaultDACPort, nil
+		}
+		return 0, fmt.Errorf("cannot resolve DAC port of instance %s via SQL Browser: %v", c.instance, err)
+	}
+	return port, nil
+}
+
+// browseDACPort 通过 SQL Browser 服务的 CLNT_UCAST_DAC 请求查询实例的 DAC 端口
+func browseDACPort(host, instance string, timeout time.Duration) (int, error) {
+	conn, err := net.DialTimeout("udp", net.JoinHostPort(host, "1434"), timeout)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	req := append([]byte{0x0F, 0x01}, instance...)
+	req = append(req, 0)
+	if _, err := conn.Write(req); err != nil {
+		return 0, err
+	}
+
+	// 响应：0x05、长度（2 字节）、版本 0x01、端口（2 字节，小端）
+	resp := make([]byte, 6)
+	n, err := conn.Read(resp)
+	if err != nil {
+		return 0, err
+	}
+	if n < 6 || resp[0] != 0x05 {
+		return 0, fmt.Errorf("unexpected SQL Browser response")
+	}
+	return int(binary.LittleEndian.Uint16(resp[4:6])), nil
+}
+
+// overrideServer 将连接串中的服务器地址替换为 host:port
+func overrideServer(dsn, host string, port int) string {
+	if strings.HasPrefix(dsn, "sqlserver://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return dsn
+		}
+		u.Host = net.JoinHostPort(host, strconv.Itoa(port))
+		u.Path = ""
+		return u.String()
+	}
+	// ADO 和 odbc: 形式改写为 odbc: 形式，值放在花括号中
+	cfg, err := msdsn.Parse(dsn)
+	if err != nil {
+		return dsn
+	}
+	params := make(map[string]string, len(cfg.Parameters)+2)
+	for k, v := range cfg.Parameters {
+		params[k] = v
+	}
+	params["server"], params["port"] = host, strconv.Itoa(port)
+	return odbcConnString(params)
+}
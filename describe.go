@@ -0,0 +1,186 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// describePattern 匹配 describe <query> 命令
+var describePattern = regexp.MustCompile(`(?is)^describe\s+(.+)$`)
+
+// describeColumns describe 命令输出的列
+var describeColumns = []string{"ordinal", "name", "type", "nullable"}
+
+// describeQuery 显示查询将返回的列信息而不执行查询
+// SQL Server 2012 起使用 sys.dm_exec_describe_first_result_set，更早的版本使用 SET FMTONLY
+func (c *CLI) describeQuery(query string) {
+	query = strings.TrimSuffix(strings.TrimSpace(query), ";")
+	if query == "" {
+		fmt.Fprintf(c.term, "Usage: describe <query>\n")
+		return
+	}
+	query = replaceParams(query)
+
+	startTime := time.Now()
+	ctx, cancel := c.statementContext()
+	defer cancel()
+	defer c.flushOutput()
+
+	var hasDMF bool
+	if err := c.conn.QueryRowContext(ctx, "SELECT CASE WHEN OBJECT_ID('sys.dm_exec_describe_first_result_set') IS NULL THEN 0 ELSE 1 END").Scan(&hasDMF); err != nil {
+		c.printError(err)
+		return
+	}
+
+	if hasDMF {
+		c.executeQuery(ctx, `SELECT column_ordinal AS [ordinal], name, system_type_name AS [type],
+	CASE is_nullable WHEN 1 THEN 'YES' ELSE 'NO' END AS [nullable]
+FROM sys.dm_exec_describe_first_result_set(@tsql, NULL, 0)
+ORDER BY column_ordinal`, startTime, false, sql.Named("tsql", query))
+		return
+	}
+
+	data, err := c.describeFMTOnly(ctx, query)
+	if err != nil {
+		c.printError(err)
+		return
+	}
+	c.renderValues(describeColumns, data, startTime)
+}
+
+// describeFMTOnly 在旧版本服务器上通过 SET FMTONLY 获取结果的列信息
+// 在会话连接上执行以便引用临时表，结束后总是关闭 FMTONLY
+func (c *CLI) describeFMTOnly(ctx context.Context, query string) ([][]interface{}, error) {
+	defer c.conn.ExecContext(context.Background(), "SET FMTONLY OFF")
+
+	rows, err := c.conn.QueryContext(ctx, "SET FMTONLY ON;\n"+query+"\n;SET FMTONLY OFF;")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	colTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	data := make([][]interface{}, len(colTypes))
+	for i, ct := range colTypes {
+		nullable := "NO"
+		if n, ok := ct.Nullable(); ok && n {
+			nullable = "YES"
+		}
+		data[i] = []interface{}{int64(i + 1), ct.Name(), columnTypeName(ct), nullable}
+	}
+	return data, rows.Err()
+}
+
+// renderValues 以当前输出格式输出客户端构造的结果集
+func (c *CLI) renderValues(cols []string, data [][]interface{}, startTime time.Time) {
+	f := c.resultFormatter("", cols, nil, c.expanded)
+	endPaging := c.beginPaging()
+	defer endPaging()
+
+	if err := f.BeginResult(cols, nil); err != nil {
+		fmt.Fprintf(c.out, "Error: %v\n\n", err)
+		return
+	}
+	c.outMu.Lock()
+	defer c.outMu.Unlock()
+	for _, row := range data {
+		if err := f.WriteRow(row); err != nil {
+			fmt.Fprintf(c.out, "Error: %v\n\n", err)
+			return
+		}
+	}
+	f.EndResult(ResultSummary{
+		RowCount: int64(len(data)),
+		Elapsed:  time.Since(startTime),
+		Timing:   c.timingEnabled,
+	})
+}
+
+// replaceParams 将语句中未声明的 @参数 替换为 NULL，以便在没有参数值时描述语句
+// 字符串、带引号的标识符和注释中的内容保持不变；包含 DECLARE 的语句不做替换
+func replaceParams(query string) string {
+	if declarePattern.MatchString(query) {
+		return query
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(query); {
+		ch := query[i]
+		switch {
+		case ch == '\'' || ch == '"' || ch == '[':
+			end := closingQuote(ch)
+			j := i + 1
+			for j < len(query) {
+				if query[j] == end {
+					// 连续两个结束符表示转义
+					if j+1 < len(query) && query[j+1] == end {
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			j = min(j+1, len(query))
+			b.WriteString(query[i:j])
+			i = j
+		case strings.HasPrefix(query[i:], "--"):
+			j := strings.IndexByte(query[i:], '\n')
+			if j < 0 {
+				j = len(query) - i
+			}
+			b.WriteString(query[i : i+j])
+			i += j
+		case strings.HasPrefix(query[i:], "/*"):
+			j := strings.Index(query[i+2:], "*/")
+			if j < 0 {
+				j = len(query) - i - 4
+			}
+			b.WriteString(query[i : i+j+4])
+			i += j + 4
+		case ch == '@' && i+1 < len(query) && query[i+1] == '@':
+			// @@ROWCOUNT 等系统函数
+			j := i + 2
+			for j < len(query) && isIdentChar(query[j]) {
+				j++
+			}
+			b.WriteString(query[i:j])
+			i = j
+		case ch == '@' && i+1 < len(query) && isIdentChar(query[i+1]):
+			j := i + 1
+			for j < len(query) && isIdentChar(query[j]) {
+				j++
+			}
+			b.WriteString("NULL")
+			i = j
+		default:
+			b.WriteByte(ch)
+			i++
+		}
+	}
+	return b.String()
+}
+
+// declarePattern 匹配 DECLARE 语句
+var declarePattern = regexp.MustCompile(`(?i)\bDECLARE\b`)
+
+// closingQuote 返回引号对应的结束符
+func closingQuote(ch byte) byte {
+	if ch == '[' {
+		return ']'
+	}
+	return ch
+}
+
+// isIdentChar 判断字符是否可以出现在标识符中
+func isIdentChar(ch byte) bool {
+	return ch == '_' || ch == '#' || ch == '$' || ch >= '0' && ch <= '9' ||
+		ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= 0x80
+}
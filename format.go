@@ -0,0 +1,1001 @@
+package mssql
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// Formatter 查询结果渲染器
+//
+// 每个结果集依次调用 BeginResult、若干次 WriteRow 和 EndResult。
+// 不返回结果集的语句只调用 EndResult，此时 summary 中仅包含影响行数。
+type Formatter interface {
+	// BeginResult 开始一个新的结果集
+	BeginResult(cols []string, colTypes []*sql.ColumnType) error
+	// WriteRow 写入一行，values 为驱动扫描出的原始值，NULL 为 nil
+	WriteRow(values []interface{}) error
+	// EndResult 结束当前结果集
+	EndResult(summary ResultSummary) error
+}
+
+// ResultSummary 结果集统计信息
+type ResultSummary struct {
+	RowCount int64         // 输出或影响的行数
+	Elapsed  time.Duration // 执行耗时
+	Timing   bool          // 是否显示耗时
+}
+
+// FormatterFactory 创建格式化器，w 为输出目标，arg 为 format 命令中格式名之后的参数
+type FormatterFactory func(w io.Writer, arg string) (Formatter, error)
+
+// formatters 已注册的输出格式
+var formatters = map[string]FormatterFactory{
+	"table": func(w io.Writer, arg string) (Formatter, error) {
+		return &tableFormatter{w: w}, nil
+	},
+	"plain":    newPlainFormatter,
+	"csv":      newCSVFormatter,
+	"json":     newJSONFormatter,
+	"html":     newHTMLFormatter,
+	"tsv":      newTSVFormatter,
+	"vertical": newVerticalFormatter,
+	"insert":   newInsertFormatter,
+	"template": newTemplateFormatter,
+}
+
+// RegisterFormatter 注册自定义输出格式，同名格式将被覆盖
+func RegisterFormatter(name string, factory FormatterFactory) {
+	formatters[strings.ToLower(name)] = factory
+}
+
+// formatterNames 返回已注册格式的名称列表
+func formatterNames() []string {
+	names := make([]string, 0, len(formatters))
+	for name := range formatters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// documentFormatter 由将各行视为同一文档分块的格式化器实现，不受 maxRows 限制
+type documentFormatter interface {
+	document()
+}
+
+// displaySettings 影响内置格式显示效果的会话设置
+type displaySettings struct {
+	alignNumbers bool   // 数值列右对齐
+	dateLayout   string // 时间格式，为空时按列类型使用默认格式
+	fullHex      bool   // 表格中完整显示二进制值，不截断
+	numbers      numberFormat
+	color        bool // 是否输出 ANSI 颜色
+	stream       bool // 表格格式是否流式输出
+	width        int  // 表格可用的屏幕宽度，0 表示不限制
+	truncate     bool // 表格中是否截断超过 maxCellWidth 的值
+	headers      bool // 是否输出列名（表头）
+	footer       bool // 是否输出行数统计和耗时
+	quiet        bool // 安静模式，不输出行数统计等提示信息
+	showTypes    bool // 表格表头下方是否显示列类型
+}
+
+// newDisplaySettings 返回默认的显示设置
+func newDisplaySettings() displaySettings {
+	return displaySettings{
+		alignNumbers: true,
+		truncate:     true,
+		headers:      true,
+		footer:       true,
+		numbers:      numberFormat{moneyDecimals: -1, floatPrecision: -1},
+	}
+}
+
+// displayAware 由需要读取会话显示设置的格式化器实现
+type displayAware interface {
+	setDisplay(d *displaySettings)
+}
+
+// displayBase 嵌入到内置格式化器中以获得显示设置和列类型
+type displayBase struct {
+	display *displaySettings
+	types   []string
+	scales  []int // 时间列的小数秒位数
+}
+
+func (b *displayBase) setDisplay(d *displaySettings) {
+	b.display = d
+}
+
+// setTypes 记录当前结果集各列的数据库类型
+func (b *displayBase) setTypes(colTypes []*sql.ColumnType) {
+	b.types = typeNames(colTypes)
+	b.scales = timeScales(colTypes)
+}
+
+// typeName 返回第 i 列的数据库类型，未知时为空串
+func (b *displayBase) typeName(i int) string {
+	return typeNameAt(b.types, i)
+}
+
+// cell 按显示设置转换第 i 列的非 NULL 值
+func (b *displayBase) cell(i int, v interface{}) string {
+	scale := -1
+	if i < len(b.scales) {
+		scale = b.scales[i]
+	}
+	return b.display.formatCell(v, b.typeName(i), scale)
+}
+
+// displayCell 与 cell 相同，但额外应用数值显示格式，仅用于面向阅读的格式
+func (b *displayBase) displayCell(i int, v interface{}) string {
+	if s, ok := b.display.formatNumber(v, b.typeName(i)); ok {
+		return s
+	}
+	return b.cell(i, v)
+}
+
+// statementFormatter 由需要知道当前语句文本的格式化器实现
+type statementFormatter interface {
+	setStatement(sqlStr string)
+}
+
+// setFormat 切换查询结果输出格式
+func (c *CLI) setFormat(args string) {
+	name, arg, _ := strings.Cut(args, " ")
+	name = strings.ToLower(name)
+	arg = strings.TrimSpace(arg)
+
+	if name == "" {
+		fmt.Fprintf(c.term, "Available formats:\n")
+		for _, n := range formatterNames() {
+			marker := " "
+			if n == c.outputFormat {
+				marker = "*"
+			}
+			fmt.Fprintf(c.term, " %s %s\n", marker, n)
+		}
+		return
+	}
+
+	factory, ok := formatters[name]
+	if !ok {
+		fmt.Fprintf(c.term, "Unknown format '%s' (available: %s)\n", name, strings.Join(formatterNames(), ", "))
+		return
+	}
+
+	f, err := factory(c.out, arg)
+	if err != nil {
+		fmt.Fprintf(c.term, "Error: %v\n", err)
+		return
+	}
+	c.formatter = f
+	c.outputFormat = name
+	fmt.Fprintf(c.term, "Output format set to %s\n", name)
+}
+
+// formatValue 将扫描出的值转换为显示字符串（非 NULL）
+func formatValue(v interface{}) string {
+	switch val := v.(type) {
+	case []byte:
+		return string(val)
+	case time.Time:
+		return val.Format("2006-01-02 15:04:05")
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// scanRow 扫描当前行的所有列
+func scanRow(rows *sql.Rows, n int) ([]interface{}, error) {
+	vals := make([]interface{}, n)
+	valPtrs := make([]interface{}, n)
+	for i := range vals {
+		valPtrs[i] = &vals[i]
+	}
+	if err := rows.Scan(valPtrs...); err != nil {
+		return nil, err
+	}
+	return vals, nil
+}
+
+// typeNames 返回各列的数据库类型名称
+func typeNames(colTypes []*sql.ColumnType) []string {
+	names := make([]string, len(colTypes))
+	for i, ct := range colTypes {
+		names[i] = ct.DatabaseTypeName()
+	}
+	return names
+}
+
+// timeScales 返回各列的小数秒位数（time、datetime2 和 datetimeoffset 的 scale），驱动没有报告时为 -1
+func timeScales(colTypes []*sql.ColumnType) []int {
+	scales := make([]int, len(colTypes))
+	for i, ct := range colTypes {
+		scales[i] = -1
+		if _, scale, ok := ct.DecimalSize(); ok {
+			scales[i] = int(scale)
+		}
+	}
+	return scales
+}
+
+// typeNameAt 返回第 i 列的类型名称，客户端构造的结果集没有类型信息时为空串
+func typeNameAt(names []string, i int) string {
+	if i < len(names) {
+		return names[i]
+	}
+	return ""
+}
+
+// typeLabel 返回列类型的完整描述，如 nvarchar(100)、decimal(10,2)，可空列以 ? 结尾
+func typeLabel(ct *sql.ColumnType) string {
+	name := columnTypeName(ct)
+	if nullable, ok := ct.Nullable(); ok && nullable {
+		name += "?"
+	}
+	return name
+}
+
+// columnTypeName 返回包含长度、精度和小数位数的列类型名称
+func columnTypeName(ct *sql.ColumnType) string {
+	name := strings.ToLower(ct.DatabaseTypeName())
+	switch name {
+	case "char", "varchar", "nchar", "nvarchar", "binary", "varbinary":
+		if length, ok := ct.Length(); ok {
+			// 驱动对 (max) 类型报告的长度不小于 1073741822
+			if length >= 1073741822 {
+				name += "(max)"
+			} else {
+				name += fmt.Sprintf("(%d)", length)
+			}
+		}
+	case "decimal", "numeric":
+		if precision, scale, ok := ct.DecimalSize(); ok {
+			name += fmt.Sprintf("(%d,%d)", precision, scale)
+		}
+	}
+	return name
+}
+
+// writeSummary 输出影响行数和耗时
+func writeSummary(w io.Writer, summary ResultSummary) {
+	if summary.RowCount == 0 {
+		fmt.Fprintf(w, "(0 rows affected)\n")
+	} else if summary.RowCount == 1 {
+		fmt.Fprintf(w, "(1 row affected)\n")
+	} else {
+		fmt.Fprintf(w, "(%d rows affected)\n", summary.RowCount)
+	}
+
+	if summary.Timing {
+		fmt.Fprintf(w, "Time: %.3f sec\n", summary.Elapsed.Seconds())
+	}
+	fmt.Fprintf(w, "\n")
+}
+
+// showHeaders 判断是否输出表头
+func (b *displayBase) showHeaders() bool {
+	return b.display == nil || b.display.headers
+}
+
+// showFooter 判断是否输出行数统计和耗时
+func (b *displayBase) showFooter() bool {
+	return b.display == nil || (b.display.footer && !b.display.quiet)
+}
+
+// writeSummary 输出影响行数和耗时，启用颜色时以暗色显示
+func (b *displayBase) writeSummary(w io.Writer, summary ResultSummary) {
+	if !b.showFooter() {
+		return
+	}
+	if b.display == nil || !b.display.color {
+		writeSummary(w, summary)
+		return
+	}
+	var buf strings.Builder
+	writeSummary(&buf, summary)
+	text := strings.TrimSuffix(buf.String(), "\n\n")
+	fmt.Fprintf(w, "%s\n\n", b.display.colorize(text, ansiDim))
+}
+
+// streamSampleRows 流式输出时用于确定列宽的样本行数
+const streamSampleRows = 50
+
+// tableFormatter 默认的 ASCII 表格格式，缓存所有行以计算列宽
+// 开启流式输出时仅缓存前 streamSampleRows 行用于确定列宽，之后逐行输出
+type tableFormatter struct {
+	displayBase
+	w          io.Writer
+	plain      bool // 无边框格式
+	trim       bool // plain 格式下不输出最后一列的尾部空格
+	started    bool
+	streaming  bool // 表头已输出，后续行直接输出且列宽固定
+	vertical   bool // 最小列宽仍超出屏幕宽度，改为纵向输出
+	rowNum     int64
+	cols       []string
+	labels     []string // 列类型描述，仅在显示列类型时使用
+	colWidths  []int
+	rightAlign []bool
+	rows       [][]string
+	nulls      [][]bool
+}
+
+// newPlainFormatter 创建无边框格式，参数 -w 表示去除尾部空格
+func newPlainFormatter(w io.Writer, arg string) (Formatter, error) {
+	f := &tableFormatter{w: w, plain: true}
+	switch arg {
+	case "":
+	case "-w", "trim":
+		f.trim = true
+	default:
+		return nil, fmt.Errorf("usage: format plain [-w]")
+	}
+	return f, nil
+}
+
+func (f *tableFormatter) BeginResult(cols []string, colTypes []*sql.ColumnType) error {
+	f.started = true
+	f.streaming = false
+	f.cols = cols
+	f.rows = nil
+	f.nulls = nil
+	f.setTypes(colTypes)
+	f.colWidths = make([]int, len(cols))
+	f.rightAlign = make([]bool, len(cols))
+	if f.display != nil && f.display.alignNumbers {
+		for i, typeName := range typeNames(colTypes) {
+			f.rightAlign[i] = isNumericType(typeName)
+		}
+	}
+	f.labels = nil
+	if f.display != nil && f.display.showTypes && f.display.headers && len(colTypes) == len(cols) {
+		f.labels = make([]string, len(cols))
+		for i, ct := range colTypes {
+			f.labels[i] = typeLabel(ct)
+		}
+	}
+	for i := range cols {
+		f.colWidths[i] = f.minWidth(i)
+	}
+	return nil
+}
+
+// minWidth 返回第 i 列的最小列宽：列名与类型描述中较宽者
+func (f *tableFormatter) minWidth(i int) int {
+	width := f.headerWidth(f.cols[i])
+	if f.labels != nil {
+		width = max(width, f.headerWidth(f.labels[i]))
+	}
+	return width
+}
+
+// maxCellWidth 开启截断时单元格的最大显示宽度
+const maxCellWidth = 50
+
+// truncating 判断是否截断过长的值
+func (f *tableFormatter) truncating() bool {
+	return f.display == nil || f.display.truncate
+}
+
+// headerWidth 返回列名占用的列宽，至少 4 列，开启截断时最多 maxCellWidth 列
+func (f *tableFormatter) headerWidth(col string) int {
+	width := max(runewidth.StringWidth(col), 4)
+	if f.truncating() {
+		width = min(width, maxCellWidth)
+	}
+	return width
+}
+
+func (f *tableFormatter) WriteRow(values []interface{}) error {
+	rowStrs := make([]string, len(values))
+	nulls := make([]bool, len(values))
+	for i, v := range values {
+		if v == nil {
+			rowStrs[i] = "NULL"
+			nulls[i] = true
+		} else {
+			rowStrs[i] = f.displayCell(i, v)
+		}
+
+		if len(rowStrs[i]) > maxCellWidth && v != nil && isBinaryType(f.typeName(i)) {
+			if f.display != nil && (f.display.fullHex || !f.display.truncate) {
+				if !f.streaming {
+					f.colWidths[i] = max(f.colWidths[i], len(rowStrs[i]))
+				}
+				continue
+			}
+			rowStrs[i] = truncateHex(rowStrs[i], maxCellWidth)
+		}
+
+		// 流式输出时列宽已固定，超宽的值在填充时截断
+		if f.streaming {
+			continue
+		}
+
+		// 按显示宽度计算，中日韩等宽字符占两列
+		width := runewidth.StringWidth(rowStrs[i])
+		if width > f.colWidths[i] {
+			if width > maxCellWidth && f.truncating() {
+				f.colWidths[i] = maxCellWidth
+				rowStrs[i] = runewidth.Truncate(rowStrs[i], maxCellWidth, "...")
+			} else {
+				f.colWidths[i] = width
+			}
+		}
+	}
+
+	if f.streaming {
+		return f.printRow(rowStrs, nulls)
+	}
+
+	f.rows = append(f.rows, rowStrs)
+	f.nulls = append(f.nulls, nulls)
+
+	if f.display != nil && f.display.stream && len(f.rows) >= streamSampleRows {
+		f.printHeader()
+		f.printBuffered()
+		f.streaming = true
+	}
+	return nil
+}
+
+func (f *tableFormatter) EndResult(summary ResultSummary) error {
+	if f.started {
+		if !f.streaming {
+			f.printHeader()
+			f.printBuffered()
+		}
+		f.printFooter()
+	}
+	f.started = false
+	f.streaming = false
+	f.rows = nil
+	f.nulls = nil
+
+	f.writeSummary(f.w, summary)
+	return nil
+}
+
+// fitWidth 按屏幕宽度收缩列宽，优先收缩最宽的文本列，且不小于列名宽度；
+// 最小列宽仍放不下时改为纵向输出
+func (f *tableFormatter) fitWidth() {
+	f.vertical = false
+	f.rowNum = 0
+	// 关闭截断时完整输出所有值，允许行折行
+	if f.display == nil || f.display.width <= 0 || !f.display.truncate || len(f.cols) == 0 {
+		return
+	}
+
+	// 边框格式每列占 "| " 与 " " 三个字符再加行尾的 "|"，无边框格式列间一个空格
+	overhead := 3*len(f.cols) + 1
+	if f.plain {
+		overhead = len(f.cols) - 1
+	}
+
+	total, minTotal := overhead, overhead
+	mins := make([]int, len(f.cols))
+	for i := range f.cols {
+		mins[i] = f.minWidth(i)
+		total += f.colWidths[i]
+		minTotal += mins[i]
+	}
+	if total <= f.display.width {
+		return
+	}
+	if minTotal > f.display.width {
+		f.vertical = true
+		return
+	}
+
+	for excess := total - f.display.width; excess > 0; excess-- {
+		widest := -1
+		for i := range f.cols {
+			if f.colWidths[i] <= mins[i] {
+				continue
+			}
+			// 文本列优先于数值列收缩
+			if widest < 0 || f.shrinkBefore(i, widest) {
+				widest = i
+			}
+		}
+		f.colWidths[widest]--
+	}
+}
+
+// shrinkBefore 判断第 i 列是否应先于第 j 列收缩
+func (f *tableFormatter) shrinkBefore(i, j int) bool {
+	iText := !isNumericType(f.typeName(i))
+	jText := !isNumericType(f.typeName(j))
+	if iText != jText {
+		return iText
+	}
+	return f.colWidths[i] > f.colWidths[j]
+}
+
+// printHeader 输出表头
+func (f *tableFormatter) printHeader() {
+	f.fitWidth()
+	if f.vertical {
+		return
+	}
+	if !f.showHeaders() {
+		if !f.plain {
+			f.printSeparator()
+		}
+		return
+	}
+
+	header := make([]string, len(f.cols))
+	for i, col := range f.cols {
+		header[i] = f.display.colorize(f.cellText(i, col), ansiBold)
+	}
+	var labels []string
+	if f.labels != nil {
+		labels = make([]string, len(f.labels))
+		for i, label := range f.labels {
+			labels[i] = f.display.colorize(f.cellText(i, label), ansiDim)
+		}
+	}
+
+	if f.plain {
+		fmt.Fprintf(f.w, "%s\n", strings.Join(header, " "))
+		if labels != nil {
+			fmt.Fprintf(f.w, "%s\n", strings.Join(labels, " "))
+		}
+		dashes := make([]string, len(f.colWidths))
+		for i, width := range f.colWidths {
+			dashes[i] = strings.Repeat("-", width)
+		}
+		fmt.Fprintf(f.w, "%s\n", strings.Join(dashes, " "))
+		return
+	}
+
+	f.printSeparator()
+	fmt.Fprintf(f.w, "| %s |\n", strings.Join(header, " | "))
+	if labels != nil {
+		fmt.Fprintf(f.w, "| %s |\n", strings.Join(labels, " | "))
+	}
+	f.printSeparator()
+}
+
+// printBuffered 输出已缓存的行
+func (f *tableFormatter) printBuffered() {
+	for r, row := range f.rows {
+		f.printRow(row, f.nulls[r])
+	}
+	f.rows = nil
+	f.nulls = nil
+}
+
+// printRow 输出一行，颜色在填充之后添加以免影响列宽计算
+func (f *tableFormatter) printRow(row []string, nulls []bool) error {
+	if f.vertical {
+		return f.printVerticalRow(row, nulls)
+	}
+
+	cells := make([]string, len(row))
+	for i, val := range row {
+		cells[i] = f.cellText(i, val)
+		if nulls[i] {
+			cells[i] = f.display.colorize(cells[i], ansiDim)
+		}
+	}
+
+	var err error
+	if f.plain {
+		_, err = fmt.Fprintf(f.w, "%s\n", strings.Join(cells, " "))
+	} else {
+		_, err = fmt.Fprintf(f.w, "| %s |\n", strings.Join(cells, " | "))
+	}
+	return err
+}
+
+// printVerticalRow 以纵向格式输出一行，用于表格超出屏幕宽度的情况
+func (f *tableFormatter) printVerticalRow(row []string, nulls []bool) error {
+	nameWidth := 0
+	for _, col := range f.cols {
+		nameWidth = max(nameWidth, runewidth.StringWidth(col))
+	}
+
+	f.rowNum++
+	fmt.Fprintf(f.w, "%s row %d %s\n", strings.Repeat("*", 15), f.rowNum, strings.Repeat("*", 15))
+	for i, val := range row {
+		if nulls[i] {
+			val = f.display.colorize(val, ansiDim)
+		}
+		name := strings.Repeat(" ", nameWidth-runewidth.StringWidth(f.cols[i])) + f.cols[i]
+		if _, err := fmt.Fprintf(f.w, "%s: %s\n", f.display.colorize(name, ansiBold), val); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printFooter 输出表格底部
+func (f *tableFormatter) printFooter() {
+	if !f.plain && !f.vertical {
+		f.printSeparator()
+	}
+}
+
+// cellText 填充单元格，plain 格式开启 trim 时不输出最后一列的尾部空格
+func (f *tableFormatter) cellText(i int, val string) string {
+	if f.plain && f.trim && i == len(f.cols)-1 && !f.rightAlign[i] {
+		if !f.truncating() {
+			return val
+		}
+		return runewidth.Truncate(val, f.colWidths[i], "...")
+	}
+	return f.pad(i, val)
+}
+
+// pad 按列宽和对齐方式填充单元格，超出列宽的内容（如过长的列名）会被截断
+func (f *tableFormatter) pad(i int, val string) string {
+	width := runewidth.StringWidth(val)
+	if width > f.colWidths[i] {
+		if !f.truncating() {
+			return val
+		}
+		val = runewidth.Truncate(val, f.colWidths[i], "...")
+		width = runewidth.StringWidth(val)
+	}
+	padding := strings.Repeat(" ", f.colWidths[i]-width)
+	if f.rightAlign[i] {
+		return padding + val
+	}
+	return val + padding
+}
+
+// printSeparator 打印表格分隔线
+func (f *tableFormatter) printSeparator() {
+	fmt.Fprintf(f.w, "+")
+	for _, width := range f.colWidths {
+		fmt.Fprintf(f.w, "%s+", strings.Repeat("-", width+2))
+	}
+	fmt.Fprintf(f.w, "\n")
+}
+
+// csvFormatter 以 RFC 4180 CSV 格式输出结果，NULL 输出为空字段
+type csvFormatter struct {
+	displayBase
+	w   io.Writer
+	csv *csv.Writer
+}
+
+func newCSVFormatter(w io.Writer, arg string) (Formatter, error) {
+	return &csvFormatter{w: w}, nil
+}
+
+func (f *csvFormatter) BeginResult(cols []string, colTypes []*sql.ColumnType) error {
+	f.setTypes(colTypes)
+	f.csv = csv.NewWriter(f.w)
+	f.csv.UseCRLF = true
+	if !f.showHeaders() {
+		return nil
+	}
+	return f.csv.Write(cols)
+}
+
+func (f *csvFormatter) WriteRow(values []interface{}) error {
+	record := make([]string, len(values))
+	for i, v := range values {
+		if v != nil {
+			record[i] = f.cell(i, v)
+		}
+	}
+	return f.csv.Write(record)
+}
+
+func (f *csvFormatter) EndResult(summary ResultSummary) error {
+	if f.csv != nil {
+		f.csv.Flush()
+		f.csv = nil
+	}
+	if f.showFooter() {
+		writeSummary(f.w, summary)
+	}
+	return nil
+}
+
+// jsonFormatter 以 JSON 对象数组输出结果，不输出行数统计以保证输出为合法 JSON
+type jsonFormatter struct {
+	w         io.Writer
+	started   bool
+	keys      []string
+	typeNames []string
+	rowCount  int
+}
+
+func newJSONFormatter(w io.Writer, arg string) (Formatter, error) {
+	return &jsonFormatter{w: w}, nil
+}
+
+func (f *jsonFormatter) BeginResult(cols []string, colTypes []*sql.ColumnType) error {
+	f.started = true
+	f.keys = uniqueColumnNames(cols)
+	f.typeNames = typeNames(colTypes)
+	f.rowCount = 0
+	_, err := fmt.Fprintf(f.w, "[")
+	return err
+}
+
+func (f *jsonFormatter) WriteRow(values []interface{}) error {
+	var b strings.Builder
+	if f.rowCount > 0 {
+		b.WriteString(",")
+	}
+	b.WriteString("\n  {")
+	for i, v := range values {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		key, _ := json.Marshal(f.keys[i])
+		val, err := json.Marshal(jsonValue(v, typeNameAt(f.typeNames, i)))
+		if err != nil {
+			val, _ = json.Marshal(formatValue(v))
+		}
+		b.Write(key)
+		b.WriteString(": ")
+		b.Write(val)
+	}
+	b.WriteString("}")
+	f.rowCount++
+
+	_, err := io.WriteString(f.w, b.String())
+	return err
+}
+
+func (f *jsonFormatter) EndResult(summary ResultSummary) error {
+	if !f.started {
+		return nil
+	}
+	f.started = false
+	if f.rowCount > 0 {
+		fmt.Fprintf(f.w, "\n")
+	}
+	_, err := fmt.Fprintf(f.w, "]\n")
+	return err
+}
+
+// jsonValue 将扫描出的值转换为适合 JSON 编码的值
+func jsonValue(v interface{}, typeName string) interface{} {
+	switch val := v.(type) {
+	case nil:
+		return nil
+	case time.Time:
+		return val.Format(time.RFC3339Nano)
+	case []byte:
+		switch {
+		case isNumericType(typeName):
+			return json.Number(string(val))
+		case typeName == "UNIQUEIDENTIFIER":
+			return formatGUID(val)
+		case isBinaryType(typeName):
+			// encoding/json 将 []byte 编码为 base64
+			return val
+		default:
+			return string(val)
+		}
+	default:
+		return val
+	}
+}
+
+// uniqueColumnNames 为重复的列名追加 _1、_2 等后缀
+func uniqueColumnNames(cols []string) []string {
+	seen := make(map[string]bool, len(cols))
+	for _, col := range cols {
+		seen[col] = true
+	}
+
+	used := make(map[string]bool, len(cols))
+	names := make([]string, len(cols))
+	for i, col := range cols {
+		name := col
+		// 生成的名称不能与已使用的名称或其他原始列名冲突
+		for n := 1; used[name] || (name != col && seen[name]); n++ {
+			name = col + "_" + strconv.Itoa(n)
+		}
+		used[name] = true
+		names[i] = name
+	}
+	return names
+}
+
+// verticalFormatter 以纵向格式逐行输出结果，每列一行 "列名: 值"，不截断长值
+type verticalFormatter struct {
+	displayBase
+	w         io.Writer
+	cols      []string
+	nameWidth int
+	rowCount  int64
+}
+
+func newVerticalFormatter(w io.Writer, arg string) (Formatter, error) {
+	return &verticalFormatter{w: w}, nil
+}
+
+func (f *verticalFormatter) BeginResult(cols []string, colTypes []*sql.ColumnType) error {
+	f.cols = cols
+	f.rowCount = 0
+	f.nameWidth = 0
+	f.setTypes(colTypes)
+	for _, col := range cols {
+		if w := runewidth.StringWidth(col); w > f.nameWidth {
+			f.nameWidth = w
+		}
+	}
+	return nil
+}
+
+func (f *verticalFormatter) WriteRow(values []interface{}) error {
+	f.rowCount++
+	fmt.Fprintf(f.w, "%s row %d %s\n", strings.Repeat("*", 15), f.rowCount, strings.Repeat("*", 15))
+	for i, v := range values {
+		val := f.display.colorize("NULL", ansiDim)
+		if v != nil {
+			val = f.displayCell(i, v)
+		}
+		name := strings.Repeat(" ", f.nameWidth-runewidth.StringWidth(f.cols[i])) + f.cols[i]
+		name = f.display.colorize(name, ansiBold)
+		if _, err := fmt.Fprintf(f.w, "%s: %s\n", name, val); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *verticalFormatter) EndResult(summary ResultSummary) error {
+	f.writeSummary(f.w, summary)
+	return nil
+}
+
+// isNumericType 判断数据库类型是否为数值类型
+func isNumericType(typeName string) bool {
+	switch typeName {
+	case "TINYINT", "SMALLINT", "INT", "BIGINT", "DECIMAL", "NUMERIC",
+		"MONEY", "SMALLMONEY", "FLOAT", "REAL", "BIT":
+		return true
+	}
+	return false
+}
+
+// isBinaryType 判断数据库类型是否为二进制类型
+func isBinaryType(typeName string) bool {
+	switch typeName {
+	case "BINARY", "VARBINARY", "IMAGE", "TIMESTAMP":
+		return true
+	}
+	return false
+}
+
+// columnClass 返回列在 HTML 输出中使用的 class 名称
+func columnClass(typeName string) string {
+	switch {
+	case isNumericType(typeName):
+		return "num"
+	case isBinaryType(typeName):
+		return "binary"
+	}
+	switch typeName {
+	case "DATE", "TIME", "DATETIME", "DATETIME2", "SMALLDATETIME", "DATETIMEOFFSET":
+		return "datetime"
+	}
+	return "text"
+}
+
+// htmlFormatter 以独立的 HTML <table> 输出结果
+type htmlFormatter struct {
+	displayBase
+	w       io.Writer
+	started bool
+	classes []string
+}
+
+func newHTMLFormatter(w io.Writer, arg string) (Formatter, error) {
+	return &htmlFormatter{w: w}, nil
+}
+
+func (f *htmlFormatter) BeginResult(cols []string, colTypes []*sql.ColumnType) error {
+	f.started = true
+	f.setTypes(colTypes)
+	f.classes = make([]string, len(cols))
+	for i, typeName := range typeNames(colTypes) {
+		f.classes[i] = columnClass(typeName)
+	}
+
+	var b strings.Builder
+	b.WriteString("<table>\n")
+	if f.showHeaders() {
+		b.WriteString("<thead>\n<tr>")
+		for i, col := range cols {
+			fmt.Fprintf(&b, "<th class=\"%s\">%s</th>", f.classes[i], html.EscapeString(col))
+		}
+		b.WriteString("</tr>\n</thead>\n")
+	}
+	b.WriteString("<tbody>\n")
+	_, err := io.WriteString(f.w, b.String())
+	return err
+}
+
+func (f *htmlFormatter) WriteRow(values []interface{}) error {
+	var b strings.Builder
+	b.WriteString("<tr>")
+	for i, v := range values {
+		cell := "<em>NULL</em>"
+		if val, ok := v.([]byte); ok && f.classes[i] == "binary" {
+			cell = formatHex(val)
+		} else if v != nil {
+			// 其余值与其他格式一样经过 formatCell，UNIQUEIDENTIFIER 等类型才能正确显示
+			cell = html.EscapeString(f.cell(i, v))
+		}
+		fmt.Fprintf(&b, "<td class=\"%s\">%s</td>", f.classes[i], cell)
+	}
+	b.WriteString("</tr>\n")
+	_, err := io.WriteString(f.w, b.String())
+	return err
+}
+
+func (f *htmlFormatter) EndResult(summary ResultSummary) error {
+	if f.started {
+		fmt.Fprintf(f.w, "</tbody>\n</table>\n")
+		f.started = false
+	}
+	if f.showFooter() {
+		writeSummary(f.w, summary)
+	}
+	return nil
+}
+
+// tsvEscaper 转义 TSV 字段中的反斜杠、制表符和换行符
+var tsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+// tsvFormatter 以制表符分隔格式输出结果，NULL 输出为 \N，不输出分隔线和行数统计
+type tsvFormatter struct {
+	displayBase
+	w io.Writer
+}
+
+func newTSVFormatter(w io.Writer, arg string) (Formatter, error) {
+	return &tsvFormatter{w: w}, nil
+}
+
+func (f *tsvFormatter) BeginResult(cols []string, colTypes []*sql.ColumnType) error {
+	f.setTypes(colTypes)
+	if !f.showHeaders() {
+		return nil
+	}
+	header := make([]string, len(cols))
+	for i, col := range cols {
+		header[i] = tsvEscaper.Replace(col)
+	}
+	_, err := fmt.Fprintf(f.w, "%s\n", strings.Join(header, "\t"))
+	return err
+}
+
+func (f *tsvFormatter) WriteRow(values []interface{}) error {
+	fields := make([]string, len(values))
+	for i, v := range values {
+		if v == nil {
+			fields[i] = `\N`
+		} else {
+			fields[i] = tsvEscaper.Replace(f.cell(i, v))
+		}
+	}
+	_, err := fmt.Fprintf(f.w, "%s\n", strings.Join(fields, "\t"))
+	return err
+}
+
+func (f *tsvFormatter) EndResult(summary ResultSummary) error {
+	return nil
+}
@@ -0,0 +1,262 @@
+package mssql
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// newTable 返回默认的表格格式化器
+func newTable(w io.Writer) Formatter {
+	return &tableFormatter{w: w}
+}
+
+// borderColumns 返回一行表格中边框字符（+ 和 |）所在的显示列
+func borderColumns(line string) []int {
+	var cols []int
+	pos := 0
+	for _, r := range line {
+		if r == '+' || r == '|' {
+			cols = append(cols, pos)
+		}
+		pos += runewidth.RuneWidth(r)
+	}
+	return cols
+}
+
+// assertAligned 检查表格每一行的边框都在同一组显示列上
+func assertAligned(t *testing.T, out string) {
+	t.Helper()
+	var want []int
+	for _, line := range strings.Split(out, "\n") {
+		if !strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "|") {
+			continue
+		}
+		got := borderColumns(line)
+		if want == nil {
+			want = got
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("border columns %v, want %v in line %q\n%s", got, want, line, out)
+		}
+	}
+	if want == nil {
+		t.Fatalf("no table rendered:\n%s", out)
+	}
+}
+
+func TestTableWideCharacterAlignment(t *testing.T) {
+	cols := []fakeColumn{
+		{name: "id", typeName: "INT"},
+		{name: "名前", typeName: "NVARCHAR"},
+		{name: "note", typeName: "NVARCHAR"},
+	}
+	tests := []struct {
+		name string
+		rows [][]interface{}
+	}{
+		{
+			name: "ascii",
+			rows: [][]interface{}{{int64(1), "alice", "plain text"}},
+		},
+		{
+			name: "cjk",
+			rows: [][]interface{}{
+				{int64(1), "山田太郎", "東京都"},
+				{int64(2), "김철수", "서울"},
+			},
+		},
+		{
+			name: "emoji",
+			rows: [][]interface{}{
+				{int64(1), "😀", "🍣🍺"},
+				{int64(22), "ok 👍", nil},
+			},
+		},
+		{
+			name: "mixed",
+			rows: [][]interface{}{
+				{int64(1), "bob", "中文 and ASCII"},
+				{int64(333), "李雷 Li Lei", "🎉 party 派对"},
+				{nil, "", "x"},
+			},
+		},
+		{
+			name: "truncated",
+			rows: [][]interface{}{
+				{int64(1), strings.Repeat("漢字", 30), strings.Repeat("a😀", 30)},
+				{int64(2), "short", "短"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			display := newDisplaySettings()
+			out := renderResult(t, newTable, &display, cols, tt.rows...)
+			assertAligned(t, out)
+		})
+	}
+}
+
+func TestTableWideCharacterFitWidth(t *testing.T) {
+	display := newDisplaySettings()
+	display.width = 30
+	cols := []fakeColumn{
+		{name: "id", typeName: "INT"},
+		{name: "名前", typeName: "NVARCHAR"},
+	}
+	out := renderResult(t, newTable, &display, cols,
+		[]interface{}{int64(1), strings.Repeat("山田", 20)},
+		[]interface{}{int64(2), "a😀b😀c😀d😀e😀f😀g😀h😀"},
+	)
+	assertAligned(t, out)
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if strings.HasPrefix(line, "+") || strings.HasPrefix(line, "|") {
+			if w := runewidth.StringWidth(line); w > display.width {
+				t.Errorf("line %q is %d columns wide, want at most %d", line, w, display.width)
+			}
+		}
+	}
+}
+
+func TestIsNumericType(t *testing.T) {
+	for _, typeName := range []string{"TINYINT", "SMALLINT", "INT", "BIGINT", "DECIMAL", "NUMERIC", "MONEY", "SMALLMONEY", "FLOAT", "REAL", "BIT"} {
+		if !isNumericType(typeName) {
+			t.Errorf("isNumericType(%q) = false, want true", typeName)
+		}
+	}
+	for _, typeName := range []string{"", "VARCHAR", "NVARCHAR", "CHAR", "DATETIME2", "DATE", "UNIQUEIDENTIFIER", "VARBINARY", "XML", "SQL_VARIANT", "int"} {
+		if isNumericType(typeName) {
+			t.Errorf("isNumericType(%q) = true, want false", typeName)
+		}
+	}
+}
+
+// tableCells 返回带边框表格中各数据行的单元格（包含填充的空格，不含两侧各一个空格）
+func tableCells(out string) [][]string {
+	var rows [][]string
+	lines := strings.Split(out, "\n")
+	// 跳过表头：第一条分隔线、列名和第二条分隔线
+	for _, line := range lines[3:] {
+		if !strings.HasPrefix(line, "| ") {
+			continue
+		}
+		cells := strings.Split(strings.TrimSuffix(strings.TrimPrefix(line, "| "), " |"), " | ")
+		rows = append(rows, cells)
+	}
+	return rows
+}
+
+func TestTableNumericAlignment(t *testing.T) {
+	cols := []fakeColumn{
+		{name: "id", typeName: "INT"},
+		{name: "name", typeName: "NVARCHAR"},
+		{name: "price", typeName: "DECIMAL", precision: 10, scale: 2},
+		{name: "ratio", typeName: "FLOAT"},
+		{name: "active", typeName: "BIT"},
+		{name: "balance", typeName: "MONEY"},
+		{name: "code", typeName: "VARCHAR"},
+	}
+	rows := [][]interface{}{
+		{int64(1), "widget", []byte("9.99"), 0.5, true, []byte("1234.5600"), "A1"},
+		{int64(1000), "gadget with a long name", []byte("12345.00"), 12.25, false, []byte("-7.0000"), "42"},
+		{nil, "x", nil, nil, nil, nil, "B"},
+	}
+	numeric := []bool{true, false, true, true, true, true, false}
+
+	tests := []struct {
+		name  string
+		align bool
+	}{
+		{name: "align numbers", align: true},
+		{name: "align off", align: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			display := newDisplaySettings()
+			display.alignNumbers = tt.align
+			out := renderResult(t, newTable, &display, cols, rows...)
+			assertAligned(t, out)
+
+			cells := tableCells(out)
+			if len(cells) != len(rows) {
+				t.Fatalf("got %d data rows, want %d:\n%s", len(cells), len(rows), out)
+			}
+			for r, row := range cells {
+				for i, cell := range row {
+					value := strings.TrimSpace(cell)
+					if value == cell {
+						continue // 值占满整列，无法判断对齐方式
+					}
+					right := strings.HasPrefix(cell, " ") && strings.HasSuffix(cell, value)
+					if want := tt.align && numeric[i]; right != want {
+						t.Errorf("row %d column %s: cell %q right aligned = %v, want %v", r, cols[i].name, cell, right, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestPlainNumericAlignment(t *testing.T) {
+	display := newDisplaySettings()
+	cols := []fakeColumn{
+		{name: "name", typeName: "NVARCHAR"},
+		{name: "qty", typeName: "BIGINT"},
+	}
+	newPlain := func(w io.Writer) Formatter {
+		f, _ := newPlainFormatter(w, "")
+		return f
+	}
+	out := renderResult(t, newPlain, &display, cols,
+		[]interface{}{"a", int64(5)},
+		[]interface{}{"bb", int64(12345)},
+	)
+	want := []string{
+		"name   qty",
+		"---- -----",
+		"a        5",
+		"bb   12345",
+	}
+	lines := strings.Split(out, "\n")
+	if len(lines) < len(want) {
+		t.Fatalf("unexpected output:\n%s", out)
+	}
+	for i, line := range want {
+		if lines[i] != line {
+			t.Errorf("line %d = %q, want %q", i, lines[i], line)
+		}
+	}
+}
+
+func TestHTMLFormatsCells(t *testing.T) {
+	display := newDisplaySettings()
+	cols := []fakeColumn{
+		{name: "id", typeName: "UNIQUEIDENTIFIER"},
+		{name: "name", typeName: "NVARCHAR"},
+		{name: "data", typeName: "VARBINARY"},
+	}
+	newHTML := func(w io.Writer) Formatter {
+		f, _ := newHTMLFormatter(w, "")
+		return f
+	}
+	guid := []byte{0xFF, 0x19, 0x96, 0x6F, 0x86, 0x8B, 0x11, 0xD0, 0xB4, 0x2D, 0x00, 0xC0, 0x4F, 0xC9, 0x64, 0xFF}
+	out := renderResult(t, newHTML, &display, cols,
+		[]interface{}{guid, []byte("a<b"), []byte{0xAB, 0x01}},
+		[]interface{}{nil, nil, nil},
+	)
+	for _, want := range []string{
+		">6F9619FF-8B86-D011-B42D-00C04FC964FF</td>",
+		">a&lt;b</td>",
+		">0xAB01</td>",
+		"<em>NULL</em>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package mssql
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/microsoft/go-mssqldb/msdsn"
+)
+
+// testTerm 测试使用的终端，输入为空，输出保存在缓冲区中
+type testTerm struct {
+	bytes.Buffer
+}
+
+// fakeColumn 测试驱动返回的列及其类型信息
+type fakeColumn struct {
+	name      string
+	typeName  string // DatabaseTypeName，如 NVARCHAR、DECIMAL
+	length    int64  // 字符和二进制类型的长度，0 表示未知
+	precision int64  // 数值类型的精度
+	scale     int64  // 数值类型的小数位数，时间类型的小数秒位数
+}
+
+// fakeConnector 不连接服务器的 driver.Connector，每次查询都返回同一个结果集，用于取得 *sql.ColumnType；
+// 执行的语句记录在 execs 中。affected 大于 0 时每次查询都像驱动一样记录一条影响行数的消息；
+// rowsErr 不为 nil 时读完 rows 后返回该错误，模拟读取结果集中途失败
+type fakeConnector struct {
+	cols     []fakeColumn
+	rows     [][]driver.Value
+	execs    []string
+	affected int64
+	rowsErr  error
+}
+
+func (c *fakeConnector) Connect(context.Context) (driver.Conn, error) { return fakeConn{c}, nil }
+func (c *fakeConnector) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct{ c *fakeConnector }
+
+func (fc fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{fc.c, query}, nil }
+func (fakeConn) Close() error                                 { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                    { return nil, errors.New("not supported") }
+
+func (fc fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if fc.c.affected > 0 {
+		// 与驱动处理 DONE 令牌时记录的消息相同
+		messageLogger{}.Log(ctx, msdsn.LogRows, fmt.Sprintf("(Rows affected: %d)", fc.c.affected))
+	}
+	return &fakeRows{c: fc.c}, nil
+}
+
+type fakeStmt struct {
+	c     *fakeConnector
+	query string
+}
+
+func (fakeStmt) Close() error  { return nil }
+func (fakeStmt) NumInput() int { return -1 }
+func (s fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	s.c.execs = append(s.c.execs, s.query)
+	return driver.RowsAffected(0), nil
+}
+func (s fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	return &fakeRows{c: s.c}, nil
+}
+
+type fakeRows struct {
+	c   *fakeConnector
+	pos int
+}
+
+func (r *fakeRows) Columns() []string {
+	names := make([]string, len(r.c.cols))
+	for i, col := range r.c.cols {
+		names[i] = col.name
+	}
+	return names
+}
+
+func (r *fakeRows) Close() error { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.c.rows) {
+		if r.c.rowsErr != nil {
+			return r.c.rowsErr
+		}
+		return io.EOF
+	}
+	copy(dest, r.c.rows[r.pos])
+	r.pos++
+	return nil
+}
+
+func (r *fakeRows) ColumnTypeDatabaseTypeName(i int) string { return r.c.cols[i].typeName }
+
+func (r *fakeRows) ColumnTypeLength(i int) (int64, bool) {
+	return r.c.cols[i].length, r.c.cols[i].length > 0
+}
+
+func (r *fakeRows) ColumnTypePrecisionScale(i int) (int64, int64, bool) {
+	col := r.c.cols[i]
+	return col.precision, col.scale, col.precision > 0 || col.scale > 0
+}
+
+// fakeRowsOf 通过测试驱动执行一次查询，返回 cols 描述的结果集，rows 为各行的值
+func fakeRowsOf(t *testing.T, cols []fakeColumn, rows ...[]driver.Value) *sql.Rows {
+	t.Helper()
+	db := sql.OpenDB(&fakeConnector{cols: cols, rows: rows})
+	t.Cleanup(func() { db.Close() })
+	result, err := db.Query("SELECT")
+	if err != nil {
+		t.Fatalf("fake query: %v", err)
+	}
+	t.Cleanup(func() { result.Close() })
+	return result
+}
+
+// connectFake 将 c 的会话连接替换为测试驱动的连接，查询都返回 cols 和 rows 描述的结果集
+func connectFake(t *testing.T, c *CLI, cols []fakeColumn, rows ...[]driver.Value) *fakeConnector {
+	t.Helper()
+	fc := &fakeConnector{cols: cols, rows: rows}
+	c.db = sql.OpenDB(fc)
+	conn, err := c.db.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("fake connection: %v", err)
+	}
+	c.conn = conn
+	t.Cleanup(func() {
+		conn.Close()
+		c.db.Close()
+	})
+	return fc
+}
+
+// fakeColumnTypes 返回 cols 对应的 *sql.ColumnType
+func fakeColumnTypes(t *testing.T, cols ...fakeColumn) []*sql.ColumnType {
+	t.Helper()
+	colTypes, err := fakeRowsOf(t, cols).ColumnTypes()
+	if err != nil {
+		t.Fatalf("ColumnTypes: %v", err)
+	}
+	return colTypes
+}
+
+// renderResult 用 newFormatter 创建的格式化器输出一个结果集，返回输出的文本
+func renderResult(t *testing.T, newFormatter func(w io.Writer) Formatter, display *displaySettings, cols []fakeColumn, rows ...[]interface{}) string {
+	t.Helper()
+	var buf bytes.Buffer
+	f := newFormatter(&buf)
+	if da, ok := f.(displayAware); ok {
+		da.setDisplay(display)
+	}
+	names := make([]string, len(cols))
+	for i, col := range cols {
+		names[i] = col.name
+	}
+	if err := f.BeginResult(names, fakeColumnTypes(t, cols...)); err != nil {
+		t.Fatalf("BeginResult: %v", err)
+	}
+	for _, row := range rows {
+		if err := f.WriteRow(row); err != nil {
+			t.Fatalf("WriteRow: %v", err)
+		}
+	}
+	if err := f.EndResult(ResultSummary{RowCount: int64(len(rows))}); err != nil {
+		t.Fatalf("EndResult: %v", err)
+	}
+	return buf.String()
+}
+
+// envTestDSN 集成测试连接的服务器，ADO 或 sqlserver:// 形式的连接串；未设置时跳过集成测试
+const envTestDSN = "MSSQL_CLI_TEST_DSN"
+
+// connectTestServer 连接 MSSQL_CLI_TEST_DSN 指定的服务器，configure 不为 nil 时在连接前修改配置。
+// 未设置该环境变量时跳过测试
+func connectTestServer(t *testing.T, configure func(*Config)) (*CLI, *testTerm) {
+	t.Helper()
+	dsn := os.Getenv(envTestDSN)
+	if dsn == "" {
+		t.Skipf("%s is not set", envTestDSN)
+	}
+	cfg, err := msdsn.Parse(dsn)
+	if err != nil {
+		t.Fatalf("invalid %s: %v", envTestDSN, err)
+	}
+	config := &Config{
+		Host:             cfg.Host,
+		Port:             int(cfg.Port),
+		Instance:         cfg.Instance,
+		Username:         cfg.User,
+		Password:         cfg.Password,
+		Database:         cfg.Database,
+		ConnectionString: dsn,
+	}
+	if configure != nil {
+		configure(config)
+	}
+	term := &testTerm{}
+	c := NewCLIWithConfig(term, config)
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c, term
+}
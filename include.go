@@ -0,0 +1,326 @@
+package mssql
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// maxIncludeDepth 脚本文件中 :r 嵌套包含的最大层数，防止循环包含
+const maxIncludeDepth = 16
+
+// errScriptStopped 在 on_error stop 模式下脚本因错误停止
+var errScriptStopped = errors.New("script stopped on error")
+
+// scriptUnit 脚本中的一个执行单元：以 GO 分隔的批处理，或以 : 开头的 sqlcmd 命令行
+type scriptUnit struct {
+	text    string
+	line    int // 起始行号
+	repeat  int // GO <n> 指定的执行次数
+	command bool
+}
+
+// splitScript 将脚本按 GO 行拆分为批处理；GO 和 : 命令只在行首且不在字符串或注释中时识别
+func splitScript(text string) ([]scriptUnit, error) {
+	var units []scriptUnit
+	var lexer sqlLexer
+	var lines []string
+	start := 0
+	flush := func(repeat int) {
+		if body := strings.TrimSpace(strings.Join(lines, "\n")); body != "" {
+			units = append(units, scriptUnit{text: body, line: start, repeat: repeat})
+		}
+		lines = nil
+	}
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if !lexer.inside() {
+			trimmed := strings.TrimSpace(line)
+			if isGo, count, err := parseGo(trimmed); isGo {
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %v", i+1, err)
+				}
+				flush(count)
+				continue
+			}
+			if strings.HasPrefix(trimmed, ":") {
+				flush(1)
+				units = append(units, scriptUnit{text: trimmed, line: i + 1, command: true})
+				continue
+			}
+		}
+		if len(lines) == 0 {
+			// 批处理之间的空行不计入起始行号
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			start = i + 1
+		}
+		lines = append(lines, line)
+		lexer.scan(line)
+	}
+	flush(1)
+	return units, nil
+}
+
+// decodeScript 将脚本文件内容解码为字符串：支持带或不带 BOM 的 UTF-8 以及 UTF-16（按 BOM 或零字节判断字节序）
+func decodeScript(data []byte) (string, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		data = data[3:]
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		return decodeUTF16(data[2:], binary.LittleEndian), nil
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		return decodeUTF16(data[2:], binary.BigEndian), nil
+	case len(data) >= 2 && data[0] != 0 && data[1] == 0:
+		return decodeUTF16(data, binary.LittleEndian), nil
+	case len(data) >= 2 && data[0] == 0 && data[1] != 0:
+		return decodeUTF16(data, binary.BigEndian), nil
+	}
+	if !utf8.Valid(data) {
+		return "", fmt.Errorf("file is not valid UTF-8 or UTF-16")
+	}
+	return string(data), nil
+}
+
+// decodeUTF16 按指定字节序解码 UTF-16 文本，奇数长度时忽略最后一个字节
+func decodeUTF16(data []byte, order binary.ByteOrder) string {
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = order.Uint16(data[2*i:])
+	}
+	return string(utf16.Decode(units))
+}
+
+// currentDir 返回解析相对路径使用的工作目录，默认为进程的当前目录
+func (c *CLI) currentDir() string {
+	if c.workDir != "" {
+		return c.workDir
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return "."
+	}
+	return dir
+}
+
+// resolvePath 将路径参数解析为绝对路径，去掉两侧的引号，相对路径基于 currentDir
+func (c *CLI) resolvePath(arg string) string {
+	path := strings.TrimSpace(arg)
+	if len(path) >= 2 && (path[0] == '"' || path[0] == '\'') && path[len(path)-1] == path[0] {
+		path = path[1 : len(path)-1]
+	}
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(c.currentDir(), path)
+}
+
+// changeDir 处理 :cd <dir> 命令，不带参数时显示当前工作目录
+func (c *CLI) changeDir(arg string) {
+	if strings.TrimSpace(arg) == "" {
+		fmt.Fprintf(c.term, "%s\n", c.currentDir())
+		return
+	}
+	dir := c.resolvePath(arg)
+	info, err := os.Stat(dir)
+	if err != nil {
+		fmt.Fprintf(c.term, "Error: %v\n", err)
+		return
+	}
+	if !info.IsDir() {
+		fmt.Fprintf(c.term, "Error: %s is not a directory\n", dir)
+		return
+	}
+	c.workDir = dir
+	fmt.Fprintf(c.term, "%s\n", dir)
+}
+
+// setOnError 处理 on_error stop|continue 和 :on error exit|ignore 命令，决定脚本中的批处理出错后停止还是继续
+func (c *CLI) setOnError(arg string) {
+	switch strings.ToLower(strings.TrimSpace(arg)) {
+	case "":
+	case "stop", "exit":
+		c.onErrorExit = true
+	case "continue", "ignore":
+		c.onErrorExit = false
+	default:
+		fmt.Fprintf(c.term, "Usage: on_error stop|continue\n")
+		return
+	}
+	mode := "continue"
+	if c.onErrorExit {
+		mode = "stop"
+	}
+	fmt.Fprintf(c.term, "On error: %s\n", mode)
+}
+
+// stopOnError 判断出错后是否停止脚本：on_error stop 时级别 11 及以上的错误停止，更低级别的只是信息
+func (c *CLI) stopOnError(err error) bool {
+	return c.onErrorExit && errorSeverity(err) >= 11
+}
+
+// stopScript 报告脚本停止的位置，有未提交的事务时回滚，返回 errScriptStopped
+func (c *CLI) stopScript(where string) error {
+	fmt.Fprintf(c.out, "%s\n", c.display.colorize("Script stopped at "+where+" (on_error stop).", ansiRed))
+	if c.openTransactions() > 0 {
+		ctx, cancel := c.statementContext()
+		_, err := c.conn.ExecContext(ctx, "IF @@TRANCOUNT > 0 ROLLBACK TRANSACTION")
+		cancel()
+		if err != nil {
+			c.printError(err)
+		} else {
+			fmt.Fprintf(c.out, "%s\n", c.display.colorize("Open transaction rolled back.", ansiRed))
+		}
+		c.tranCount = c.openTransactions()
+	}
+	fmt.Fprintf(c.out, "\n")
+	c.flushOutput()
+	return errScriptStopped
+}
+
+// beginScript 开始顶层脚本时清空出错位置的记录，被包含的脚本沿用外层的记录
+func (c *CLI) beginScript() {
+	if c.includeDepth == 0 {
+		c.scriptFailures = nil
+	}
+}
+
+// printScriptFailures 在顶层脚本全部执行后汇总出错的批处理
+func (c *CLI) printScriptFailures() {
+	if c.includeDepth > 0 || len(c.scriptFailures) == 0 {
+		return
+	}
+	noun := "batches"
+	if len(c.scriptFailures) == 1 {
+		noun = "batch"
+	}
+	fmt.Fprintf(c.out, "%s\n", c.display.colorize(fmt.Sprintf("Script finished with %d failed %s:", len(c.scriptFailures), noun), ansiRed))
+	for _, where := range c.scriptFailures {
+		fmt.Fprintf(c.out, "  %s\n", where)
+	}
+	fmt.Fprintf(c.out, "\n")
+	c.scriptFailures = nil
+}
+
+// includeCommand 处理 :r <path> 和 source <path> 命令
+func (c *CLI) includeCommand(arg string) {
+	if strings.TrimSpace(arg) == "" {
+		fmt.Fprintf(c.term, "Usage: :r <file>\n")
+		return
+	}
+	c.beginScript()
+	if err := c.runScriptFile(arg); err != nil {
+		if !errors.Is(err, errScriptStopped) {
+			c.printError(err)
+		}
+		c.flushOutput()
+		return
+	}
+	c.printScriptFailures()
+	c.flushOutput()
+}
+
+// runScriptFile 读取脚本文件并依次执行其中的批处理和 sqlcmd 命令，出错时输出文件名、批处理序号和行号；
+// on_error stop 时在第一个错误处停止并返回 errScriptStopped
+func (c *CLI) runScriptFile(arg string) error {
+	if c.includeDepth >= maxIncludeDepth {
+		return fmt.Errorf(":r nested more than %d levels deep", maxIncludeDepth)
+	}
+	path := c.resolvePath(arg)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	text, err := decodeScript(data)
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	units, err := splitScript(text)
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+
+	c.includeDepth++
+	defer func() { c.includeDepth-- }()
+	return c.runScriptUnits(filepath.Base(path), units)
+}
+
+// runScriptUnits 依次执行脚本的批处理和命令行，name 用于错误信息，出错的位置记录下来在结束后汇总；
+// on_error stop 时在第一个错误处停止、回滚未提交的事务并返回 errScriptStopped。
+// RunScript 执行期间还会记录错误，并在其 context 取消后停止
+func (c *CLI) runScriptUnits(name string, units []scriptUnit) error {
+	var err error
+	batch := 0
+	for _, u := range units {
+		if c.runCtx != nil && c.runCtx.Err() != nil {
+			return c.runCtx.Err()
+		}
+		if u.command {
+			if err := c.runScriptCommand(u.text); err != nil {
+				if errors.Is(err, errScriptStopped) || c.runCtx != nil && c.runCtx.Err() != nil {
+					return err
+				}
+				c.recordScriptError(err)
+				where := fmt.Sprintf("%s, line %d", name, u.line)
+				fmt.Fprintf(c.out, "%s\n\n", c.display.colorize(fmt.Sprintf("Error in %s: %v", where, err), ansiRed))
+				c.scriptFailures = append(c.scriptFailures, where)
+				if c.stopOnError(err) {
+					return c.stopScript(where)
+				}
+			}
+			continue
+		}
+
+		batch++
+		if u.repeat > 1 {
+			err = c.repeatBatch(u.text, u.repeat)
+		} else if text, expandErr := c.expandVariables(u.text); expandErr != nil {
+			c.printError(expandErr)
+			err = expandErr
+		} else if params, ok := c.bindParams(text); !ok {
+			err = errParamsCancelled
+		} else {
+			err = c.runStatement(text, time.Now(), c.expanded, params...)
+			c.flushOutput()
+		}
+		if err == nil {
+			continue
+		}
+		c.recordScriptError(err)
+		where := fmt.Sprintf("%s, batch %d (line %d)", name, batch, u.line)
+		fmt.Fprintf(c.out, "%s\n\n", c.display.colorize("-- in "+where, ansiRed))
+		c.scriptFailures = append(c.scriptFailures, where)
+		if c.stopOnError(err) {
+			return c.stopScript(where)
+		}
+	}
+	return nil
+}
+
+// runScriptCommand 执行脚本中的 sqlcmd 命令行：:r 递归包含，其余交给特殊命令处理
+func (c *CLI) runScriptCommand(line string) error {
+	if name, arg := splitCommand(line); name == ":r" {
+		return c.runScriptFile(arg)
+	}
+	if !c.handleSpecialCommand(line) {
+		return fmt.Errorf("unknown command %s", line)
+	}
+	return nil
+}
+
+// splitCommand 将命令行拆分为小写的命令名和参数
+func splitCommand(line string) (name, arg string) {
+	line = strings.TrimSpace(line)
+	if i := strings.IndexAny(line, " \t"); i >= 0 {
+		return strings.ToLower(line[:i]), strings.TrimSpace(line[i+1:])
+	}
+	return strings.ToLower(line), ""
+}
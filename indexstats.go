@@ -0,0 +1,144 @@
+package mssql
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// 索引维护建议的阈值：小于 indexStatsMinPages 页的索引碎片影响可以忽略
+const (
+	indexStatsMinPages = 1000
+	reorganizeFragPct  = 5.0
+	rebuildFragPct     = 30.0
+)
+
+// indexStatsQuery 以 LIMITED 模式读取物理统计信息并关联使用统计，不排序以便结果在计算出来后立即返回
+const indexStatsQuery = `SELECT s.name, o.name, COALESCE(i.name, ''), ps.index_id, i.type_desc, ps.partition_number,
+	ps.avg_fragmentation_in_percent, ps.page_count,
+	us.user_seeks, us.user_scans, us.user_lookups, us.user_updates,
+	(SELECT MAX(v) FROM (VALUES (us.last_user_seek), (us.last_user_scan), (us.last_user_lookup)) AS x(v)),
+	us.last_user_update
+FROM sys.dm_db_index_physical_stats(DB_ID(), @object, NULL, NULL, 'LIMITED') AS ps
+JOIN sys.indexes AS i ON i.object_id = ps.object_id AND i.index_id = ps.index_id
+JOIN sys.objects AS o ON o.object_id = ps.object_id
+JOIN sys.schemas AS s ON s.schema_id = o.schema_id
+LEFT JOIN sys.dm_db_index_usage_stats AS us
+	ON us.database_id = DB_ID() AND us.object_id = ps.object_id AND us.index_id = ps.index_id
+WHERE ps.alloc_unit_type_desc = 'IN_ROW_DATA' AND o.is_ms_shipped = 0 AND ps.page_count >= @minPages`
+
+// indexStatsColumns indexstats 命令输出的列
+var indexStatsColumns = []string{"table", "index", "type", "partition", "frag_pct", "pages",
+	"seeks", "scans", "lookups", "updates", "last_read", "last_update"}
+
+// showIndexStats 处理 indexstats [table] 命令，显示索引的碎片率、页数和使用统计，并给出
+// 碎片严重的索引的 ALTER INDEX REBUILD/REORGANIZE 建议（不执行）；不指定表时只报告
+// 不少于 indexStatsMinPages 页的索引，并以流式输出逐行显示
+func (c *CLI) showIndexStats(arg string) {
+	startTime := time.Now()
+	ctx, cancel := c.statementContext()
+	defer cancel()
+	defer c.flushOutput()
+
+	var object interface{}
+	minPages := indexStatsMinPages
+	if arg != "" {
+		obj, err := c.resolveObject(ctx, arg)
+		if err != nil {
+			c.printError(err)
+			return
+		}
+		object, minPages = obj.id, 0
+	} else if !c.display.stream {
+		// 扫描整个数据库可能很慢，临时开启流式输出让行在到达时就显示
+		c.display.stream = true
+		defer func() { c.display.stream = false }()
+	}
+
+	rows, err := c.conn.QueryContext(ctx, indexStatsQuery, sql.Named("object", object), sql.Named("minPages", minPages))
+	if err != nil {
+		c.printError(err)
+		return
+	}
+	defer rows.Close()
+
+	f := c.resultFormatter("", indexStatsColumns, nil, c.expanded)
+	endPaging := c.beginPaging()
+	defer endPaging()
+	if err := f.BeginResult(indexStatsColumns, nil); err != nil {
+		fmt.Fprintf(c.out, "Error: %v\n\n", err)
+		return
+	}
+
+	var suggestions []string
+	suggested := make(map[string]bool)
+	var count int64
+	for rows.Next() {
+		var schema, name, index, typeDesc string
+		var indexID, partition int
+		var frag float64
+		var pages int64
+		var seeks, scans, lookups, updates sql.NullInt64
+		var lastRead, lastUpdate sql.NullTime
+		if err := rows.Scan(&schema, &name, &index, &indexID, &typeDesc, &partition, &frag, &pages,
+			&seeks, &scans, &lookups, &updates, &lastRead, &lastUpdate); err != nil {
+			fmt.Fprintf(c.out, "Error: %v\n\n", err)
+			return
+		}
+		table := schema + "." + name
+		if index == "" {
+			index = "(heap)"
+		}
+		c.outMu.Lock()
+		err := f.WriteRow([]interface{}{table, index, typeDesc, partition, fmt.Sprintf("%.1f", frag), pages,
+			nullInt(seeks), nullInt(scans), nullInt(lookups), nullInt(updates), nullTime(lastRead), nullTime(lastUpdate)})
+		c.outMu.Unlock()
+		if err != nil {
+			fmt.Fprintf(c.out, "Error: %v\n\n", err)
+			return
+		}
+		count++
+
+		// 堆没有 ALTER INDEX，每个索引只建议一次
+		key := table + "." + index
+		if indexID == 0 || pages < indexStatsMinPages || frag < reorganizeFragPct || suggested[key] {
+			continue
+		}
+		suggested[key] = true
+		action := "REORGANIZE"
+		if frag >= rebuildFragPct {
+			action = "REBUILD"
+		}
+		suggestions = append(suggestions, fmt.Sprintf("ALTER INDEX %s ON %s %s;  -- %.1f%% fragmented",
+			quoteIdent(index), quoteIdent(schema)+"."+quoteIdent(name), action, frag))
+	}
+	if err := rows.Err(); err != nil {
+		c.printError(err)
+		return
+	}
+	f.EndResult(ResultSummary{RowCount: count, Elapsed: time.Since(startTime), Timing: c.timingEnabled})
+
+	if len(suggestions) > 0 && c.showInfo() {
+		c.section("Suggested maintenance (not executed)")
+		for _, s := range suggestions {
+			fmt.Fprintln(c.out, s)
+		}
+		fmt.Fprintln(c.out)
+	}
+}
+
+// nullInt 将 sql.NullInt64 转换为可输出的值，NULL 为 nil
+func nullInt(v sql.NullInt64) interface{} {
+	if !v.Valid {
+		return nil
+	}
+	return v.Int64
+}
+
+// nullTime 将 sql.NullTime 转换为可输出的值，NULL 为 nil
+func nullTime(v sql.NullTime) interface{} {
+	if !v.Valid {
+		return nil
+	}
+	return v.Time
+}
@@ -0,0 +1,154 @@
+package mssql
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// fromTablePattern 提取简单 SELECT 语句中 FROM 后的表名
+	fromTablePattern = regexp.MustCompile(`(?is)^\s*SELECT\b.*?\bFROM\s+((?:\[[^\]]+\]|[\w#@$]+)(?:\.(?:\[[^\]]+\]|[\w#@$]+)){0,3})`)
+	// multiTablePattern 匹配涉及多个表的语句，此时无法推断目标表
+	multiTablePattern = regexp.MustCompile(`(?is)\b(JOIN|UNION|INTERSECT|EXCEPT|APPLY)\b|\bFROM\s+[^;]*?,`)
+)
+
+// inferTableName 从简单的单表查询中推断表名
+func inferTableName(sqlStr string) string {
+	if multiTablePattern.MatchString(sqlStr) {
+		return ""
+	}
+	m := fromTablePattern.FindStringSubmatch(sqlStr)
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
+
+// quoteIdent 用方括号引用标识符
+func quoteIdent(name string) string {
+	return "[" + strings.ReplaceAll(name, "]", "]]") + "]"
+}
+
+// quoteString 生成 Unicode 字符串字面量 N'...'
+func quoteString(s string) string {
+	return "N'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// sqlLiteral 将扫描出的值转换为 T-SQL 字面量
+func sqlLiteral(v interface{}, typeName string) string {
+	switch val := v.(type) {
+	case nil:
+		return "NULL"
+	case bool:
+		if val {
+			return "1"
+		}
+		return "0"
+	case int64:
+		return strconv.FormatInt(val, 10)
+	case float64:
+		return strconv.FormatFloat(val, 'g', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(val), 'g', -1, 32)
+	case string:
+		return quoteString(val)
+	case []byte:
+		switch {
+		case isNumericType(typeName):
+			return string(val)
+		case typeName == "UNIQUEIDENTIFIER":
+			return "'" + formatGUID(val) + "'"
+		case isBinaryType(typeName):
+			return formatHex(val)
+		default:
+			return quoteString(string(val))
+		}
+	case time.Time:
+		switch typeName {
+		case "DATE":
+			return "'" + val.Format("2006-01-02") + "'"
+		case "TIME":
+			return "CONVERT(time, '" + val.Format("15:04:05.0000000") + "')"
+		case "DATETIMEOFFSET":
+			return "CONVERT(datetimeoffset, '" + val.Format("2006-01-02T15:04:05.0000000-07:00") + "', 127)"
+		case "DATETIME", "SMALLDATETIME":
+			return "CONVERT(" + strings.ToLower(typeName) + ", '" + val.Format("2006-01-02T15:04:05.000") + "', 126)"
+		default:
+			return "CONVERT(datetime2, '" + val.Format("2006-01-02T15:04:05.0000000") + "', 126)"
+		}
+	default:
+		return quoteString(fmt.Sprintf("%v", v))
+	}
+}
+
+// insertFormatter 将每一行结果输出为 INSERT 语句
+type insertFormatter struct {
+	w         io.Writer
+	table     string // 指定的目标表，为空时从查询推断
+	identity  bool   // 是否包裹 SET IDENTITY_INSERT
+	statement string
+	target    string // 当前结果集的目标表
+	typeNames []string
+	prefix    string
+}
+
+// newInsertFormatter 创建 INSERT 格式，参数为 [table] [-identity]
+func newInsertFormatter(w io.Writer, arg string) (Formatter, error) {
+	f := &insertFormatter{w: w}
+	for _, field := range strings.Fields(arg) {
+		if strings.EqualFold(field, "-identity") {
+			f.identity = true
+		} else {
+			f.table = field
+		}
+	}
+	return f, nil
+}
+
+func (f *insertFormatter) setStatement(sqlStr string) {
+	f.statement = sqlStr
+}
+
+func (f *insertFormatter) BeginResult(cols []string, colTypes []*sql.ColumnType) error {
+	f.target = f.table
+	if f.target == "" {
+		f.target = inferTableName(f.statement)
+	}
+	if f.target == "" {
+		return fmt.Errorf("cannot infer target table, use 'format insert <table>'")
+	}
+
+	f.typeNames = typeNames(colTypes)
+	quotedCols := make([]string, len(cols))
+	for i, col := range cols {
+		quotedCols[i] = quoteIdent(col)
+	}
+	f.prefix = fmt.Sprintf("INSERT INTO %s (%s) VALUES (", f.target, strings.Join(quotedCols, ", "))
+
+	if f.identity {
+		fmt.Fprintf(f.w, "SET IDENTITY_INSERT %s ON;\n", f.target)
+	}
+	return nil
+}
+
+func (f *insertFormatter) WriteRow(values []interface{}) error {
+	literals := make([]string, len(values))
+	for i, v := range values {
+		literals[i] = sqlLiteral(v, typeNameAt(f.typeNames, i))
+	}
+	_, err := fmt.Fprintf(f.w, "%s%s);\n", f.prefix, strings.Join(literals, ", "))
+	return err
+}
+
+func (f *insertFormatter) EndResult(summary ResultSummary) error {
+	if f.identity && f.target != "" {
+		fmt.Fprintf(f.w, "SET IDENTITY_INSERT %s OFF;\n", f.target)
+	}
+	f.target = ""
+	return nil
+}
@@ -0,0 +1,188 @@
+package mssql
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// hasAgent 判断服务器版本是否提供 SQL Server Agent：Express、Azure SQL Database、Synapse 和 SQL Edge 没有 Agent
+func (s ServerInfo) hasAgent() bool {
+	switch s.EngineEdition {
+	case 4, 5, 6, 9, 11:
+		return false
+	}
+	return true
+}
+
+// checkAgent 在没有 SQL Agent 的版本上返回说明性的错误，而不是查询 msdb 时的权限错误
+func (c *CLI) checkAgent() error {
+	if c.serverInfo.hasAgent() {
+		return nil
+	}
+	edition := c.serverInfo.Edition
+	if edition == "" {
+		edition = "this server"
+	}
+	return fmt.Errorf("SQL Agent not available on this edition (%s)", edition)
+}
+
+// agentTime 将 Agent 以 YYYYMMDD 和 HHMMSS 整数保存的日期和时间转换为 time.Time
+func agentTime(date, clock int64) time.Time {
+	return time.Date(int(date/10000), time.Month(date/100%100), int(date%100),
+		int(clock/10000), int(clock/100%100), int(clock%100), 0, time.Local)
+}
+
+// agentDuration 将 Agent 以 HHMMSS 整数保存的运行时长转换为可读的时长
+func agentDuration(hhmmss int64) string {
+	d := time.Duration(hhmmss/10000)*time.Hour + time.Duration(hhmmss/100%100)*time.Minute + time.Duration(hhmmss%100)*time.Second
+	return d.String()
+}
+
+// agentOutcomes sysjobhistory.run_status 的取值
+var agentOutcomes = []string{"Failed", "Succeeded", "Retry", "Canceled", "In Progress"}
+
+// agentOutcome 返回运行结果的名称
+func agentOutcome(status int64) string {
+	if status >= 0 && int(status) < len(agentOutcomes) {
+		return agentOutcomes[status]
+	}
+	return strconv.FormatInt(status, 10)
+}
+
+// listJobs 处理 jobs 命令，显示 SQL Agent 作业的启用状态、运行状态、上次运行的时间、结果和时长以及下次计划运行时间
+func (c *CLI) listJobs(arg string) {
+	if arg != "" {
+		fmt.Fprintf(c.term, "Usage: jobs\n")
+		return
+	}
+	startTime := time.Now()
+	ctx, cancel := c.statementContext()
+	defer cancel()
+	defer c.flushOutput()
+
+	if err := c.checkAgent(); err != nil {
+		c.printError(err)
+		return
+	}
+	// sysjobactivity 中只有最近一次 Agent 会话的记录反映当前的运行状态
+	rows, err := c.conn.QueryContext(ctx, `SELECT j.name, j.enabled, h.run_date, h.run_time, h.run_status, h.run_duration,
+	a.start_execution_date,
+	(SELECT MIN(CAST(s.next_run_date AS bigint) * 1000000 + s.next_run_time)
+		FROM msdb.dbo.sysjobschedules AS s WHERE s.job_id = j.job_id AND s.next_run_date > 0)
+FROM msdb.dbo.sysjobs AS j
+OUTER APPLY (SELECT TOP 1 run_date, run_time, run_status, run_duration FROM msdb.dbo.sysjobhistory
+	WHERE job_id = j.job_id AND step_id = 0 ORDER BY instance_id DESC) AS h
+OUTER APPLY (SELECT TOP 1 start_execution_date FROM msdb.dbo.sysjobactivity
+	WHERE job_id = j.job_id AND start_execution_date IS NOT NULL AND stop_execution_date IS NULL
+		AND session_id = (SELECT MAX(session_id) FROM msdb.dbo.syssessions)) AS a
+ORDER BY j.name`)
+	if err != nil {
+		c.printError(err)
+		return
+	}
+	defer rows.Close()
+
+	var data [][]interface{}
+	for rows.Next() {
+		var name string
+		var enabled bool
+		var runDate, runTime, runStatus, runDuration, nextRun sql.NullInt64
+		var running sql.NullTime
+		if err := rows.Scan(&name, &enabled, &runDate, &runTime, &runStatus, &runDuration, &running, &nextRun); err != nil {
+			c.printError(err)
+			return
+		}
+		status := "idle"
+		if running.Valid {
+			status = "running since " + running.Time.Format("2006-01-02 15:04:05")
+		}
+		var lastRun, outcome, duration, next interface{}
+		if runDate.Valid {
+			lastRun = agentTime(runDate.Int64, runTime.Int64)
+			outcome = agentOutcome(runStatus.Int64)
+			duration = agentDuration(runDuration.Int64)
+		}
+		if nextRun.Valid {
+			next = agentTime(nextRun.Int64/1000000, nextRun.Int64%1000000)
+		}
+		data = append(data, []interface{}{name, yesNo(enabled), status, lastRun, outcome, duration, next})
+	}
+	if err := rows.Err(); err != nil {
+		c.printError(err)
+		return
+	}
+	c.renderValues([]string{"name", "enabled", "status", "last_run", "last_outcome", "last_duration", "next_run"}, data, startTime)
+}
+
+// jobHistoryRuns jobhistory 默认显示的执行次数
+const jobHistoryRuns = 10
+
+// showJobHistory 处理 jobhistory <name> [N] 命令，显示作业最近 N 次执行的结果和各步骤的消息
+func (c *CLI) showJobHistory(arg string) {
+	name, runs := strings.TrimSpace(arg), jobHistoryRuns
+	if i := strings.LastIndexAny(name, " \t"); i > 0 {
+		if n, err := strconv.Atoi(name[i+1:]); err == nil && n > 0 {
+			name, runs = strings.TrimSpace(name[:i]), n
+		}
+	}
+	if name == "" {
+		fmt.Fprintf(c.term, "Usage: jobhistory <job name> [N]\n")
+		return
+	}
+	startTime := time.Now()
+	ctx, cancel := c.statementContext()
+	defer cancel()
+	defer c.flushOutput()
+
+	if err := c.checkAgent(); err != nil {
+		c.printError(err)
+		return
+	}
+	var jobID string
+	err := c.conn.QueryRowContext(ctx, "SELECT CAST(job_id AS nvarchar(36)) FROM msdb.dbo.sysjobs WHERE name = @name",
+		sql.Named("name", name)).Scan(&jobID)
+	if err == sql.ErrNoRows {
+		c.printError(fmt.Errorf("job '%s' not found", name))
+		return
+	}
+	if err != nil {
+		c.printError(err)
+		return
+	}
+
+	// 步骤的开始时间不早于所属执行的开始时间，取最近 N 次执行中最早的开始时间之后的所有记录
+	rows, err := c.conn.QueryContext(ctx, `WITH runs AS (
+	SELECT TOP (@runs) CAST(run_date AS bigint) * 1000000 + run_time AS started
+	FROM msdb.dbo.sysjobhistory WHERE job_id = @job AND step_id = 0 ORDER BY instance_id DESC
+)
+SELECT run_date, run_time, step_id, step_name, run_status, run_duration, message
+FROM msdb.dbo.sysjobhistory
+WHERE job_id = @job AND CAST(run_date AS bigint) * 1000000 + run_time >= (SELECT MIN(started) FROM runs)
+ORDER BY instance_id DESC`, sql.Named("runs", runs), sql.Named("job", jobID))
+	if err != nil {
+		c.printError(err)
+		return
+	}
+	defer rows.Close()
+
+	var data [][]interface{}
+	for rows.Next() {
+		var runDate, runTime, runStatus, runDuration int64
+		var step int
+		var stepName, message string
+		if err := rows.Scan(&runDate, &runTime, &step, &stepName, &runStatus, &runDuration, &message); err != nil {
+			c.printError(err)
+			return
+		}
+		data = append(data, []interface{}{agentTime(runDate, runTime), step, stepName, agentOutcome(runStatus),
+			agentDuration(runDuration), strings.Join(strings.Fields(message), " ")})
+	}
+	if err := rows.Err(); err != nil {
+		c.printError(err)
+		return
+	}
+	c.renderValues([]string{"run_at", "step", "step_name", "outcome", "duration", "message"}, data, startTime)
+}
@@ -0,0 +1,103 @@
+package mssql
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// keepAliveTimeout 保活查询的超时时间
+const keepAliveTimeout = 10 * time.Second
+
+// setIdle 记录是否在主提示符处等待输入，只有空闲时才发送保活查询
+// 非空闲时等待正在进行的保活查询结束，保证语句和保活查询不会同时使用会话连接
+func (c *CLI) setIdle(idle bool) {
+	c.keepAliveMu.Lock()
+	defer c.keepAliveMu.Unlock()
+	c.idle = idle
+	c.lastActive = time.Now()
+}
+
+// setKeepAlive 处理 keepalive <minutes>|off 命令
+func (c *CLI) setKeepAlive(arg string) {
+	switch strings.ToLower(arg) {
+	case "":
+		if c.keepAlive == 0 {
+			fmt.Fprintf(c.term, "Keep-alive is off\n")
+		} else {
+			fmt.Fprintf(c.term, "Keep-alive every %s of inactivity\n", c.keepAlive)
+		}
+		return
+	case "off", "0":
+		c.stopKeepAlive()
+		c.keepAlive = 0
+		fmt.Fprintf(c.term, "Keep-alive off\n")
+		return
+	}
+	minutes, err := strconv.Atoi(arg)
+	if err != nil || minutes < 0 {
+		fmt.Fprintf(c.term, "Invalid keep-alive interval '%s' (minutes or off)\n", arg)
+		return
+	}
+	c.stopKeepAlive()
+	c.keepAlive = time.Duration(minutes) * time.Minute
+	c.startKeepAlive()
+	fmt.Fprintf(c.term, "Keep-alive every %s of inactivity\n", c.keepAlive)
+}
+
+// startKeepAlive 启动保活协程，间隔为 0 时不启动
+func (c *CLI) startKeepAlive() {
+	if c.keepAlive <= 0 || c.keepAliveStop != nil {
+		return
+	}
+	stop := make(chan struct{})
+	c.keepAliveStop = stop
+	interval := c.keepAlive
+	go func() {
+		ticker := time.NewTicker(min(interval, time.Minute))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				c.keepAliveTick(interval)
+			}
+		}
+	}()
+}
+
+// stopKeepAlive 停止保活协程
+func (c *CLI) stopKeepAlive() {
+	if c.keepAliveStop != nil {
+		close(c.keepAliveStop)
+		c.keepAliveStop = nil
+	}
+}
+
+// keepAliveTick 空闲超过间隔时在会话连接上执行 SELECT 1，失败时重新连接
+func (c *CLI) keepAliveTick(interval time.Duration) {
+	c.keepAliveMu.Lock()
+	defer c.keepAliveMu.Unlock()
+	if !c.idle || c.conn == nil || time.Since(c.lastActive) < interval {
+		return
+	}
+	c.lastActive = time.Now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), keepAliveTimeout)
+	defer cancel()
+	var one int
+	err := c.conn.QueryRowContext(ctx, "SELECT 1").Scan(&one)
+	if err == nil {
+		return
+	}
+	fmt.Fprintf(c.term, "\nKeep-alive failed (%v). Reconnecting...\n", err)
+	if err := c.reconnect(); err != nil {
+		fmt.Fprintf(c.term, "Reconnect failed: %v\n", err)
+	} else {
+		fmt.Fprintf(c.term, "Reconnected to %s\n", c.serverAddress())
+	}
+	c.reader.Refresh()
+}
@@ -0,0 +1,97 @@
+package mssql
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/microsoft/go-mssqldb/msdsn"
+	// 注册命名管道协议（仅 Windows），LocalDB 只监听命名管道
+	_ "github.com/microsoft/go-mssqldb/namedpipe"
+)
+
+// defaultLocalDBInstance (localdb) 没有指定实例名时使用的自动实例
+const defaultLocalDBInstance = "MSSQLLocalDB"
+
+// localDBPipePattern 匹配 sqllocaldb info 输出中的管道名，例如
+// Instance pipe name: np:\\.\pipe\LOCALDB#1A2B3C4D\tsql\query；实例未运行时管道名为空
+var localDBPipePattern = regexp.MustCompile(`(?m)^[ \t]*Instance pipe name:[ \t]*(?:np:)?(\\\\\S+)[ \t]*\r?$`)
+
+// isLocalDB 判断目标是否为 (localdb)\实例名 形式的 LocalDB 实例
+func (c *CLI) isLocalDB() bool {
+	return strings.EqualFold(c.host, "(localdb)")
+}
+
+// localDBInstance 返回 LocalDB 的实例名
+func (c *CLI) localDBInstance() string {
+	if c.instance == "" {
+		return defaultLocalDBInstance
+	}
+	return c.instance
+}
+
+// checkLocalDB 检查 LocalDB 目标：LocalDB 只在 Windows 上提供
+func (c *CLI) checkLocalDB() error {
+	if c.isLocalDB() && runtime.GOOS != "windows" {
+		return fmt.Errorf("LocalDB instance %s is only available on Windows", c.localDBInstance())
+	}
+	return nil
+}
+
+// resolveLocalDBPipe 通过 sqllocaldb info 查询 LocalDB 实例当前的命名管道。
+// 实例每次启动都使用新的管道名，因此每次建立连接前都重新查询
+func (c *CLI) resolveLocalDBPipe() error {
+	instance := c.localDBInstance()
+	out, err := exec.Command("sqllocaldb", "info", instance).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("sqllocaldb info %s: %v: %s", instance, err, strings.TrimSpace(string(out)))
+	}
+	pipe, err := parseLocalDBPipe(instance, string(out))
+	if err != nil {
+		return err
+	}
+	c.localDBPipe = pipe
+	return nil
+}
+
+// parseLocalDBPipe 从 sqllocaldb info 的输出中取出命名管道的路径
+func parseLocalDBPipe(instance, info string) (string, error) {
+	m := localDBPipePattern.FindStringSubmatch(info)
+	if m == nil {
+		return "", fmt.Errorf("LocalDB instance %s is not running; start it with 'sqllocaldb start %s'", instance, instance)
+	}
+	return m[1], nil
+}
+
+// localDBError 为 LocalDB 的连接错误附加启动实例的提示
+func (c *CLI) localDBError(err error) error {
+	instance := c.localDBInstance()
+	return fmt.Errorf("%w\nhint: make sure LocalDB instance %s is running ('sqllocaldb start %s')", err, instance, instance)
+}
+
+// localDBConnString 返回连接 LocalDB 实例命名管道的连接串；提供了 Config.ConnectionString 时其中的参数优先，
+// 服务器地址和端口总是替换为实例的管道
+func (c *CLI) localDBConnString() string {
+	params := make(map[string]string)
+	for k, v := range c.connParams() {
+		params[k] = v[0]
+	}
+	// 集成身份验证时不提供用户名和密码，驱动使用 SSPI 以当前 Windows 账号登录
+	if !c.trustedConnection() {
+		params["user id"] = c.username
+		params["password"] = c.password
+	}
+	if c.config.ConnectionString != "" {
+		if cfg, err := msdsn.Parse(c.config.ConnectionString); err == nil {
+			for k, v := range cfg.Parameters {
+				params[k] = v
+			}
+		}
+	}
+	delete(params, "port")
+	params["server"] = c.localDBPipe
+	params["protocol"] = "np"
+	return odbcConnString(params)
+}
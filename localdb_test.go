@@ -0,0 +1,107 @@
+package mssql
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/microsoft/go-mssqldb/msdsn"
+)
+
+func TestParseLocalDBPipe(t *testing.T) {
+	tests := []struct {
+		name    string
+		info    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "running",
+			info: "Name:               MSSQLLocalDB\r\n" +
+				"Version:            15.0.4153.1\r\n" +
+				"State:              Running\r\n" +
+				"Instance pipe name: np:\\\\.\\pipe\\LOCALDB#1A2B3C4D\\tsql\\query\r\n",
+			want: `\\.\pipe\LOCALDB#1A2B3C4D\tsql\query`,
+		},
+		{
+			name: "stopped",
+			info: "Name:               MSSQLLocalDB\r\n" +
+				"State:              Stopped\r\n" +
+				"Instance pipe name: \r\n",
+			wantErr: true,
+		},
+		{
+			name:    "empty",
+			info:    "",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseLocalDBPipe("MSSQLLocalDB", tt.info)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "sqllocaldb start MSSQLLocalDB") {
+					t.Fatalf("parseLocalDBPipe() error = %v, want a hint to start the instance", err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("parseLocalDBPipe() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocalDBConnString(t *testing.T) {
+	c := NewCLIWithConfig(&testTerm{}, &Config{
+		Host:     `(localdb)\Dev`,
+		Port:     1433,
+		Username: "sa",
+		Password: "p;w={x}",
+		Database: "app",
+	})
+	if c.instance != "Dev" || c.serverAddress() != `(localdb)\Dev` {
+		t.Fatalf("instance = %q, address = %q", c.instance, c.serverAddress())
+	}
+	c.localDBPipe = `\\.\pipe\LOCALDB#1A2B3C4D\tsql\query`
+
+	dsn := c.connectionString()
+	for _, want := range []string{
+		`server={\\.\pipe\LOCALDB#1A2B3C4D\tsql\query}`,
+		"protocol={np}",
+		"user id={sa}",
+		"password={p;w={x}}}",
+		"database={app}",
+	} {
+		if !strings.Contains(dsn, want) {
+			t.Errorf("connection string %q does not contain %q", dsn, want)
+		}
+	}
+	if strings.Contains(dsn, "port=") {
+		t.Errorf("connection string %q should not set a port for LocalDB", dsn)
+	}
+}
+
+func TestODBCConnString(t *testing.T) {
+	params := map[string]string{
+		"server":   "db1",
+		"user id":  "sa",
+		"password": " p;w=1}x ",
+		"database": "app",
+	}
+	cfg, err := msdsn.Parse(odbcConnString(params))
+	if err != nil {
+		t.Fatalf("msdsn.Parse: %v", err)
+	}
+	for key, want := range params {
+		if got := cfg.Parameters[key]; got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestLocalDBDefaultInstance(t *testing.T) {
+	c := NewCLIWithConfig(&testTerm{}, &Config{Host: "(localdb)"})
+	if got := c.localDBInstance(); got != defaultLocalDBInstance {
+		t.Fatalf("localDBInstance() = %q, want %q", got, defaultLocalDBInstance)
+	}
+}
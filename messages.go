@@ -0,0 +1,73 @@
+package mssql
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"sync"
+
+	mssqldb "github.com/microsoft/go-mssqldb"
+	"github.com/microsoft/go-mssqldb/msdsn"
+)
+
+// messageSinkKey 在 context 中保存接收服务器消息的函数
+type messageSinkKey struct{}
+
+// rowsAffectedKey 在 context 中保存接收影响行数的函数
+type rowsAffectedKey struct{}
+
+// rowsAffectedPattern 匹配驱动记录的影响行数消息：DONE 令牌为 "(Rows affected: n)"，
+// DONEINPROC 令牌为 "(n rows affected)"
+var rowsAffectedPattern = regexp.MustCompile(`^\((?:(\d+) rows?(?:\(s\))? affected|Rows affected: (\d+))\)$`)
+
+// messageLogger 将驱动记录的 PRINT / RAISERROR 消息和影响的行数转发给发起语句的 CLI
+//
+// 驱动只通过全局 logger 报告信息类消息，消息在 token 处理时即被记录，
+// 因此 RAISERROR ... WITH NOWAIT 的消息可以在语句执行过程中实时显示。
+type messageLogger struct{}
+
+func (messageLogger) Log(ctx context.Context, category msdsn.Log, msg string) {
+	if ctx == nil {
+		return
+	}
+	switch category {
+	case msdsn.LogMessages:
+		if sink, ok := ctx.Value(statsSinkKey{}).(func(string)); ok && isStatisticsMessage(msg) {
+			sink(msg)
+			return
+		}
+		if sink, ok := ctx.Value(messageSinkKey{}).(func(string)); ok {
+			sink(msg)
+		}
+	case msdsn.LogRows:
+		sink, ok := ctx.Value(rowsAffectedKey{}).(func(int64))
+		if m := rowsAffectedPattern.FindStringSubmatch(msg); ok && m != nil {
+			n, _ := strconv.ParseInt(m[1]+m[2], 10, 64)
+			sink(n)
+		}
+	}
+}
+
+var installLoggerOnce sync.Once
+
+// installMessageLogger 安装全局消息 logger，必须在建立连接之前调用
+func installMessageLogger() {
+	installLoggerOnce.Do(func() {
+		mssqldb.SetContextLogger(messageLogger{})
+	})
+}
+
+// withMessages 返回携带消息接收函数的 context，消息直接输出到终端，安静模式下忽略
+func (c *CLI) withMessages(ctx context.Context) context.Context {
+	return context.WithValue(ctx, messageSinkKey{}, func(msg string) {
+		// stats on 时不是由 executeQuery 发起的语句（如目录命令）的统计消息不显示
+		if c.display.quiet || c.statsEnabled && isStatisticsMessage(msg) {
+			return
+		}
+		c.outMu.Lock()
+		defer c.outMu.Unlock()
+		c.clearSpinner()
+		fmt.Fprintf(c.term, "%s\n", c.display.colorize("-- "+msg, ansiDim))
+	})
+}
@@ -0,0 +1,42 @@
+package mssql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/microsoft/go-mssqldb/msdsn"
+)
+
+func TestMessageLoggerRowsAffected(t *testing.T) {
+	tests := []struct {
+		msg  string
+		want []int64
+	}{
+		{msg: "(Rows affected: 5)", want: []int64{5}},
+		{msg: "(Rows affected: 0)", want: []int64{0}},
+		{msg: "(12 rows affected)", want: []int64{12}},
+		{msg: "(1 row affected)", want: []int64{1}},
+		{msg: "(3 row(s) affected)", want: []int64{3}},
+		{msg: "Rows affected: 5", want: nil},
+		{msg: "(five rows affected)", want: nil},
+	}
+	for _, tt := range tests {
+		var got []int64
+		ctx := context.WithValue(context.Background(), rowsAffectedKey{}, func(n int64) { got = append(got, n) })
+		messageLogger{}.Log(ctx, msdsn.LogRows, tt.msg)
+		if len(got) != len(tt.want) || (len(got) == 1 && got[0] != tt.want[0]) {
+			t.Errorf("Log(%q) recorded %v, want %v", tt.msg, got, tt.want)
+		}
+	}
+}
+
+func TestMessageLoggerMessages(t *testing.T) {
+	var got []string
+	ctx := context.WithValue(context.Background(), messageSinkKey{}, func(msg string) { got = append(got, msg) })
+	messageLogger{}.Log(ctx, msdsn.LogMessages, "Changed database context to 'app'.")
+	// 影响行数不作为消息显示
+	messageLogger{}.Log(ctx, msdsn.LogRows, "(Rows affected: 5)")
+	if len(got) != 1 || got[0] != "Changed database context to 'app'." {
+		t.Errorf("messages = %q", got)
+	}
+}
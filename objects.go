@@ -0,0 +1,471 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// dbObject 解析得到的数据库对象
+type dbObject struct {
+	id     int64
+	schema string
+	name   string
+	typ    string // sys.objects.type，如 U、V、P
+}
+
+// fullName 返回 schema.name 形式的对象名
+func (o dbObject) fullName() string {
+	return o.schema + "." + o.name
+}
+
+// parseObjectName 将 [db.][schema.]name 拆分为各部分，支持 [方括号] 和 "双引号" 标识符
+func parseObjectName(name string) ([]string, error) {
+	var parts []string
+	var part strings.Builder
+	quoted := false
+	for i := 0; i < len(name); i++ {
+		ch := name[i]
+		switch {
+		case ch == '[' || ch == '"':
+			closing := byte(']')
+			if ch == '"' {
+				closing = '"'
+			}
+			i++
+			for ; i < len(name); i++ {
+				if name[i] == closing {
+					// 连续两个结束符表示字面量
+					if i+1 < len(name) && name[i+1] == closing {
+						part.WriteByte(closing)
+						i++
+						continue
+					}
+					break
+				}
+				part.WriteByte(name[i])
+			}
+			if i >= len(name) {
+				return nil, fmt.Errorf("unterminated identifier in '%s'", name)
+			}
+			quoted = true
+		case ch == '.':
+			parts = append(parts, part.String())
+			part.Reset()
+			quoted = false
+		default:
+			part.WriteByte(ch)
+		}
+	}
+	if part.Len() == 0 && !quoted {
+		return nil, fmt.Errorf("invalid object name '%s'", name)
+	}
+	parts = append(parts, part.String())
+	if len(parts) > 3 {
+		return nil, fmt.Errorf("invalid object name '%s'", name)
+	}
+	return parts, nil
+}
+
+// resolveObject 在当前数据库中解析对象名，对象不存在或位于其他数据库时返回错误
+// 不带架构的名称依次在默认架构、dbo 和 sys 架构中查找，sys 用于解析 master 中的系统存储过程
+func (c *CLI) resolveObject(ctx context.Context, name string) (dbObject, error) {
+	var obj dbObject
+	parts, err := parseObjectName(strings.TrimSpace(name))
+	if err != nil {
+		return obj, err
+	}
+	if len(parts) == 3 {
+		if parts[0] != "" && !strings.EqualFold(parts[0], c.database) {
+			return obj, fmt.Errorf("'%s' is in database %s; switch with USE %s first", name, parts[0], quoteIdent(parts[0]))
+		}
+		parts = parts[1:]
+	}
+
+	// 与服务器的名称解析一致：先在默认架构中查找，再查找 dbo
+	schemas := []string{"dbo"}
+	if c.defaultSchema != "" && !strings.EqualFold(c.defaultSchema, "dbo") {
+		schemas = []string{c.defaultSchema, "dbo"}
+	}
+	var candidates []string
+	switch {
+	case len(parts) == 2 && parts[0] != "":
+		candidates = append(candidates, quoteIdent(parts[0])+"."+quoteIdent(parts[1]))
+	case len(parts) == 2:
+		for _, schema := range schemas {
+			candidates = append(candidates, quoteIdent(schema)+"."+quoteIdent(parts[1]))
+		}
+	default:
+		for _, schema := range append(schemas, "sys") {
+			candidates = append(candidates, quoteIdent(schema)+"."+quoteIdent(parts[0]))
+		}
+	}
+
+	for _, candidate := range candidates {
+		err := c.conn.QueryRowContext(ctx, `SELECT o.object_id, s.name, o.name, RTRIM(o.type)
+FROM sys.all_objects AS o
+JOIN sys.schemas AS s ON s.schema_id = o.schema_id
+WHERE o.object_id = OBJECT_ID(@name)`, sql.Named("name", candidate)).Scan(&obj.id, &obj.schema, &obj.name, &obj.typ)
+		if err == nil {
+			return obj, nil
+		}
+		if err != sql.ErrNoRows {
+			return obj, err
+		}
+	}
+	return obj, fmt.Errorf("object '%s' does not exist in database %s", name, c.database)
+}
+
+// sqlTypeExpr 返回将类型渲染为 nvarchar(100)、decimal(18,2) 形式的 T-SQL 表达式
+// 参数为 user_type_id、max_length、precision、scale 所在的列
+func sqlTypeExpr(typeID, maxLength, precision, scale string) string {
+	return `TYPE_NAME(` + typeID + `) + CASE
+	WHEN TYPE_NAME(` + typeID + `) IN ('varchar', 'char', 'varbinary', 'binary')
+		THEN '(' + CASE ` + maxLength + ` WHEN -1 THEN 'max' ELSE CAST(` + maxLength + ` AS varchar(10)) END + ')'
+	WHEN TYPE_NAME(` + typeID + `) IN ('nvarchar', 'nchar')
+		THEN '(' + CASE ` + maxLength + ` WHEN -1 THEN 'max' ELSE CAST(` + maxLength + ` / 2 AS varchar(10)) END + ')'
+	WHEN TYPE_NAME(` + typeID + `) IN ('decimal', 'numeric')
+		THEN '(' + CAST(` + precision + ` AS varchar(10)) + ',' + CAST(` + scale + ` AS varchar(10)) + ')'
+	WHEN TYPE_NAME(` + typeID + `) IN ('datetime2', 'time', 'datetimeoffset')
+		THEN '(' + CAST(` + scale + ` AS varchar(10)) + ')'
+	ELSE '' END`
+}
+
+// queryValues 在会话连接上执行查询并读取全部行，用于需要在客户端加工的结果
+func (c *CLI) queryValues(ctx context.Context, query string, args ...interface{}) ([][]interface{}, error) {
+	rows, err := c.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	var data [][]interface{}
+	for rows.Next() {
+		vals, err := scanRow(rows, len(cols))
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, vals)
+	}
+	return data, rows.Err()
+}
+
+// section 在多个结果之间输出小节标题，隐藏表头或安静模式时不输出
+func (c *CLI) section(title string) {
+	if !c.display.headers || c.display.quiet {
+		return
+	}
+	fmt.Fprintf(c.out, "%s\n", c.display.colorize(title, ansiBold))
+}
+
+// describeObject 处理 desc <name> 命令，显示表或视图的列、索引和约束，或存储过程和函数的参数
+func (c *CLI) describeObject(arg string) {
+	if arg == "" {
+		fmt.Fprintf(c.term, "Usage: desc <[schema.]table|procedure>\n")
+		return
+	}
+	ctx, cancel := c.statementContext()
+	defer cancel()
+	defer c.flushOutput()
+
+	obj, err := c.resolveObject(ctx, arg)
+	if err != nil {
+		c.printError(err)
+		return
+	}
+	switch obj.typ {
+	case "U", "V":
+		c.describeTable(ctx, obj)
+	case "P", "PC", "X", "FN", "IF", "TF", "FS", "FT":
+		c.describeProcedure(ctx, obj)
+	default:
+		c.printError(fmt.Errorf("%s is not a table, view, procedure or function", obj.fullName()))
+	}
+}
+
+// describeTable 显示表的列定义，随后是主键和索引、外键、检查约束
+func (c *CLI) describeTable(ctx context.Context, obj dbObject) {
+	startTime := time.Now()
+	id := sql.Named("id", obj.id)
+	c.section(obj.fullName())
+	if err := c.executeQuery(ctx, `SELECT c.column_id AS [ordinal], c.name,
+	`+sqlTypeExpr("c.user_type_id", "c.max_length", "c.precision", "c.scale")+` AS [type],
+	CASE c.is_nullable WHEN 1 THEN 'YES' ELSE 'NO' END AS [nullable],
+	dc.definition AS [default],
+	CASE WHEN ic.column_id IS NOT NULL THEN 'IDENTITY(' + CAST(ic.seed_value AS varchar(40)) + ','
+		+ CAST(ic.increment_value AS varchar(40)) + ')' END AS [identity],
+	cc.definition AS [computed]
+FROM sys.columns AS c
+LEFT JOIN sys.default_constraints AS dc ON dc.object_id = c.default_object_id
+LEFT JOIN sys.identity_columns AS ic ON ic.object_id = c.object_id AND ic.column_id = c.column_id
+LEFT JOIN sys.computed_columns AS cc ON cc.object_id = c.object_id AND cc.column_id = c.column_id
+WHERE c.object_id = @id
+ORDER BY c.column_id`, startTime, c.expanded, id); err != nil {
+		return
+	}
+
+	indexes, err := c.indexData(ctx, obj.id)
+	if err != nil {
+		c.printError(err)
+		return
+	}
+	if len(indexes) > 0 {
+		c.section("Indexes")
+		c.renderValues(indexColumns, indexes, startTime)
+	}
+
+	foreignKeys, err := c.foreignKeyData(ctx, obj.id)
+	if err != nil {
+		c.printError(err)
+		return
+	}
+	if len(foreignKeys) > 0 {
+		c.section("Foreign keys")
+		c.renderForeignKeys(foreignKeys, startTime)
+	}
+
+	checks, err := c.queryValues(ctx, `SELECT name, definition,
+	CASE is_disabled WHEN 1 THEN 'YES' ELSE 'NO' END,
+	CASE is_not_trusted WHEN 1 THEN 'NO' ELSE 'YES' END
+FROM sys.check_constraints
+WHERE parent_object_id = @id
+ORDER BY name`, id)
+	if err != nil {
+		c.printError(err)
+		return
+	}
+	if len(checks) > 0 {
+		c.section("Check constraints")
+		c.renderValues([]string{"name", "definition", "disabled", "trusted"}, checks, startTime)
+	}
+}
+
+// describeProcedure 显示存储过程或函数的参数，随后是创建、修改时间和是否为本机编译
+// 系统存储过程的参数只在 sys.all_parameters 中
+func (c *CLI) describeProcedure(ctx context.Context, obj dbObject) {
+	startTime := time.Now()
+	id := sql.Named("id", obj.id)
+	c.section(obj.fullName())
+	if err := c.executeQuery(ctx, `SELECT p.parameter_id AS [ordinal],
+	CASE p.parameter_id WHEN 0 THEN '(return)' ELSE p.name END AS [name],
+	`+sqlTypeExpr("p.user_type_id", "p.max_length", "p.precision", "p.scale")+` AS [type],
+	CASE WHEN p.is_output = 1 AND p.parameter_id > 0 THEN 'OUTPUT' WHEN p.parameter_id = 0 THEN 'RETURN' ELSE 'IN' END AS [direction],
+	CASE p.has_default_value WHEN 1 THEN 'YES' ELSE 'NO' END AS [has_default],
+	CASE WHEN t.is_table_type = 1 THEN SCHEMA_NAME(t.schema_id) + '.' + t.name END AS [table_type]
+FROM sys.all_parameters AS p
+JOIN sys.types AS t ON t.user_type_id = p.user_type_id
+WHERE p.object_id = @id
+ORDER BY p.parameter_id`, startTime, c.expanded, id); err != nil {
+		return
+	}
+
+	info, err := c.queryValues(ctx, `SELECT o.create_date, o.modify_date,
+	CASE ISNULL(m.uses_native_compilation, 0) WHEN 1 THEN 'YES' ELSE 'NO' END
+FROM sys.all_objects AS o
+LEFT JOIN sys.all_sql_modules AS m ON m.object_id = o.object_id
+WHERE o.object_id = @id`, id)
+	if err != nil {
+		c.printError(err)
+		return
+	}
+	c.renderValues([]string{"created", "modified", "natively_compiled"}, info, startTime)
+}
+
+// listIndexes 处理 indexes <table> 命令，每个索引一行显示类型、唯一性、键列、包含列和筛选条件
+func (c *CLI) listIndexes(arg string) {
+	if arg == "" {
+		fmt.Fprintf(c.term, "Usage: indexes <[schema.]table>\n")
+		return
+	}
+	startTime := time.Now()
+	ctx, cancel := c.statementContext()
+	defer cancel()
+	defer c.flushOutput()
+
+	obj, err := c.resolveObject(ctx, arg)
+	if err != nil {
+		c.printError(err)
+		return
+	}
+	indexes, err := c.indexData(ctx, obj.id)
+	if err != nil {
+		c.printError(err)
+		return
+	}
+	c.renderValues(indexColumns, indexes, startTime)
+}
+
+// indexColumns 索引列表输出的列
+var indexColumns = []string{"name", "type", "unique", "primary_key", "key_columns", "included_columns", "filter"}
+
+// indexData 返回表的索引，每个索引一行，键列（带排序方向）和包含列在客户端合并为逗号分隔的列表
+// 堆（index_id 0）显示为 HEAP 行
+func (c *CLI) indexData(ctx context.Context, objectID int64) ([][]interface{}, error) {
+	rows, err := c.conn.QueryContext(ctx, `SELECT i.index_id, ISNULL(i.name, 'HEAP'), i.type_desc,
+	i.is_unique, i.is_primary_key, i.filter_definition,
+	col.name, ic.is_included_column, ic.is_descending_key
+FROM sys.indexes AS i
+LEFT JOIN sys.index_columns AS ic ON ic.object_id = i.object_id AND ic.index_id = i.index_id
+LEFT JOIN sys.columns AS col ON col.object_id = ic.object_id AND col.column_id = ic.column_id
+WHERE i.object_id = @id
+ORDER BY i.index_id, ic.is_included_column, ic.key_ordinal, ic.index_column_id`, sql.Named("id", objectID))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var data [][]interface{}
+	var keys, included []string
+	lastID := int64(-1)
+	flush := func() {
+		if len(data) > 0 {
+			row := data[len(data)-1]
+			row[4], row[5] = strings.Join(keys, ", "), strings.Join(included, ", ")
+		}
+		keys, included = nil, nil
+	}
+	for rows.Next() {
+		var indexID int64
+		var name, typeDesc string
+		var unique, primary bool
+		var filter, column sql.NullString
+		var isIncluded, descending sql.NullBool
+		if err := rows.Scan(&indexID, &name, &typeDesc, &unique, &primary, &filter, &column, &isIncluded, &descending); err != nil {
+			return nil, err
+		}
+		if indexID != lastID {
+			flush()
+			lastID = indexID
+			var filterValue interface{}
+			if filter.Valid {
+				filterValue = filter.String
+			}
+			data = append(data, []interface{}{name, strings.ToLower(strings.ReplaceAll(typeDesc, "_", " ")),
+				yesNo(unique), yesNo(primary), "", "", filterValue})
+		}
+		if !column.Valid {
+			continue
+		}
+		switch {
+		case isIncluded.Bool:
+			included = append(included, column.String)
+		case descending.Bool:
+			keys = append(keys, column.String+" DESC")
+		default:
+			keys = append(keys, column.String)
+		}
+	}
+	flush()
+	return data, rows.Err()
+}
+
+// yesNo 将布尔值显示为 YES 或 NO
+func yesNo(b bool) string {
+	if b {
+		return "YES"
+	}
+	return "NO"
+}
+
+// listForeignKeys 处理 fks <table> 命令，显示表引用其他表和被其他表引用的外键
+func (c *CLI) listForeignKeys(arg string) {
+	if arg == "" {
+		fmt.Fprintf(c.term, "Usage: fks <[schema.]table>\n")
+		return
+	}
+	startTime := time.Now()
+	ctx, cancel := c.statementContext()
+	defer cancel()
+	defer c.flushOutput()
+
+	obj, err := c.resolveObject(ctx, arg)
+	if err != nil {
+		c.printError(err)
+		return
+	}
+	foreignKeys, err := c.foreignKeyData(ctx, obj.id)
+	if err != nil {
+		c.printError(err)
+		return
+	}
+	c.renderForeignKeys(foreignKeys, startTime)
+}
+
+// foreignKeyColumns 外键列表输出的列
+var foreignKeyColumns = []string{"direction", "name", "table", "columns", "referenced_table", "referenced_columns", "on_delete", "on_update", "status"}
+
+// foreignKeyData 返回表的外向和内向外键，每个外键一行，多列外键的列按键顺序在客户端合并
+func (c *CLI) foreignKeyData(ctx context.Context, objectID int64) ([][]interface{}, error) {
+	rows, err := c.conn.QueryContext(ctx, `SELECT fk.object_id,
+	CASE WHEN fk.parent_object_id = @id THEN 'outgoing' ELSE 'incoming' END, fk.name,
+	OBJECT_SCHEMA_NAME(fk.parent_object_id) + '.' + OBJECT_NAME(fk.parent_object_id), pc.name,
+	OBJECT_SCHEMA_NAME(fk.referenced_object_id) + '.' + OBJECT_NAME(fk.referenced_object_id), rc.name,
+	fk.delete_referential_action_desc, fk.update_referential_action_desc, fk.is_disabled, fk.is_not_trusted
+FROM sys.foreign_keys AS fk
+JOIN sys.foreign_key_columns AS fkc ON fkc.constraint_object_id = fk.object_id
+JOIN sys.columns AS pc ON pc.object_id = fkc.parent_object_id AND pc.column_id = fkc.parent_column_id
+JOIN sys.columns AS rc ON rc.object_id = fkc.referenced_object_id AND rc.column_id = fkc.referenced_column_id
+WHERE fk.parent_object_id = @id OR fk.referenced_object_id = @id
+ORDER BY CASE WHEN fk.parent_object_id = @id THEN 0 ELSE 1 END, fk.name, fkc.constraint_column_id`, sql.Named("id", objectID))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var data [][]interface{}
+	var columns, referenced []string
+	lastID := int64(-1)
+	flush := func() {
+		if len(data) > 0 {
+			row := data[len(data)-1]
+			row[3], row[5] = strings.Join(columns, ", "), strings.Join(referenced, ", ")
+		}
+		columns, referenced = nil, nil
+	}
+	for rows.Next() {
+		var id int64
+		var direction, name, table, column, refTable, refColumn, onDelete, onUpdate string
+		var disabled, untrusted bool
+		if err := rows.Scan(&id, &direction, &name, &table, &column, &refTable, &refColumn, &onDelete, &onUpdate, &disabled, &untrusted); err != nil {
+			return nil, err
+		}
+		if id != lastID {
+			flush()
+			lastID = id
+			status := "ok"
+			switch {
+			case disabled:
+				status = "DISABLED"
+			case untrusted:
+				status = "UNTRUSTED"
+			}
+			data = append(data, []interface{}{direction, name, table, "", refTable, "",
+				strings.ReplaceAll(onDelete, "_", " "), strings.ReplaceAll(onUpdate, "_", " "), status})
+		}
+		columns = append(columns, column)
+		referenced = append(referenced, refColumn)
+	}
+	flush()
+	return data, rows.Err()
+}
+
+// renderForeignKeys 输出外键列表，存在停用或不受信任的外键时给出警告，这类外键不能被优化器利用
+func (c *CLI) renderForeignKeys(data [][]interface{}, startTime time.Time) {
+	c.renderValues(foreignKeyColumns, data, startTime)
+	flagged := 0
+	for _, row := range data {
+		if row[8] != "ok" {
+			flagged++
+		}
+	}
+	if flagged > 0 {
+		fmt.Fprintf(c.out, "%s\n\n", c.display.colorize(fmt.Sprintf(
+			"Warning: %d foreign key(s) disabled or untrusted; the optimizer cannot rely on them "+
+				"(re-enable with ALTER TABLE ... WITH CHECK CHECK CONSTRAINT ...)", flagged), ansiRed))
+	}
+}
@@ -0,0 +1,145 @@
+package mssql
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// outputFile output 命令打开的结果输出文件
+type outputFile struct {
+	path string
+	file *os.File
+	buf  *bufio.Writer
+	tee  bool // 是否同时输出到终端
+}
+
+// Write 写入文件前去除 ANSI 颜色控制码
+func (o *outputFile) Write(p []byte) (int, error) {
+	if _, err := o.buf.WriteString(stripANSI(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush 将缓冲的内容写入文件
+func (o *outputFile) Flush() error {
+	return o.buf.Flush()
+}
+
+// Close 刷新缓冲并关闭文件
+func (o *outputFile) Close() error {
+	err := o.buf.Flush()
+	if cerr := o.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// resultTarget 返回结果输出的目标，terminal 为终端一侧的写入器（可能经过分页）
+func (c *CLI) resultTarget(terminal io.Writer) io.Writer {
+	switch {
+	case c.outFile == nil:
+		return terminal
+	case c.outFile.tee:
+		return io.MultiWriter(terminal, c.outFile)
+	default:
+		return c.outFile
+	}
+}
+
+// outputToTerminal 判断结果是否输出到终端
+func (c *CLI) outputToTerminal() bool {
+	return c.outFile == nil || c.outFile.tee
+}
+
+// setOutput 处理 output 命令：output [-a] [-tee] <file> 或 output off
+func (c *CLI) setOutput(arg string) {
+	fields := strings.Fields(arg)
+	if len(fields) == 0 {
+		switch {
+		case c.outFile == nil:
+			fmt.Fprintf(c.term, "Output: terminal\n")
+		case c.outFile.tee:
+			fmt.Fprintf(c.term, "Output: terminal and %s\n", c.outFile.path)
+		default:
+			fmt.Fprintf(c.term, "Output: %s\n", c.outFile.path)
+		}
+		return
+	}
+
+	if len(fields) == 1 && strings.EqualFold(fields[0], "off") {
+		if c.outFile == nil {
+			fmt.Fprintf(c.term, "Output is not redirected\n")
+			return
+		}
+		path := c.outFile.path
+		c.closeOutput()
+		fmt.Fprintf(c.term, "Output to %s closed\n", path)
+		return
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	tee := false
+	var path string
+	for _, field := range fields {
+		switch strings.ToLower(field) {
+		case "-a":
+			flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+		case "-tee":
+			tee = true
+		default:
+			if path != "" {
+				fmt.Fprintf(c.term, "Usage: output [-a] [-tee] <file> | output off\n")
+				return
+			}
+			path = field
+		}
+	}
+	if path == "" {
+		fmt.Fprintf(c.term, "Usage: output [-a] [-tee] <file> | output off\n")
+		return
+	}
+
+	file, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		fmt.Fprintf(c.term, "Error: %v\n", err)
+		return
+	}
+
+	c.closeOutput()
+	c.outFile = &outputFile{path: path, file: file, buf: bufio.NewWriter(file), tee: tee}
+	c.out.w = c.resultTarget(c.term)
+	if tee {
+		fmt.Fprintf(c.term, "Output copied to %s\n", path)
+	} else {
+		fmt.Fprintf(c.term, "Output redirected to %s\n", path)
+	}
+}
+
+// flushOutput 每条语句执行完毕后刷新输出文件
+func (c *CLI) flushOutput() {
+	if c.outFile == nil {
+		return
+	}
+	if err := c.outFile.Flush(); err != nil {
+		fmt.Fprintf(c.term, "Error writing %s: %v\n", c.outFile.path, err)
+		return
+	}
+	if !c.outFile.tee && !c.display.quiet {
+		fmt.Fprintf(c.term, "%s\n", c.display.colorize("-- output written to "+c.outFile.path, ansiDim))
+	}
+}
+
+// closeOutput 关闭输出文件并恢复输出到终端
+func (c *CLI) closeOutput() error {
+	if c.outFile == nil {
+		return nil
+	}
+	err := c.outFile.Close()
+	c.outFile = nil
+	c.out.w = c.term
+	return err
+}
@@ -0,0 +1,278 @@
+package mssql
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/chzyer/readline"
+)
+
+// outputWriter 结果输出的目标，可按结果集切换（例如先写入缓冲区再交给分页器）
+type outputWriter struct {
+	w io.Writer
+}
+
+func (o *outputWriter) Write(p []byte) (int, error) {
+	return o.w.Write(p)
+}
+
+// terminalFile 返回终端对应的 *os.File，供子进程直接使用
+func terminalFile(v interface{}) (*os.File, bool) {
+	if f, ok := v.(*os.File); ok {
+		return f, readline.IsTerminal(int(f.Fd()))
+	}
+	if f, ok := v.(interface{ Fd() uintptr }); ok && f.Fd() == os.Stdout.Fd() {
+		return os.Stdout, readline.IsTerminal(int(os.Stdout.Fd()))
+	}
+	return nil, false
+}
+
+// terminalSizer 可报告窗口尺寸的终端，例如 SSH 会话可实现该方法
+type terminalSizer interface {
+	Size() (width, height int)
+}
+
+// terminalSize 返回终端的宽度和高度，优先使用终端自身的 Size 方法
+func terminalSize(v interface{}) (width, height int, ok bool) {
+	if s, isSizer := v.(terminalSizer); isSizer {
+		width, height = s.Size()
+		return width, height, width > 0 && height > 0
+	}
+	f, isFd := v.(interface{ Fd() uintptr })
+	if !isFd || !readline.IsTerminal(int(f.Fd())) {
+		return 0, 0, false
+	}
+	width, height, err := readline.GetSize(int(f.Fd()))
+	if err != nil || width <= 0 || height <= 0 {
+		return 0, 0, false
+	}
+	return width, height, true
+}
+
+// defaultPager 返回默认的分页命令：$PAGER 或 less -S
+func defaultPager() string {
+	if pager := strings.TrimSpace(os.Getenv("PAGER")); pager != "" {
+		return pager
+	}
+	return "less -S"
+}
+
+// setPager 设置分页命令，off 关闭分页，on 或空参数使用默认命令
+func (c *CLI) setPager(arg string) {
+	switch strings.ToLower(arg) {
+	case "":
+		if c.pager == "" {
+			fmt.Fprintf(c.term, "Pager: off\n")
+		} else {
+			fmt.Fprintf(c.term, "Pager: %s\n", c.pager)
+		}
+		return
+	case "off":
+		c.pager = ""
+		fmt.Fprintf(c.term, "Pager off\n")
+		return
+	case "on":
+		arg = defaultPager()
+	}
+	c.pager = arg
+	fmt.Fprintf(c.term, "Pager set to %s\n", c.pager)
+}
+
+// pagerEnabled 判断当前是否可以使用分页器，仅在输出为本地终端时启用
+func (c *CLI) pagerEnabled() bool {
+	if c.pager == "" {
+		return false
+	}
+	_, ok := terminalFile(c.term)
+	return ok
+}
+
+// beginPaging 按分页设置重定向结果输出，返回的函数在结果输出完毕后调用
+// 外部分页器优先；否则启用 more 时使用内置的 --More-- 分页
+func (c *CLI) beginPaging() func() {
+	// 结果只写入文件或非交互执行时不分页
+	if !c.outputToTerminal() || c.nonInteractive {
+		return func() {}
+	}
+	if !c.pagerEnabled() {
+		if !c.more {
+			return func() {}
+		}
+		c.paging = &moreWriter{c: c, w: c.term, height: c.screenHeight()}
+		c.out.w = c.resultTarget(c.paging)
+		return func() {
+			c.out.w = c.resultTarget(c.term)
+			c.paging = nil
+		}
+	}
+	var buf bytes.Buffer
+	c.out.w = c.resultTarget(&buf)
+	return func() {
+		c.out.w = c.resultTarget(c.term)
+		c.page(buf.Bytes())
+	}
+}
+
+// page 输出内容，超过一屏时交给分页器，分页器无法启动时直接输出
+func (c *CLI) page(data []byte) {
+	_, height, ok := terminalSize(c.term)
+	if !ok || bytes.Count(data, []byte("\n")) < height-1 {
+		c.term.Write(data)
+		return
+	}
+
+	tty, _ := terminalFile(c.term)
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", c.pager)
+	} else {
+		cmd = exec.Command("sh", "-c", c.pager)
+	}
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout = tty
+	cmd.Stderr = tty
+
+	// 分页器运行期间 Ctrl+C 只作用于分页器，不结束当前会话
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+	defer signal.Stop(sigs)
+
+	if err := cmd.Start(); err != nil {
+		fmt.Fprintf(c.term, "Pager '%s' failed: %v\n", c.pager, err)
+		c.term.Write(data)
+		return
+	}
+	cmd.Wait()
+}
+
+// defaultScreenHeight 无法获取终端尺寸时的默认屏幕高度
+const defaultScreenHeight = 24
+
+// screenHeight 返回终端高度，无法获取时（如 SSH 会话）使用配置的行数
+func (c *CLI) screenHeight() int {
+	if _, height, ok := terminalSize(c.term); ok {
+		return height
+	}
+	return c.moreLines
+}
+
+// setMore 设置内置分页：on、off 或无法获取终端尺寸时使用的屏幕行数
+func (c *CLI) setMore(arg string) {
+	switch strings.ToLower(arg) {
+	case "":
+		fmt.Fprintf(c.term, "More: %s (screen height %d)\n", onOff(c.more), c.screenHeight())
+		return
+	case "on", "off":
+		c.more = strings.ToLower(arg) == "on"
+	default:
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 2 {
+			fmt.Fprintf(c.term, "Usage: more on|off|<lines>\n")
+			return
+		}
+		c.moreLines = n
+		c.more = true
+	}
+	fmt.Fprintf(c.term, "More %s (screen height %d)\n", onOff(c.more), c.screenHeight())
+}
+
+// moreWriter 内置分页，每输出一屏后提示用户继续、显示全部或放弃
+type moreWriter struct {
+	c      *CLI
+	w      io.Writer
+	height int
+	lines  int
+	all    bool // 不再提示，输出剩余全部内容
+	quit   bool // 用户放弃，丢弃后续输出
+}
+
+func (m *moreWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 && !m.quit {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			_, err := m.w.Write(p)
+			return n, err
+		}
+		if _, err := m.w.Write(p[:i+1]); err != nil {
+			return n, err
+		}
+		p = p[i+1:]
+
+		m.lines++
+		if !m.all && m.lines >= m.height-1 {
+			m.prompt()
+		}
+	}
+	return n, nil
+}
+
+// prompt 通过 Reader 读取用户的选择
+func (m *moreWriter) prompt() {
+	m.c.reader.SetPrompt(m.c.display.colorize("--More-- (q to quit, space for next page, a for all)", ansiBold) + " ")
+	line, err := m.c.reader.ReadLine()
+	if err != nil {
+		m.quit = true
+		return
+	}
+
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "q":
+		m.quit = true
+	case "a":
+		m.all = true
+	default:
+		m.lines = 0
+	}
+}
+
+// pagingQuit 判断用户是否在 --More-- 提示处放弃了当前结果
+func (c *CLI) pagingQuit() bool {
+	return c.paging != nil && c.paging.quit
+}
+
+// screenWidth 返回表格可用的宽度，width 命令的设置优先，0 表示不限制
+func (c *CLI) screenWidth() int {
+	if c.widthOverride > 0 {
+		return c.widthOverride
+	}
+	if !c.outputToTerminal() {
+		return 0
+	}
+	if width, _, ok := terminalSize(c.term); ok {
+		return width
+	}
+	return 0
+}
+
+// setWidth 设置表格宽度，auto 表示按终端宽度自动检测
+func (c *CLI) setWidth(arg string) {
+	switch strings.ToLower(arg) {
+	case "":
+	case "auto", "0":
+		c.widthOverride = 0
+	default:
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 0 {
+			fmt.Fprintf(c.term, "Usage: width <n>|auto\n")
+			return
+		}
+		c.widthOverride = n
+	}
+
+	switch width := c.screenWidth(); {
+	case c.widthOverride > 0:
+		fmt.Fprintf(c.term, "Width: %d\n", width)
+	case width > 0:
+		fmt.Fprintf(c.term, "Width: auto (%d)\n", width)
+	default:
+		fmt.Fprintf(c.term, "Width: auto (unknown, tables are not limited)\n")
+	}
+}
@@ -0,0 +1,325 @@
+package mssql
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	mssqldb "github.com/microsoft/go-mssqldb"
+)
+
+// errParamsCancelled 输入参数值时被中断，语句没有执行
+var errParamsCancelled = errors.New("parameter input cancelled")
+
+// paramValue 参数的值：bind 命令绑定的值在执行时直接使用，提示输入的值作为下次提示的默认值
+type paramValue struct {
+	name  string // 参数名，保留输入时的大小写，不含 @
+	text  string
+	null  bool
+	bound bool
+}
+
+// display 返回值的显示文本
+func (v paramValue) display() string {
+	if v.null {
+		return "NULL"
+	}
+	return "'" + strings.ReplaceAll(v.text, "'", "''") + "'"
+}
+
+// paramPattern 匹配 @name 形式的变量或参数引用
+var paramPattern = regexp.MustCompile(`@[\p{L}_#$][\p{L}\p{N}_@#$]*`)
+
+// execWordPattern 匹配 EXEC 关键字
+var execWordPattern = regexp.MustCompile(`(?i)\bEXEC(?:UTE)?\b`)
+
+// declareEndWords 出现在 DECLARE 列表之后、表示下一条语句开始的关键字
+var declareEndWords = map[string]bool{
+	"SELECT": true, "SET": true, "INSERT": true, "UPDATE": true, "DELETE": true, "MERGE": true,
+	"IF": true, "WHILE": true, "BEGIN": true, "EXEC": true, "EXECUTE": true, "RETURN": true,
+	"PRINT": true, "DECLARE": true, "FETCH": true, "OPEN": true, "RAISERROR": true, "THROW": true,
+}
+
+// declaredVariables 返回批处理中 DECLARE 声明的变量名（小写），包括 DECLARE @a int, @b int 这样的列表
+func declaredVariables(code string) map[string]bool {
+	declared := make(map[string]bool)
+	for _, m := range declareVariablePattern.FindAllStringIndex(code, -1) {
+		i := m[1] - 1
+		for i < len(code) && code[i] == '@' {
+			name := paramPattern.FindString(code[i:])
+			if name == "" {
+				break
+			}
+			declared[strings.ToLower(name)] = true
+			i = nextDeclaration(code, i+len(name))
+		}
+	}
+	return declared
+}
+
+// nextDeclaration 从一个变量声明的类型部分开始向后扫描，找到列表中下一个变量的 @ 的位置；
+// 列表在分号或下一条语句的关键字处结束，此时返回 len(code)
+func nextDeclaration(code string, i int) int {
+	depth := 0
+	for i < len(code) {
+		ch := code[i]
+		switch {
+		case ch == '(':
+			depth++
+		case ch == ')':
+			depth--
+		case ch == ';' && depth == 0:
+			return len(code)
+		case ch == ',' && depth == 0:
+			i++
+			for i < len(code) && (code[i] == ' ' || code[i] == '\t' || code[i] == '\r' || code[i] == '\n') {
+				i++
+			}
+			if i < len(code) && code[i] == '@' {
+				return i
+			}
+			return len(code)
+		case depth == 0 && isWordStart(code, i):
+			end := i
+			for end < len(code) && isWordChar(code[end]) {
+				end++
+			}
+			if declareEndWords[strings.ToUpper(code[i:end])] {
+				return len(code)
+			}
+			i = end
+			continue
+		}
+		i++
+	}
+	return len(code)
+}
+
+// isWordChar 判断字节是否可以作为标识符的一部分
+func isWordChar(ch byte) bool {
+	return ch == '_' || ch >= '0' && ch <= '9' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= 0x80
+}
+
+// isWordStart 判断 i 处是否为一个单词的开头
+func isWordStart(code string, i int) bool {
+	return isWordChar(code[i]) && (i == 0 || !isWordChar(code[i-1]) && code[i-1] != '@')
+}
+
+// statementParams 返回语句中未声明的 @name 参数名，按首次出现的顺序排列；
+// 忽略字符串和注释中的内容、@@ 系统函数、DECLARE 声明的变量、EXEC 的 @name = 命名实参，
+// CREATE PROCEDURE 等模块定义中的参数属于模块本身，整条语句不检测
+func statementParams(sqlStr string) []string {
+	if moduleStartPattern.MatchString(sqlStr) {
+		return nil
+	}
+	var lexer sqlLexer
+	lines := strings.Split(sqlStr, "\n")
+	for i, line := range lines {
+		lines[i] = lexer.scan(line)
+	}
+	code := strings.Join(lines, "\n")
+
+	declared := declaredVariables(code)
+	firstExec := -1
+	if loc := execWordPattern.FindStringIndex(code); loc != nil {
+		firstExec = loc[0]
+	}
+	seen := make(map[string]bool)
+	var names []string
+	for _, m := range paramPattern.FindAllStringIndex(code, -1) {
+		if m[0] > 0 && (code[m[0]-1] == '@' || isWordChar(code[m[0]-1])) {
+			continue
+		}
+		name := code[m[0]:m[1]]
+		key := strings.ToLower(name)
+		if declared[key] || seen[key] {
+			continue
+		}
+		// EXEC proc @name = value 中的 @name 是存储过程的形参
+		if firstExec >= 0 && m[0] > firstExec {
+			if rest := strings.TrimLeft(code[m[1]:], " \t\r\n"); strings.HasPrefix(rest, "=") {
+				continue
+			}
+		}
+		seen[key] = true
+		names = append(names, name[1:])
+	}
+	return names
+}
+
+// parseParamText 解析提示或 bind 命令中输入的参数值：NULL（不区分大小写）表示空值，
+// 字符串不需要引号，整个值用单引号括起时去掉引号，其中连续两个单引号表示一个单引号，用于输入字面的 NULL 或首尾空格
+func parseParamText(name, text string) paramValue {
+	text = strings.TrimSpace(text)
+	if strings.EqualFold(text, "NULL") {
+		return paramValue{name: name, null: true}
+	}
+	if len(text) >= 2 && strings.HasPrefix(text, "'") && strings.HasSuffix(text, "'") {
+		text = strings.ReplaceAll(text[1:len(text)-1], "''", "'")
+	}
+	return paramValue{name: name, text: text}
+}
+
+// paramArg 按服务器推断的类型转换参数值：整数、bit 和浮点数转换为对应的 Go 类型，varchar 和 char 以非 Unicode
+// 字符串发送以免列上的隐式转换，其余类型以字符串发送，由服务器转换
+func paramArg(v paramValue, hint string) (interface{}, error) {
+	if v.null {
+		return sql.Named(v.name, nil), nil
+	}
+	base := strings.ToLower(hint)
+	if i := strings.IndexByte(base, '('); i >= 0 {
+		base = base[:i]
+	}
+	var value interface{} = v.text
+	var err error
+	switch base {
+	case "tinyint", "smallint", "int", "bigint":
+		value, err = strconv.ParseInt(v.text, 10, 64)
+	case "bit":
+		value, err = strconv.ParseBool(v.text)
+	case "float", "real":
+		value, err = strconv.ParseFloat(v.text, 64)
+	case "varchar", "char":
+		value = mssqldb.VarChar(v.text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s value for @%s: %s", hint, v.name, v.text)
+	}
+	return sql.Named(v.name, value), nil
+}
+
+// paramHints 用 sp_describe_undeclared_parameters 获取参数的推断类型，键为小写的参数名；
+// 该过程不支持临时表、同一参数多次使用等情况，失败时没有类型提示，值以字符串发送。
+// 同一条语句再次执行时使用缓存的结果
+func (c *CLI) paramHints(sqlStr string) map[string]string {
+	if c.paramHintSQL == sqlStr {
+		return c.paramHintCache
+	}
+	ctx, cancel := c.statementContext()
+	defer cancel()
+	hints := make(map[string]string)
+	data, err := c.queryValues(ctx, "EXEC sys.sp_describe_undeclared_parameters @tsql", sql.Named("tsql", sqlStr))
+	if err == nil {
+		for _, row := range data {
+			if len(row) > 3 && row[1] != nil && row[3] != nil {
+				hints[strings.ToLower(strings.TrimPrefix(fmt.Sprint(row[1]), "@"))] = fmt.Sprint(row[3])
+			}
+		}
+	}
+	c.paramHintSQL, c.paramHintCache = sqlStr, hints
+	return hints
+}
+
+// bindParams 为语句中未声明的参数准备 sql.Named 实参：bind 绑定的值直接使用，其余逐个提示输入，
+// 提示中显示推断的类型和上次输入的值，直接回车沿用上次的值。没有参数时返回 nil；
+// 输入被中断时返回 ok 为 false，语句不执行
+func (c *CLI) bindParams(sqlStr string) (args []interface{}, ok bool) {
+	names := statementParams(sqlStr)
+	if len(names) == 0 {
+		return nil, true
+	}
+	hints := c.paramHints(sqlStr)
+	if c.params == nil {
+		c.params = make(map[string]paramValue)
+	}
+	for _, name := range names {
+		key := strings.ToLower(name)
+		v, known := c.params[key]
+		for {
+			if !v.bound && c.nonInteractive {
+				c.printError(fmt.Errorf("parameter @%s is not bound (use 'bind @%s=<value>')", name, name))
+				return nil, false
+			}
+			if !v.bound {
+				prompt := "@" + name
+				if hint := hints[key]; hint != "" {
+					prompt += " (" + hint + ")"
+				}
+				if known {
+					prompt += " [" + v.display() + "]"
+				}
+				c.reader.SetPrompt(prompt + ": ")
+				line, err := c.reader.ReadLine()
+				if err != nil {
+					fmt.Fprintf(c.term, "Cancelled.\n")
+					return nil, false
+				}
+				if strings.TrimSpace(line) != "" || !known {
+					v = parseParamText(name, line)
+				}
+				v.name = name
+				c.params[key] = v
+				known = true
+			}
+			arg, err := paramArg(v, hints[key])
+			if err == nil {
+				args = append(args, arg)
+				break
+			}
+			if v.bound {
+				c.printError(err)
+				return nil, false
+			}
+			fmt.Fprintf(c.term, "%v\n", err)
+			known = false
+		}
+	}
+	return args, true
+}
+
+// bindCommand 处理 bind 命令：不带参数时列出绑定的值，bind @name=value 或 bind @name value 绑定一个值
+func (c *CLI) bindCommand(arg string) {
+	if arg == "" {
+		var bound []paramValue
+		for _, v := range c.params {
+			if v.bound {
+				bound = append(bound, v)
+			}
+		}
+		if len(bound) == 0 {
+			fmt.Fprintf(c.term, "No parameters bound.\n")
+			return
+		}
+		sort.Slice(bound, func(i, j int) bool { return strings.ToLower(bound[i].name) < strings.ToLower(bound[j].name) })
+		for _, v := range bound {
+			fmt.Fprintf(c.term, "@%s = %s\n", v.name, v.display())
+		}
+		return
+	}
+
+	name, value, found := strings.Cut(arg, "=")
+	if !found {
+		name, value, found = strings.Cut(arg, " ")
+	}
+	name = strings.TrimSpace(name)
+	if !found || !strings.HasPrefix(name, "@") || paramPattern.FindString(name) != name {
+		fmt.Fprintf(c.term, "Usage: bind @name=value (NULL for a null value, quote as 'NULL' for the text)\n")
+		return
+	}
+	v := parseParamText(name[1:], value)
+	v.bound = true
+	if c.params == nil {
+		c.params = make(map[string]paramValue)
+	}
+	c.params[strings.ToLower(v.name)] = v
+	fmt.Fprintf(c.term, "@%s = %s\n", v.name, v.display())
+}
+
+// unbindCommand 处理 unbind [@name] 命令，清除一个或所有参数的值，之后执行时重新提示
+func (c *CLI) unbindCommand(arg string) {
+	if arg == "" {
+		c.params = nil
+		fmt.Fprintf(c.term, "All parameter values cleared.\n")
+		return
+	}
+	key := strings.ToLower(strings.TrimPrefix(arg, "@"))
+	if _, ok := c.params[key]; !ok {
+		fmt.Fprintf(c.term, "Parameter @%s is not bound.\n", strings.TrimPrefix(arg, "@"))
+		return
+	}
+	delete(c.params, key)
+}
@@ -0,0 +1,523 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// planNode showplan XML 的一个元素，按通用的树结构解析
+type planNode struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Children []planNode `xml:",any"`
+}
+
+// attr 返回属性值，没有该属性时返回空字符串
+func (n *planNode) attr(name string) string {
+	for _, a := range n.Attrs {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// floatAttr 返回数值属性，没有或无法解析时返回 0
+func (n *planNode) floatAttr(name string) float64 {
+	f, _ := strconv.ParseFloat(n.attr(name), 64)
+	return f
+}
+
+// child 返回第一个指定名称的直接子元素
+func (n *planNode) child(name string) *planNode {
+	for i := range n.Children {
+		if n.Children[i].XMLName.Local == name {
+			return &n.Children[i]
+		}
+	}
+	return nil
+}
+
+// find 返回所有指定名称的后代元素，不进入名为 stop 的元素（不包括 n 本身），找到的元素也不再向下查找
+func (n *planNode) find(name, stop string) []*planNode {
+	var found []*planNode
+	for i := range n.Children {
+		ch := &n.Children[i]
+		switch ch.XMLName.Local {
+		case name:
+			found = append(found, ch)
+		case stop:
+		default:
+			found = append(found, ch.find(name, stop)...)
+		}
+	}
+	return found
+}
+
+// showplanXML 在会话连接上打开 SHOWPLAN_XML 编译语句，返回各批处理的估计执行计划，语句不会执行。
+// 无论编译是否出错，返回前都会关闭 SHOWPLAN_XML，否则会话中之后的语句都只返回计划而不执行
+func (c *CLI) showplanXML(sqlStr string) ([]string, error) {
+	ctx, cancel := c.statementContext()
+	defer cancel()
+
+	if _, err := c.conn.ExecContext(ctx, "SET SHOWPLAN_XML ON"); err != nil {
+		return nil, err
+	}
+	defer c.setOptionOff("SHOWPLAN_XML")
+
+	rows, err := c.conn.QueryContext(ctx, sqlStr)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var docs []string
+	for {
+		for rows.Next() {
+			var doc sql.NullString
+			if err := rows.Scan(&doc); err != nil {
+				return nil, err
+			}
+			if doc.Valid {
+				docs = append(docs, doc.String)
+			}
+		}
+		if !rows.NextResultSet() {
+			break
+		}
+	}
+	return docs, rows.Err()
+}
+
+// setOptionOff 关闭 SHOWPLAN_XML 或 STATISTICS XML，使用独立的 context 以免语句超时或中断后无法关闭
+func (c *CLI) setOptionOff(option string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := c.conn.ExecContext(ctx, "SET "+option+" OFF"); err != nil {
+		fmt.Fprintf(c.term, "Warning: could not turn %s off (%v); run 'reconnect' to reset the session\n", option, err)
+	}
+}
+
+// planSinkKey 在 context 中保存接收实际执行计划 XML 的函数，设置后 executeQuery 不显示计划结果集
+type planSinkKey struct{}
+
+// showplanColumn SET STATISTICS XML ON 时返回计划的结果集的列名
+const showplanColumn = "Microsoft SQL Server 2005 XML Showplan"
+
+// isShowplanResult 判断结果集是否为 STATISTICS XML 返回的执行计划
+func isShowplanResult(cols []string) bool {
+	return len(cols) == 1 && cols[0] == showplanColumn
+}
+
+// errExplainCancelled 用户没有确认执行修改数据的语句
+var errExplainCancelled = errors.New("explain analyze cancelled")
+
+// statisticsXML 打开 STATISTICS XML 执行语句，正常显示结果并返回各语句的实际执行计划。语句会真正执行，
+// 先给出提示，可能修改数据的语句需要确认；无论执行是否出错，返回前都会关闭 STATISTICS XML
+func (c *CLI) statisticsXML(sqlStr string) ([]string, error) {
+	fmt.Fprintf(c.term, "%s\n", c.display.colorize("Note: explain analyze executes the statement.", ansiDim))
+	if !isReadOnlyStatement(sqlStr) && !c.confirm("The statement may modify data. Execute it?") {
+		return nil, errExplainCancelled
+	}
+	params, ok := c.bindParams(sqlStr)
+	if !ok {
+		return nil, errExplainCancelled
+	}
+
+	startTime := time.Now()
+	ctx, cancel := c.statementContext()
+	defer cancel()
+	defer c.flushOutput()
+
+	if _, err := c.conn.ExecContext(ctx, "SET STATISTICS XML ON"); err != nil {
+		return nil, err
+	}
+	defer c.setOptionOff("STATISTICS XML")
+
+	var docs []string
+	ctx = context.WithValue(ctx, planSinkKey{}, func(doc string) { docs = append(docs, doc) })
+	// executeQuery 已经显示了错误，这里只返回收集到的计划
+	c.executeQuery(ctx, sqlStr, startTime, c.expanded, params...)
+	return docs, nil
+}
+
+// explainCommand 处理 explain 命令：explain <statement> 显示估计执行计划的操作符树，explain xml <statement>
+// 输出计划的完整 XML，explain file <path> <statement> 将计划保存为 .sqlplan 文件，可以用 SSMS 等工具打开；
+// explain analyze 执行语句并使用实际执行计划，xml 和 file 同样适用
+func (c *CLI) explainCommand(arg string) {
+	analyze := false
+	if word, rest := splitCommand(arg); word == "analyze" {
+		analyze, arg = true, rest
+	}
+	mode, rest := splitCommand(arg)
+	path := ""
+	switch mode {
+	case "xml":
+		arg = rest
+	case "file":
+		i := strings.IndexAny(rest, " \t\r\n")
+		if i < 0 {
+			fmt.Fprintf(c.term, "Usage: explain [analyze] file <path> <statement>\n")
+			return
+		}
+		path, arg = rest[:i], rest[i+1:]
+	default:
+		mode = ""
+	}
+	sqlStr := strings.TrimSpace(arg)
+	if sqlStr == "" {
+		fmt.Fprintf(c.term, "Usage: explain [analyze] [xml | file <path>] <statement>\n")
+		return
+	}
+	sqlStr, err := c.expandVariables(sqlStr)
+	if err != nil {
+		c.printError(err)
+		return
+	}
+
+	var docs []string
+	if analyze {
+		docs, err = c.statisticsXML(sqlStr)
+	} else {
+		docs, err = c.showplanXML(sqlStr)
+	}
+	if err != nil {
+		if !errors.Is(err, errExplainCancelled) {
+			c.printError(err)
+		}
+		return
+	}
+	if len(docs) == 0 {
+		fmt.Fprintf(c.term, "No plan was returned.\n")
+		return
+	}
+
+	switch mode {
+	case "file":
+		c.savePlans(path, docs)
+	case "xml":
+		endPaging := c.beginPaging()
+		defer endPaging()
+		c.outMu.Lock()
+		defer c.outMu.Unlock()
+		for _, doc := range docs {
+			fmt.Fprintf(c.out, "%s\n\n", doc)
+		}
+	default:
+		c.printPlans(docs)
+	}
+}
+
+// savePlans 将计划保存为 .sqlplan 文件，路径没有扩展名时加上 .sqlplan；多个批处理的计划依次保存为 name-2.sqlplan 等
+func (c *CLI) savePlans(path string, docs []string) {
+	path = c.resolvePath(path)
+	if filepath.Ext(path) == "" {
+		path += ".sqlplan"
+	}
+	ext := filepath.Ext(path)
+	for i, doc := range docs {
+		name := path
+		if i > 0 {
+			name = fmt.Sprintf("%s-%d%s", strings.TrimSuffix(path, ext), i+1, ext)
+		}
+		if err := os.WriteFile(name, []byte(doc), 0644); err != nil {
+			c.printError(err)
+			return
+		}
+		fmt.Fprintf(c.term, "Plan saved to %s\n", name)
+	}
+}
+
+// printPlans 将计划显示为缩进的操作符树，每个操作符一行，显示估计行数、自身开销占语句总开销的百分比和警告；
+// 语句级的警告（如影响计划的隐式转换）和缺失索引建议显示在树之后
+func (c *CLI) printPlans(docs []string) {
+	endPaging := c.beginPaging()
+	defer endPaging()
+	c.outMu.Lock()
+	defer c.outMu.Unlock()
+
+	n := 0
+	for _, doc := range docs {
+		var root planNode
+		if err := xml.Unmarshal([]byte(doc), &root); err != nil {
+			fmt.Fprintf(c.out, "Could not parse plan: %v\n\n", err)
+			continue
+		}
+		for _, stmt := range root.find("StmtSimple", "") {
+			plan := stmt.child("QueryPlan")
+			if plan == nil {
+				continue
+			}
+			ops := plan.find("RelOp", "")
+			if len(ops) == 0 {
+				continue
+			}
+			n++
+			total := stmt.floatAttr("StatementSubTreeCost")
+			if total == 0 {
+				total = ops[0].floatAttr("EstimatedTotalSubtreeCost")
+			}
+			header := fmt.Sprintf("Statement %d: %s", n, shortStatement(stmt.attr("StatementText")))
+			fmt.Fprintf(c.out, "%s\n", c.display.colorize(header, ansiBold))
+			fmt.Fprintf(c.out, "%s\n", c.display.colorize(fmt.Sprintf("Estimated cost %.4f, %s rows", total, planRows(stmt.floatAttr("StatementEstRows"))), ansiDim))
+			actual := len(plan.find("RunTimeInformation", "")) > 0
+			if actual {
+				for _, line := range runtimeSummary(plan) {
+					fmt.Fprintf(c.out, "%s\n", c.display.colorize(line, ansiDim))
+				}
+			}
+			for _, op := range ops {
+				c.printPlanOp(op, total, 0, actual)
+			}
+			if actual {
+				c.printDiscrepancies(plan)
+			}
+			if warnings := plan.child("Warnings"); warnings != nil {
+				for _, w := range planWarnings(warnings) {
+					fmt.Fprintf(c.out, "%s\n", c.display.colorize("Warning: "+w, ansiRed))
+				}
+			}
+			for _, mi := range missingIndexes(plan) {
+				fmt.Fprintf(c.out, "%s\n", c.display.colorize(mi, ansiDim))
+			}
+			fmt.Fprintf(c.out, "\n")
+		}
+	}
+	if n == 0 {
+		fmt.Fprintf(c.out, "The statement has no query plan.\n\n")
+	}
+}
+
+// printPlanOp 输出一个操作符及其子操作符，actual 为 true 时显示实际行数和估计行数
+func (c *CLI) printPlanOp(op *planNode, total float64, depth int, actual bool) {
+	children := op.find("RelOp", "RelOp")
+	own := op.floatAttr("EstimatedTotalSubtreeCost")
+	for _, ch := range children {
+		own -= ch.floatAttr("EstimatedTotalSubtreeCost")
+	}
+	pct := 0.0
+	if total > 0 && own > 0 {
+		pct = own * 100 / total
+	}
+
+	line := op.attr("PhysicalOp")
+	if logical := op.attr("LogicalOp"); logical != "" && logical != line {
+		line += " (" + logical + ")"
+	}
+	if objects := op.find("Object", "RelOp"); len(objects) > 0 {
+		line += " " + planObject(objects[0])
+	}
+	if actual {
+		rows, executed := actualRows(op)
+		estimated := estimatedRows(op)
+		if executed {
+			line += fmt.Sprintf("  rows %s (est %s)", groupDigits(strconv.FormatInt(rows, 10)), planRows(estimated))
+		} else {
+			line += "  not executed"
+		}
+		line += fmt.Sprintf("  cost %.0f%%", pct)
+		if executed && rowsRatio(float64(rows), estimated) >= discrepancyThreshold {
+			line = c.display.colorize(line, ansiRed)
+		}
+	} else {
+		line += fmt.Sprintf("  rows %s  cost %.0f%%", planRows(op.floatAttr("EstimateRows")), pct)
+		if pct >= 50 {
+			line = c.display.colorize(line, ansiBold)
+		}
+	}
+	fmt.Fprintf(c.out, "%s%s\n", strings.Repeat("  ", depth), line)
+	if warnings := op.child("Warnings"); warnings != nil {
+		for _, w := range planWarnings(warnings) {
+			fmt.Fprintf(c.out, "%s%s\n", strings.Repeat("  ", depth+1), c.display.colorize("! "+w, ansiRed))
+		}
+	}
+	for _, ch := range children {
+		c.printPlanOp(ch, total, depth+1, actual)
+	}
+}
+
+// discrepancyThreshold 实际行数与估计行数相差的倍数达到该值时突出显示
+const discrepancyThreshold = 10
+
+// actualRows 返回操作符在所有线程上实际返回的行数之和，操作符没有执行时 executed 为 false
+func actualRows(op *planNode) (rows int64, executed bool) {
+	rt := op.child("RunTimeInformation")
+	if rt == nil {
+		return 0, false
+	}
+	var executions int64
+	for _, counters := range rt.find("RunTimeCountersPerThread", "") {
+		n, _ := strconv.ParseInt(counters.attr("ActualRows"), 10, 64)
+		e, _ := strconv.ParseInt(counters.attr("ActualExecutions"), 10, 64)
+		rows += n
+		executions += e
+	}
+	return rows, executions > 0
+}
+
+// estimatedRows 返回操作符所有执行的估计行数之和：EstimateRows 是每次执行的估计值，乘以执行次数
+func estimatedRows(op *planNode) float64 {
+	return op.floatAttr("EstimateRows") * (1 + op.floatAttr("EstimateRebinds") + op.floatAttr("EstimateRewinds"))
+}
+
+// rowsRatio 返回实际行数与估计行数中较大者与较小者之比，不足 1 行按 1 行计算
+func rowsRatio(actual, estimated float64) float64 {
+	return max(actual, estimated, 1) / max(min(actual, estimated), 1)
+}
+
+// runtimeSummary 返回实际执行计划的并行度、内存授予和耗时
+func runtimeSummary(plan *planNode) []string {
+	var lines []string
+	if dop := plan.attr("DegreeOfParallelism"); dop != "" {
+		lines = append(lines, "Degree of parallelism "+dop)
+	}
+	if grant := plan.child("MemoryGrantInfo"); grant != nil && grant.attr("GrantedMemory") != "" {
+		lines = append(lines, fmt.Sprintf("Memory grant: requested %s, granted %s, max used %s",
+			formatSize(int64(grant.floatAttr("RequestedMemory"))), formatSize(int64(grant.floatAttr("GrantedMemory"))),
+			formatSize(int64(grant.floatAttr("MaxUsedMemory")))))
+	}
+	if stats := plan.child("QueryTimeStats"); stats != nil {
+		lines = append(lines, fmt.Sprintf("CPU time %s ms, elapsed time %s ms", stats.attr("CpuTime"), stats.attr("ElapsedTime")))
+	}
+	return lines
+}
+
+// allRelOps 返回计划中的所有操作符
+func allRelOps(n *planNode) []*planNode {
+	var ops []*planNode
+	for i := range n.Children {
+		ch := &n.Children[i]
+		if ch.XMLName.Local == "RelOp" {
+			ops = append(ops, ch)
+		}
+		ops = append(ops, allRelOps(ch)...)
+	}
+	return ops
+}
+
+// printDiscrepancies 列出实际行数与估计行数相差最大的几个操作符，相差不到 discrepancyThreshold 倍的不列出
+func (c *CLI) printDiscrepancies(plan *planNode) {
+	type discrepancy struct {
+		op    *planNode
+		rows  int64
+		ratio float64
+	}
+	var list []discrepancy
+	for _, op := range allRelOps(plan) {
+		rows, executed := actualRows(op)
+		if !executed {
+			continue
+		}
+		if ratio := rowsRatio(float64(rows), estimatedRows(op)); ratio >= discrepancyThreshold {
+			list = append(list, discrepancy{op, rows, ratio})
+		}
+	}
+	if len(list) == 0 {
+		return
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].ratio > list[j].ratio })
+	if len(list) > 3 {
+		list = list[:3]
+	}
+	fmt.Fprintf(c.out, "Largest estimate errors:\n")
+	for _, d := range list {
+		name := d.op.attr("PhysicalOp")
+		if objects := d.op.find("Object", "RelOp"); len(objects) > 0 {
+			name += " " + planObject(objects[0])
+		}
+		fmt.Fprintf(c.out, "  %s (node %s): estimated %s, actual %s, off by %.0fx\n", name, d.op.attr("NodeId"),
+			planRows(estimatedRows(d.op)), groupDigits(strconv.FormatInt(d.rows, 10)), d.ratio)
+	}
+}
+
+// planRows 格式化估计行数，小于 1 时保留两位小数
+func planRows(rows float64) string {
+	if rows < 1 {
+		return strconv.FormatFloat(rows, 'f', 2, 64)
+	}
+	return groupDigits(strconv.FormatFloat(rows, 'f', 0, 64))
+}
+
+// planObject 返回操作符访问的对象名，如 [dbo].[orders].[PK_orders]
+func planObject(obj *planNode) string {
+	var parts []string
+	for _, name := range []string{"Schema", "Table", "Index"} {
+		if v := obj.attr(name); v != "" {
+			parts = append(parts, v)
+		}
+	}
+	name := strings.Join(parts, ".")
+	if alias := obj.attr("Alias"); alias != "" {
+		name += " AS " + alias
+	}
+	return name
+}
+
+// planWarnings 将 Warnings 元素转换为可读的警告文本
+func planWarnings(w *planNode) []string {
+	var result []string
+	for _, a := range w.Attrs {
+		if a.Value == "true" || a.Value == "1" {
+			result = append(result, splitCamel(a.Name.Local))
+		}
+	}
+	for i := range w.Children {
+		ch := &w.Children[i]
+		switch ch.XMLName.Local {
+		case "PlanAffectingConvert":
+			result = append(result, fmt.Sprintf("implicit conversion %s may affect %s", ch.attr("Expression"), strings.ToLower(ch.attr("ConvertIssue"))))
+		case "ColumnsWithNoStatistics":
+			var cols []string
+			for _, ref := range ch.find("ColumnReference", "") {
+				cols = append(cols, ref.attr("Column"))
+			}
+			result = append(result, "no statistics on "+strings.Join(cols, ", "))
+		case "SpillToTempDb":
+			result = append(result, "spill to tempdb, level "+ch.attr("SpillLevel"))
+		default:
+			result = append(result, splitCamel(ch.XMLName.Local))
+		}
+	}
+	return result
+}
+
+// missingIndexes 返回计划中的缺失索引建议，每条一行
+func missingIndexes(plan *planNode) []string {
+	var result []string
+	for _, group := range plan.find("MissingIndexGroup", "") {
+		for _, mi := range group.find("MissingIndex", "") {
+			line := fmt.Sprintf("Missing index (impact %.0f%%): %s.%s.%s", group.floatAttr("Impact"),
+				mi.attr("Database"), mi.attr("Schema"), mi.attr("Table"))
+			for _, cg := range mi.find("ColumnGroup", "") {
+				var cols []string
+				for _, col := range cg.find("Column", "") {
+					cols = append(cols, col.attr("Name"))
+				}
+				line += fmt.Sprintf(" %s %s", strings.ToLower(cg.attr("Usage")), strings.Join(cols, ", "))
+			}
+			result = append(result, line)
+		}
+	}
+	return result
+}
+
+// splitCamel 将 NoJoinPredicate 这样的名称转换为 no join predicate
+func splitCamel(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) && i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
@@ -0,0 +1,239 @@
+package mssql
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// SQL Server 为 FOR XML / FOR JSON 结果生成的列名
+const (
+	forXMLColumn  = "XML_F52E2B61-18A1-11d1-B105-00805F49916B"
+	forJSONColumn = "JSON_F52E2B61-18A1-11d1-B105-00805F49916B"
+)
+
+var (
+	// forXMLPattern 匹配以 FOR XML 子句结尾的语句
+	forXMLPattern = regexp.MustCompile(`(?is)\bFOR\s+XML\b[^()]*$`)
+	// forJSONPattern 匹配以 FOR JSON 子句结尾的语句
+	forJSONPattern = regexp.MustCompile(`(?is)\bFOR\s+JSON\b[^()]*$`)
+)
+
+// isXMLResult 判断结果集是否为需要合并显示的 XML 文档
+func isXMLResult(sqlStr string, cols []string, colTypes []*sql.ColumnType) bool {
+	if len(cols) != 1 {
+		return false
+	}
+	if cols[0] == forXMLColumn || forXMLPattern.MatchString(sqlStr) {
+		return true
+	}
+	return len(colTypes) == 1 && colTypes[0].DatabaseTypeName() == "XML"
+}
+
+// isJSONResult 判断结果集是否为被分块返回的 FOR JSON 文档
+func isJSONResult(sqlStr string, cols []string) bool {
+	if len(cols) != 1 {
+		return false
+	}
+	return cols[0] == forJSONColumn || forJSONPattern.MatchString(sqlStr)
+}
+
+// xmlDocumentFormatter 合并 FOR XML 结果片段并以缩进格式流式输出
+type xmlDocumentFormatter struct {
+	w    io.Writer
+	pw   *io.PipeWriter
+	done chan struct{}
+}
+
+func (f *xmlDocumentFormatter) document() {}
+
+func (f *xmlDocumentFormatter) BeginResult(cols []string, colTypes []*sql.ColumnType) error {
+	pr, pw := io.Pipe()
+	f.pw = pw
+	f.done = make(chan struct{})
+
+	// 解码在独立的 goroutine 中进行，片段到达即输出，无需缓存整个文档
+	go func() {
+		defer close(f.done)
+		dec := xml.NewDecoder(pr)
+		dec.Strict = false
+
+		p := &xmlPrinter{w: f.w}
+		for {
+			tok, err := dec.RawToken()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				p.flush()
+				fmt.Fprintf(f.w, "\nWarning: XML could not be formatted (%v), remaining output is raw\n", err)
+				io.Copy(f.w, pr)
+				break
+			}
+			p.write(tok)
+		}
+		p.flush()
+		pr.Close()
+	}()
+	return nil
+}
+
+func (f *xmlDocumentFormatter) WriteRow(values []interface{}) error {
+	if values[0] == nil {
+		return nil
+	}
+	_, err := io.WriteString(f.pw, formatValue(values[0]))
+	return err
+}
+
+func (f *xmlDocumentFormatter) EndResult(summary ResultSummary) error {
+	if f.pw != nil {
+		f.pw.Close()
+		<-f.done
+		f.pw = nil
+	}
+	fmt.Fprintf(f.w, "\n")
+	if summary.Timing {
+		fmt.Fprintf(f.w, "Time: %.3f sec\n", summary.Elapsed.Seconds())
+	}
+	fmt.Fprintf(f.w, "\n")
+	return nil
+}
+
+// jsonDocumentFormatter 合并 FOR JSON 结果分块并缩进输出，无法解析时按原文输出
+type jsonDocumentFormatter struct {
+	w    io.Writer
+	data bytes.Buffer
+}
+
+func (f *jsonDocumentFormatter) document() {}
+
+func (f *jsonDocumentFormatter) BeginResult(cols []string, colTypes []*sql.ColumnType) error {
+	f.data.Reset()
+	return nil
+}
+
+func (f *jsonDocumentFormatter) WriteRow(values []interface{}) error {
+	if values[0] != nil {
+		f.data.WriteString(formatValue(values[0]))
+	}
+	return nil
+}
+
+func (f *jsonDocumentFormatter) EndResult(summary ResultSummary) error {
+	data := f.data.Bytes()
+	var out bytes.Buffer
+	if err := json.Indent(&out, data, "", "  "); err != nil && len(bytes.TrimSpace(data)) > 0 {
+		fmt.Fprintf(f.w, "Warning: result is not valid JSON (%v), showing raw text\n", err)
+		out.Reset()
+		out.Write(data)
+	}
+	out.WriteString("\n")
+	f.w.Write(out.Bytes())
+	f.data.Reset()
+
+	if summary.Timing {
+		fmt.Fprintf(f.w, "Time: %.3f sec\n", summary.Elapsed.Seconds())
+	}
+	fmt.Fprintf(f.w, "\n")
+	return nil
+}
+
+// xmlPrinter 按 token 输出带缩进的 XML，保留原始的命名空间前缀
+type xmlPrinter struct {
+	w       io.Writer
+	depth   int
+	pending *xml.StartElement // 尚未确定是否为空元素的开始标签
+	text    string            // pending 元素内的文本内容
+	started bool
+}
+
+func (p *xmlPrinter) line(s string) {
+	if p.started {
+		fmt.Fprintf(p.w, "\n")
+	}
+	p.started = true
+	fmt.Fprintf(p.w, "%s%s", strings.Repeat("  ", p.depth), s)
+}
+
+// flush 将挂起的开始标签作为普通开始标签输出
+func (p *xmlPrinter) flush() {
+	if p.pending == nil {
+		return
+	}
+	p.line(startTag(p.pending) + ">")
+	p.depth++
+	if p.text != "" {
+		p.line(p.text)
+	}
+	p.pending = nil
+	p.text = ""
+}
+
+func (p *xmlPrinter) write(tok xml.Token) {
+	switch t := tok.(type) {
+	case xml.StartElement:
+		p.flush()
+		start := t.Copy()
+		p.pending = &start
+	case xml.EndElement:
+		if p.pending != nil {
+			if p.text == "" {
+				p.line(startTag(p.pending) + "/>")
+			} else {
+				p.line(startTag(p.pending) + ">" + p.text + "</" + qualifiedName(t.Name) + ">")
+			}
+			p.pending = nil
+			p.text = ""
+			return
+		}
+		p.depth--
+		p.line("</" + qualifiedName(t.Name) + ">")
+	case xml.CharData:
+		text := strings.TrimSpace(string(t))
+		if text == "" {
+			return
+		}
+		var b strings.Builder
+		xml.EscapeText(&b, []byte(text))
+		if p.pending != nil {
+			p.text += b.String()
+			return
+		}
+		p.line(b.String())
+	case xml.Comment:
+		p.flush()
+		p.line("<!--" + string(t) + "-->")
+	case xml.ProcInst:
+		p.flush()
+		p.line("<?" + t.Target + " " + string(t.Inst) + "?>")
+	case xml.Directive:
+		p.flush()
+		p.line("<!" + string(t) + ">")
+	}
+}
+
+// startTag 生成不含结尾 '>' 的开始标签
+func startTag(start *xml.StartElement) string {
+	var b strings.Builder
+	b.WriteString("<" + qualifiedName(start.Name))
+	for _, attr := range start.Attr {
+		b.WriteString(" " + qualifiedName(attr.Name) + `="`)
+		xml.EscapeText(&b, []byte(attr.Value))
+		b.WriteString(`"`)
+	}
+	return b.String()
+}
+
+// qualifiedName 还原带前缀的名称
+func qualifiedName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
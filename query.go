@@ -0,0 +1,159 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ResultSet 查询返回的一个结果集
+type ResultSet struct {
+	Columns     []string
+	ColumnTypes []*sql.ColumnType
+	Rows        [][]interface{} // 驱动扫描出的原始值，保留 Go 类型，NULL 为 nil
+	Truncated   bool            // 行数超过 maxrows，只保留了前 maxrows 行
+}
+
+// QueryResult Query 的执行结果。批处理和存储过程可能返回多个结果集，
+// 嵌入的 ResultSet 为第一个结果集，没有结果集时为空
+type QueryResult struct {
+	ResultSet
+	ResultSets   []ResultSet   // 所有结果集
+	RowsAffected int64         // 服务器报告的影响行数之和
+	Duration     time.Duration // 执行耗时
+	Messages     []string      // PRINT、RAISERROR 等服务器消息
+}
+
+// Query 在会话连接上执行一条语句（或一个批处理）并返回结构化的结果，不向终端输出任何内容。
+// 每个结果集最多保留 maxrows 行，超出时设置 Truncated；语句超时与交互执行相同。
+// 语句出错时同时返回已读取的部分结果和错误
+func (c *CLI) Query(ctx context.Context, stmt string) (*QueryResult, error) {
+	if c.conn == nil {
+		return nil, errors.New("not connected")
+	}
+	// 不显示等待状态
+	defer func(saved bool) { c.nonInteractive = saved }(c.nonInteractive)
+	c.nonInteractive = true
+
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+	result := &QueryResult{}
+	// 消息在驱动的 goroutine 中到达
+	var mu sync.Mutex
+	ctx = context.WithValue(ctx, messageSinkKey{}, func(msg string) {
+		mu.Lock()
+		defer mu.Unlock()
+		result.Messages = append(result.Messages, msg)
+	})
+
+	var scanErr error
+	startTime := time.Now()
+	affected, _, _, err := c.runQuery(ctx, stmt, startTime, func(rows *sql.Rows, cols []string, colTypes []*sql.ColumnType) bool {
+		set := ResultSet{Columns: cols, ColumnTypes: colTypes, Rows: [][]interface{}{}}
+		_, truncated, err := scanRows(rows, len(cols), c.maxRows, func(vals []interface{}) bool {
+			set.Rows = append(set.Rows, vals)
+			return true
+		})
+		// 跳过超出的行，继续读取之后的结果集
+		for truncated && rows.Next() {
+		}
+		set.Truncated = truncated
+		result.ResultSets = append(result.ResultSets, set)
+		scanErr = err
+		return err != nil
+	})
+	if err == nil {
+		err = scanErr
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	result.RowsAffected = affected
+	result.Duration = time.Since(startTime)
+	if len(result.ResultSets) > 0 {
+		result.ResultSet = result.ResultSets[0]
+	}
+	if err != nil {
+		return result, err
+	}
+	c.trackSession(stmt)
+	return result, nil
+}
+
+// resultHandler 读取 runQuery 交给它的一个结果集，返回 true 表示应取消查询
+type resultHandler func(rows *sql.Rows, cols []string, colTypes []*sql.ColumnType) bool
+
+// runQuery 执行语句并将每个有列的结果集依次交给 handle，返回服务器报告的影响行数、是否有结果集，
+// 以及 handle 是否取消了查询。explain analyze 收集的实际执行计划交给 context 中的接收函数，不作为结果集。
+// 交互执行和 Query 都通过它读取结果
+func (c *CLI) runQuery(ctx context.Context, sqlStr string, startTime time.Time, handle resultHandler, args ...interface{}) (affected int64, hasResults, cancelled bool, err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// 影响的行数由驱动在处理 DONE 令牌时报告，处理在驱动的 goroutine 中进行
+	var count atomic.Int64
+	ctx = context.WithValue(ctx, rowsAffectedKey{}, func(n int64) { count.Add(n) })
+
+	// 等待服务器返回第一个结果时显示已执行的时间
+	stopSpinner := c.startSpinner(startTime)
+	rows, err := c.conn.QueryContext(ctx, sqlStr, args...)
+	stopSpinner()
+	if err != nil {
+		return 0, false, false, err
+	}
+	defer rows.Close()
+
+	for {
+		cols, _ := rows.Columns()
+		colTypes, _ := rows.ColumnTypes()
+
+		// 没有列的结果集不包含可显示的数据
+		if sink, ok := ctx.Value(planSinkKey{}).(func(string)); ok && isShowplanResult(cols) {
+			for rows.Next() {
+				var doc sql.NullString
+				if err := rows.Scan(&doc); err == nil && doc.Valid {
+					sink(doc.String)
+				}
+			}
+		} else if len(cols) > 0 {
+			hasResults = true
+			if handle(rows, cols, colTypes) {
+				cancel()
+				return count.Load(), true, true, nil
+			}
+		}
+
+		stopSpinner = c.startSpinner(time.Now())
+		next := rows.NextResultSet()
+		stopSpinner()
+		if !next {
+			break
+		}
+	}
+	return count.Load(), hasResults, false, rows.Err()
+}
+
+// scanRows 逐行扫描结果集交给 fn，fn 返回 false 时停止。limit 大于 0 时最多读取 limit 行，
+// 之后还有行时 truncated 为 true，此时为判断是否截断已多读取了一行
+func scanRows(rows *sql.Rows, n, limit int, fn func(vals []interface{}) bool) (count int64, truncated bool, err error) {
+	for rows.Next() {
+		if limit > 0 && count >= int64(limit) {
+			return count, true, nil
+		}
+		vals, err := scanRow(rows, n)
+		if err != nil {
+			return count, false, err
+		}
+		count++
+		if !fn(vals) {
+			break
+		}
+	}
+	return count, false, rows.Err()
+}
@@ -0,0 +1,149 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDMLOutputVerb(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{sql: "INSERT INTO t (name) OUTPUT inserted.id VALUES ('a')", want: "INSERT"},
+		{sql: "update t set n = n + 1\noutput deleted.n, inserted.n\nwhere id = 1", want: "UPDATE"},
+		{sql: "DELETE FROM t OUTPUT deleted.* WHERE id < 10", want: "DELETE"},
+		{sql: "MERGE t USING s ON t.id = s.id WHEN MATCHED THEN DELETE OUTPUT $action, deleted.id;", want: "MERGE"},
+		{sql: "WITH old AS (SELECT TOP 5 * FROM t ORDER BY id)\nDELETE FROM old OUTPUT deleted.id", want: "DELETE"},
+		{sql: "INSERT INTO t (name) VALUES ('a')", want: ""},
+		{sql: "INSERT INTO t (note) VALUES ('OUTPUT')", want: ""},
+		{sql: "UPDATE t SET n = 1 -- OUTPUT inserted.n", want: ""},
+		{sql: "DELETE FROM t /* OUTPUT deleted.id */ WHERE id = 1", want: ""},
+		{sql: "SELECT * FROM t", want: ""},
+		{sql: "EXEC dbo.next_id @id OUTPUT", want: ""},
+	}
+	for _, tt := range tests {
+		if got := dmlOutputVerb(tt.sql); got != tt.want {
+			t.Errorf("dmlOutputVerb(%q) = %q, want %q", tt.sql, got, tt.want)
+		}
+	}
+}
+
+func TestExecPattern(t *testing.T) {
+	for sql, want := range map[string]bool{
+		"EXEC dbo.report":               true,
+		"  execute sp_who2":             true,
+		"exec('SELECT 1')":              true,
+		"EXECUTIVE_SUMMARY":             false,
+		"SELECT 1; EXEC dbo.report":     false,
+		"INSERT INTO t EXEC dbo.report": false,
+		"-- EXEC dbo.report\nSELECT 1":  false,
+	} {
+		if got := execPattern.MatchString(sql); got != want {
+			t.Errorf("execPattern.MatchString(%q) = %v, want %v", sql, got, want)
+		}
+	}
+}
+
+func TestOutputRowsAreRendered(t *testing.T) {
+	term := &testTerm{}
+	c := NewCLIWithConfig(term, &Config{Host: "db1", Username: "sa", Password: "x"})
+	fc := connectFake(t, c, []fakeColumn{{name: "id", typeName: "INT"}}, []driver.Value{int64(41)}, []driver.Value{int64(42)})
+	fc.affected = 2
+
+	if err := c.executeQuery(context.Background(), "INSERT INTO t (name) OUTPUT inserted.id VALUES ('a'), ('b')", time.Now(), false); err != nil {
+		t.Fatalf("executeQuery: %v", err)
+	}
+	out := term.String()
+	for _, want := range []string{"|   id |", "|   41 |", "|   42 |", "INSERT affected 2 rows"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestScanRowsReportsReadError(t *testing.T) {
+	readErr := errors.New("connection reset")
+	db := sql.OpenDB(&fakeConnector{
+		cols:    []fakeColumn{{name: "id", typeName: "INT"}},
+		rows:    [][]driver.Value{{int64(1)}, {int64(2)}},
+		rowsErr: readErr,
+	})
+	defer db.Close()
+	rows, err := db.Query("SELECT")
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	defer rows.Close()
+
+	count, truncated, err := scanRows(rows, 1, 0, func([]interface{}) bool { return true })
+	if !errors.Is(err, readErr) {
+		t.Errorf("err = %v, want %v", err, readErr)
+	}
+	if count != 2 || truncated {
+		t.Errorf("count, truncated = %d, %v, want 2, false", count, truncated)
+	}
+}
+
+func TestStatementWithoutResultShowsRowCount(t *testing.T) {
+	term := &testTerm{}
+	c := NewCLIWithConfig(term, &Config{Host: "db1", Username: "sa", Password: "x"})
+	fc := connectFake(t, c, nil)
+	fc.affected = 3
+
+	if err := c.executeQuery(context.Background(), "UPDATE t SET n = 1", time.Now(), false); err != nil {
+		t.Fatalf("executeQuery: %v", err)
+	}
+	if out := term.String(); out != "(3 rows affected)\n\n" {
+		t.Errorf("output = %q, want %q", out, "(3 rows affected)\n\n")
+	}
+}
+
+func TestExecResultAndReturnStatus(t *testing.T) {
+	term := &testTerm{}
+	c := NewCLIWithConfig(term, &Config{Host: "db1", Username: "sa", Password: "x"})
+	connectFake(t, c, []fakeColumn{{name: "name", typeName: "NVARCHAR"}}, []driver.Value{"report"})
+
+	if err := c.runStatement("EXEC dbo.report", time.Now(), false); err != nil {
+		t.Fatalf("runStatement: %v", err)
+	}
+	out := term.String()
+	for _, want := range []string{"| report |", "Return status = 0"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestOutputAndExecRoutingOnServer(t *testing.T) {
+	c, term := connectTestServer(t, nil)
+	run := func(sql string) string {
+		t.Helper()
+		term.Reset()
+		if err := c.runStatement(sql, time.Now(), false); err != nil {
+			t.Fatalf("%s: %v\n%s", sql, err, term.String())
+		}
+		return term.String()
+	}
+
+	run("CREATE TABLE #routing (id int IDENTITY(41, 1), name nvarchar(20))")
+	out := run("INSERT INTO #routing (name) OUTPUT inserted.id VALUES (N'a'), (N'b')")
+	for _, want := range []string{"|   41 |", "|   42 |", "INSERT affected 2 rows"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("INSERT ... OUTPUT output does not contain %q:\n%s", want, out)
+		}
+	}
+
+	run("CREATE PROCEDURE #routing_report AS BEGIN SELECT name FROM #routing ORDER BY id; RETURN 3 END")
+	out = run("EXEC #routing_report")
+	for _, want := range []string{"| a    |", "| b    |", "Return status = 3"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("EXEC output does not contain %q:\n%s", want, out)
+		}
+	}
+}
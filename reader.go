@@ -50,3 +50,14 @@ func (r *Reader) SetPrompt(prompt string) {
 func (r *Reader) Close() error {
 	return r.rl.Close()
 }
+
+// ReadPassword 读取一行不回显的输入
+func (r *Reader) ReadPassword(prompt string) (string, error) {
+	pw, err := r.rl.ReadPassword(prompt)
+	return string(pw), err
+}
+
+// Refresh 在其他输出之后重新显示提示符和已输入的内容
+func (r *Reader) Refresh() {
+	r.rl.Refresh()
+}
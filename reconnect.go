@@ -0,0 +1,158 @@
+package mssql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"regexp"
+	"strings"
+	"time"
+
+	mssqldb "github.com/microsoft/go-mssqldb"
+)
+
+// isConnectionError 判断错误是否由连接断开引起，语句超时和取消不属于连接错误
+func isConnectionError(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, net.ErrClosed) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, s := range []string{"connection reset", "broken pipe", "i/o timeout", "use of closed network connection"} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// isRetryableConnectError 判断首次连接的错误是否值得重试
+// Azure SQL 数据库暂不可用（40613、40197 等）和网络错误可重试，登录失败（18456）等服务器错误立即失败
+func isRetryableConnectError(err error) bool {
+	var sqlErr mssqldb.Error
+	if errors.As(err, &sqlErr) {
+		switch sqlErr.Number {
+		case 40613, 40197, 40501, 49918, 49919, 49920:
+			return true
+		}
+		return false
+	}
+	return isConnectionError(err)
+}
+
+// connectWithRetry 建立首次连接，按配置重试并在终端显示进度，Ctrl+C 中止
+func (c *CLI) connectWithRetry() error {
+	attempts := max(c.config.ConnectRetries, 1)
+	delay := c.config.ConnectRetryBackoff
+	if delay <= 0 {
+		delay = 2 * time.Second
+	}
+
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if c.config.ConnectRetryDeadline > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), c.config.ConnectRetryDeadline)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+	defer cancel()
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+	defer signal.Stop(sigs)
+	interrupted := make(chan struct{})
+	go func() {
+		select {
+		case <-sigs:
+			close(interrupted)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	for attempt := 1; ; attempt++ {
+		err := c.openDB(ctx)
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-interrupted:
+			return fmt.Errorf("connect aborted")
+		default:
+		}
+		if attempt >= attempts || !isRetryableConnectError(err) || ctx.Err() != nil {
+			return err
+		}
+
+		fmt.Fprintf(c.term, "Waiting for server... attempt %d/%d (%v)\n", attempt+1, attempts, err)
+		select {
+		case <-time.After(delay):
+		case <-interrupted:
+			return fmt.Errorf("connect aborted")
+		case <-ctx.Done():
+			return err
+		}
+		delay = min(delay*2, 30*time.Second)
+	}
+}
+
+// modifyingPattern 匹配可能修改数据或会话状态的关键字
+var modifyingPattern = regexp.MustCompile(`(?i)\b(INSERT|UPDATE|DELETE|MERGE|INTO|CREATE|ALTER|DROP|TRUNCATE|EXEC|EXECUTE|GRANT|REVOKE|DENY|BEGIN|COMMIT|ROLLBACK|SET|USE|DBCC|BACKUP|RESTORE)\b`)
+
+// isReadOnlyStatement 判断语句是否为可以安全重试的只读查询
+func isReadOnlyStatement(sqlStr string) bool {
+	upper := strings.ToUpper(strings.TrimSpace(sqlStr))
+	if !strings.HasPrefix(upper, "SELECT") && !strings.HasPrefix(upper, "WITH") {
+		return false
+	}
+	return !modifyingPattern.MatchString(sqlStr)
+}
+
+// reconnectAttempts 返回重新连接的次数
+func (c *CLI) reconnectAttempts() int {
+	switch {
+	case c.config.ReconnectAttempts < 0:
+		return 0
+	case c.config.ReconnectAttempts == 0:
+		return 3
+	}
+	return c.config.ReconnectAttempts
+}
+
+// reconnect 重新建立连接并恢复会话状态，失败时按指数退避重试
+func (c *CLI) reconnect() error {
+	attempts := c.reconnectAttempts()
+	if attempts == 0 {
+		return fmt.Errorf("reconnect is disabled")
+	}
+	delay := c.config.ReconnectBackoff
+	if delay <= 0 {
+		delay = time.Second
+	}
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 {
+			time.Sleep(delay)
+			delay *= 2
+		}
+		if err = c.openDB(context.Background()); err == nil {
+			return c.restoreSession()
+		}
+		fmt.Fprintf(c.term, "Reconnect attempt %d/%d failed: %v\n", attempt, attempts, err)
+	}
+	return err
+}
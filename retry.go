@@ -0,0 +1,127 @@
+package mssql
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	mssqldb "github.com/microsoft/go-mssqldb"
+)
+
+// lockErrorNames 可以重试的锁错误：1205 死锁牺牲品，1222 锁请求超时
+var lockErrorNames = map[int32]string{
+	1205: "Deadlock",
+	1222: "Lock timeout",
+}
+
+// maxRetryDelay 重试前等待的最长时间
+const maxRetryDelay = 5 * time.Second
+
+// lockError 返回可以重试的锁错误的错误号，不是这类错误时返回 0
+func lockError(err error) int32 {
+	var sqlErr mssqldb.Error
+	if errors.As(err, &sqlErr) {
+		if _, ok := lockErrorNames[sqlErr.Number]; ok {
+			return sqlErr.Number
+		}
+	}
+	return 0
+}
+
+// retryBlockedPattern 匹配包含事务或流程控制语句的批处理，这类批处理重新执行可能只重放其中一部分
+var retryBlockedPattern = regexp.MustCompile(`(?i)\b(BEGIN|COMMIT|ROLLBACK|SAVE|DECLARE|IF|WHILE|GOTO|USE)\b`)
+
+// modifyingStatementPattern 匹配修改数据或执行存储过程的语句关键字
+var modifyingStatementPattern = regexp.MustCompile(`(?i)\b(INSERT|UPDATE|DELETE|MERGE|TRUNCATE|EXEC|EXECUTE)\b`)
+
+// isSingleStatement 判断批处理是否只包含一条语句，可以整体安全地重新执行：语句之间没有分号，
+// 不含事务和流程控制语句，最多一条修改数据的语句（MERGE 的 WHEN 子句中的 INSERT、UPDATE、DELETE 不计）
+func isSingleStatement(sqlStr string) bool {
+	var lexer sqlLexer
+	lines := strings.Split(sqlStr, "\n")
+	for i, line := range lines {
+		lines[i] = lexer.scan(line)
+	}
+	code := strings.TrimSuffix(strings.TrimSpace(strings.Join(lines, "\n")), ";")
+	if strings.Contains(code, ";") || retryBlockedPattern.MatchString(code) {
+		return false
+	}
+	modifying := modifyingStatementPattern.FindAllString(code, -1)
+	if len(modifying) > 0 && strings.EqualFold(modifying[0], "MERGE") {
+		return true
+	}
+	return len(modifying) <= 1
+}
+
+// retryDelay 返回第 attempt 次重试前的等待时间：从 100ms 起每次翻倍，并加入随机抖动，避免冲突的会话同时重试
+func retryDelay(attempt int) time.Duration {
+	base := 100 * time.Millisecond << (attempt - 1)
+	delay := base + time.Duration(rand.Int63n(int64(base)))
+	return min(delay, maxRetryDelay)
+}
+
+// retryLocked 在语句因死锁或锁超时失败后按 retry deadlock 的设置重新执行，返回最后一次执行的错误。
+// 只重试显式事务之外的单条语句，重放事务或批处理的一部分会产生错误的结果
+func (c *CLI) retryLocked(sqlStr string, vertical bool, params []interface{}, err error) error {
+	if c.deadlockRetries <= 0 || lockError(err) == 0 {
+		return err
+	}
+	// tranCount 是执行这条语句之前的事务层数
+	if c.tranCount > 0 || !isSingleStatement(sqlStr) {
+		return err
+	}
+	attempts := 1
+	for attempts <= c.deadlockRetries && lockError(err) != 0 {
+		delay := retryDelay(attempts)
+		msg := fmt.Sprintf("%s (Msg %d), retrying in %s (attempt %d of %d)", lockErrorNames[lockError(err)], lockError(err),
+			delay.Round(time.Millisecond), attempts+1, c.deadlockRetries+1)
+		fmt.Fprintf(c.term, "%s\n", c.display.colorize(msg, ansiDim))
+		time.Sleep(delay)
+		attempts++
+		err = c.runStatement(sqlStr, time.Now(), vertical, params...)
+	}
+	if n := lockError(err); n != 0 {
+		msg := fmt.Sprintf("%s (Msg %d): giving up after %d attempts", lockErrorNames[n], n, attempts)
+		fmt.Fprintf(c.out, "%s\n\n", c.display.colorize(msg, ansiRed))
+	}
+	return err
+}
+
+// setRetry 处理 retry 命令：retry deadlock <n> 在死锁或锁超时后最多重试 n 次，retry deadlock off 关闭（默认）
+func (c *CLI) setRetry(arg string) {
+	fields := strings.Fields(strings.ToLower(arg))
+	if len(fields) == 0 {
+		fmt.Fprintf(c.term, "Retry on deadlock: %s\n", retryText(c.deadlockRetries))
+		return
+	}
+	if fields[0] != "deadlock" || len(fields) != 2 {
+		fmt.Fprintf(c.term, "Usage: retry deadlock <n>|off\n")
+		return
+	}
+	n := 0
+	if fields[1] != "off" {
+		var err error
+		n, err = strconv.Atoi(fields[1])
+		if err != nil || n < 0 {
+			fmt.Fprintf(c.term, "Usage: retry deadlock <n>|off\n")
+			return
+		}
+	}
+	c.deadlockRetries = n
+	fmt.Fprintf(c.term, "Retry on deadlock: %s\n", retryText(n))
+}
+
+// retryText 返回重试设置的显示文本
+func retryText(n int) string {
+	if n <= 0 {
+		return "off"
+	}
+	if n == 1 {
+		return "up to 1 time"
+	}
+	return fmt.Sprintf("up to %d times", n)
+}
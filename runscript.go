@@ -0,0 +1,110 @@
+package mssql
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	mssqldb "github.com/microsoft/go-mssqldb"
+)
+
+// ScriptResult RunScript 的执行结果，可以据此决定进程的退出码
+type ScriptResult struct {
+	Errors       int   // 出错的批处理和命令数
+	MaxSeverity  int   // 出现过的最高错误级别（Level），没有错误时为 0；不是服务器返回的错误按 16 计
+	RowsAffected int64 // 所有语句影响的行数之和
+	Stopped      bool  // 是否因 on_error stop（或 Config.OnErrorExit）在第一个错误处停止
+	ReturnStatus int   // 最后一个非 0 的存储过程返回值（EXEC 的 Return status），都为 0 时为 0
+}
+
+// ExitCode 返回对应的进程退出码：有错误时为 1；否则为非 0 的存储过程返回值，超出 1-255 时按 1 计；
+// 都没有时为 0
+func (r ScriptResult) ExitCode() int {
+	switch {
+	case r.Errors > 0:
+		return 1
+	case r.ReturnStatus >= 1 && r.ReturnStatus <= 255:
+		return r.ReturnStatus
+	case r.ReturnStatus != 0:
+		return 1
+	}
+	return 0
+}
+
+// RunScript 以非交互方式执行脚本：读取 r 中以 GO 分隔的批处理并依次执行，结果通过当前的输出格式写出。
+// 执行期间不提示输入（未绑定的参数和需要确认的操作按出错或取消处理），不分页，也不显示等待状态。
+// 出错后是否继续由 on_error 决定，初始值为 Config.OnErrorExit；继续时最后汇总出错的批处理。SQL 错误记录在结果中；
+// 返回的 error 只表示脚本无法读取或解析、未连接，或 ctx 已取消
+func (c *CLI) RunScript(ctx context.Context, r io.Reader) (ScriptResult, error) {
+	var result ScriptResult
+	if c.conn == nil {
+		return result, errors.New("not connected")
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return result, err
+	}
+	text, err := decodeScript(data)
+	if err != nil {
+		return result, err
+	}
+	units, err := splitScript(text)
+	if err != nil {
+		return result, err
+	}
+
+	c.nonInteractive, c.runCtx, c.scriptResult = true, ctx, &result
+	defer func() {
+		c.nonInteractive, c.runCtx, c.scriptResult = false, nil, nil
+	}()
+	defer c.flushOutput()
+
+	c.beginScript()
+	err = c.runScriptUnits("script", units)
+	c.tranCount = c.openTransactions()
+	switch {
+	case errors.Is(err, errScriptStopped):
+		result.Stopped = true
+	case err != nil:
+		return result, err
+	default:
+		c.printScriptFailures()
+	}
+	return result, ctx.Err()
+}
+
+// recordScriptError 在 RunScript 执行期间记录一个出错的批处理或命令及其错误级别
+func (c *CLI) recordScriptError(err error) {
+	if c.scriptResult == nil {
+		return
+	}
+	c.scriptResult.Errors++
+	c.scriptResult.MaxSeverity = max(c.scriptResult.MaxSeverity, errorSeverity(err))
+}
+
+// recordReturnStatus 在 RunScript 执行期间记录最近一次 EXEC 非 0 的返回值
+func (c *CLI) recordReturnStatus() {
+	if c.scriptResult != nil && c.lastReturnStatus != 0 {
+		c.scriptResult.ReturnStatus = c.lastReturnStatus
+	}
+}
+
+// recordRowsAffected 在 RunScript 执行期间累计影响的行数
+func (c *CLI) recordRowsAffected(n int64) {
+	if c.scriptResult != nil {
+		c.scriptResult.RowsAffected += n
+	}
+}
+
+// errorSeverity 返回错误的级别：服务器错误取其中最高的 Class，其他错误按 16 计
+func errorSeverity(err error) int {
+	var sqlErr mssqldb.Error
+	if !errors.As(err, &sqlErr) {
+		return 16
+	}
+	severity := int(sqlErr.Class)
+	for _, e := range sqlErr.All {
+		severity = max(severity, int(e.Class))
+	}
+	return severity
+}
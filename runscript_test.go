@@ -0,0 +1,48 @@
+package mssql
+
+import "testing"
+
+func TestRecordReturnStatus(t *testing.T) {
+	c := NewCLIWithConfig(&testTerm{}, &Config{Host: "db1", Username: "sa", Password: "x"})
+	var result ScriptResult
+	c.scriptResult = &result
+
+	for _, status := range []int{0, 3, 0, -6, 0} {
+		c.lastReturnStatus = status
+		c.recordReturnStatus()
+	}
+	if result.ReturnStatus != -6 {
+		t.Errorf("ReturnStatus = %d, want the last non-zero status -6", result.ReturnStatus)
+	}
+
+	// 交互执行时不记录
+	c.scriptResult = nil
+	c.lastReturnStatus = 9
+	c.recordReturnStatus()
+	if result.ReturnStatus != -6 {
+		t.Errorf("ReturnStatus = %d after the script ended", result.ReturnStatus)
+	}
+}
+
+func TestScriptResultExitCode(t *testing.T) {
+	tests := []struct {
+		name   string
+		result ScriptResult
+		want   int
+	}{
+		{name: "clean", result: ScriptResult{RowsAffected: 10}, want: 0},
+		{name: "errors", result: ScriptResult{Errors: 2, MaxSeverity: 16}, want: 1},
+		{name: "errors win over return status", result: ScriptResult{Errors: 1, ReturnStatus: 5}, want: 1},
+		{name: "return status", result: ScriptResult{ReturnStatus: 5}, want: 5},
+		{name: "largest exit status", result: ScriptResult{ReturnStatus: 255}, want: 255},
+		{name: "return status out of range", result: ScriptResult{ReturnStatus: 256}, want: 1},
+		{name: "negative return status", result: ScriptResult{ReturnStatus: -4}, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.result.ExitCode(); got != tt.want {
+				t.Errorf("ExitCode() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package mssql
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// safeLimitBlockers 出现在主 SELECT 中（括号之外）时不改写的关键字：集合运算、SELECT INTO、已有的行数限制、
+// FOR XML/JSON、查询提示，以及第二条语句
+var safeLimitBlockers = map[string]bool{
+	"UNION": true, "EXCEPT": true, "INTERSECT": true, "INTO": true, "TOP": true, "OFFSET": true,
+	"FETCH": true, "FOR": true, "OPTION": true, "SELECT": true, "INSERT": true, "UPDATE": true,
+	"DELETE": true, "MERGE": true, "EXEC": true, "EXECUTE": true, "DECLARE": true, "SET": true,
+}
+
+// selectModifierPattern 匹配 SELECT 之后的 ALL / DISTINCT，selectAssignPattern 匹配以变量赋值开始的选择列表，
+// safeLimitStartKeywords 为 CTE 之后可能出现的主语句关键字
+var (
+	selectModifierPattern  = regexp.MustCompile(`(?i)^\s+(?:ALL|DISTINCT)\b`)
+	selectAssignPattern    = regexp.MustCompile(`(?i)^\s+(?:(?:ALL|DISTINCT)\s+)?@`)
+	safeLimitStartKeywords = map[string]bool{"SELECT": true, "INSERT": true, "UPDATE": true, "DELETE": true, "MERGE": true}
+)
+
+// topLevelWord 括号之外的一个单词及其位置
+type topLevelWord struct {
+	word string // 大写
+	pos  int
+}
+
+// topLevelWords 返回代码中括号之外的所有单词；code 中的字符串和注释应已替换为空格
+func topLevelWords(code string) []topLevelWord {
+	var words []topLevelWord
+	depth := 0
+	for i := 0; i < len(code); i++ {
+		switch ch := code[i]; {
+		case ch == '(':
+			depth++
+		case ch == ')':
+			depth--
+		case isWordStart(code, i):
+			end := i
+			for end < len(code) && isWordChar(code[end]) {
+				end++
+			}
+			if depth == 0 {
+				words = append(words, topLevelWord{strings.ToUpper(code[i:end]), i})
+			}
+			i = end - 1
+		}
+	}
+	return words
+}
+
+// applySafeLimit 为没有行数限制的普通 SELECT 加上限制：有 ORDER BY 时在末尾追加 OFFSET/FETCH，否则在 SELECT
+// 之后插入 TOP (n)。只改写能够确定结构的语句：单条 SELECT（可以带只供查询使用的 CTE），有 FROM，
+// 没有集合运算、SELECT INTO、TOP、OFFSET、FOR XML/JSON、查询提示和变量赋值；其余返回 false，语句保持不变
+func applySafeLimit(sqlStr string, n int) (string, bool) {
+	var lexer sqlLexer
+	lines := strings.Split(sqlStr, "\n")
+	for i, line := range lines {
+		lines[i] = lexer.scan(line)
+	}
+	code := strings.Join(lines, "\n")
+	// 去掉末尾的空白和分号，代码与原文逐字节对应
+	code = strings.TrimRight(code, " \t\r\n;")
+	if lexer.inside() || strings.Contains(code, ";") {
+		return sqlStr, false
+	}
+
+	words := topLevelWords(code)
+	if len(words) == 0 {
+		return sqlStr, false
+	}
+	main := 0
+	if words[0].word == "WITH" {
+		// CTE 的定义在括号中，括号外第一个语句关键字是主语句；主语句不是 SELECT 时 CTE 供 DML 使用
+		main = -1
+		for i, w := range words {
+			if safeLimitStartKeywords[w.word] {
+				main = i
+				break
+			}
+		}
+		if main < 0 {
+			return sqlStr, false
+		}
+	}
+	if words[main].word != "SELECT" {
+		return sqlStr, false
+	}
+
+	hasFrom, hasOrderBy := false, false
+	for i, w := range words[main+1:] {
+		switch {
+		case safeLimitBlockers[w.word]:
+			return sqlStr, false
+		case w.word == "FROM":
+			hasFrom = true
+		case w.word == "ORDER" && main+i+2 < len(words) && words[main+i+2].word == "BY":
+			hasOrderBy = true
+		}
+	}
+	afterSelect := words[main].pos + len("SELECT")
+	if !hasFrom || selectAssignPattern.MatchString(code[afterSelect:]) {
+		return sqlStr, false
+	}
+
+	if hasOrderBy {
+		return sqlStr[:len(code)] + fmt.Sprintf(" OFFSET 0 ROWS FETCH NEXT %d ROWS ONLY", n), true
+	}
+	if m := selectModifierPattern.FindStringIndex(code[afterSelect:]); m != nil {
+		afterSelect += m[1]
+	}
+	return sqlStr[:afterSelect] + fmt.Sprintf(" TOP (%d)", n) + sqlStr[afterSelect:len(code)], true
+}
+
+// setSafeLimit 处理 safelimit <n|off> 命令：打开后没有行数限制的普通 SELECT 只从服务器取前 n 行
+func (c *CLI) setSafeLimit(arg string) {
+	switch strings.ToLower(arg) {
+	case "":
+	case "off", "0":
+		c.safeLimit = 0
+	default:
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 0 {
+			fmt.Fprintf(c.term, "Usage: safelimit <n>|off\n")
+			return
+		}
+		c.safeLimit = n
+	}
+	if c.safeLimit == 0 {
+		fmt.Fprintf(c.term, "Safe limit: off\n")
+		return
+	}
+	fmt.Fprintf(c.term, "Safe limit: %d rows for plain SELECT statements\n", c.safeLimit)
+}
@@ -0,0 +1,333 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// scriptTable 处理 script <table> 命令，由目录视图生成重建表的 CREATE TABLE 语句，
+// 随后是索引和外键语句
+func (c *CLI) scriptTable(arg string) {
+	if arg == "" {
+		fmt.Fprintf(c.term, "Usage: script <[schema.]table>\n")
+		return
+	}
+	ctx, cancel := c.statementContext()
+	defer cancel()
+	defer c.flushOutput()
+
+	obj, err := c.resolveObject(ctx, arg)
+	if err != nil {
+		c.printError(err)
+		return
+	}
+	if obj.typ != "U" {
+		c.printError(fmt.Errorf("%s is not a table", obj.fullName()))
+		return
+	}
+	ddl, err := c.tableDDL(ctx, obj)
+	if err != nil {
+		c.printError(err)
+		return
+	}
+	c.printDefinition(obj.fullName(), ddl, false)
+}
+
+// tableDDL 生成表的完整 DDL
+func (c *CLI) tableDDL(ctx context.Context, obj dbObject) (string, error) {
+	table := quoteIdent(obj.schema) + "." + quoteIdent(obj.name)
+	id := sql.Named("id", obj.id)
+
+	columns, err := c.scriptColumns(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	keys, indexes, err := c.scriptIndexes(ctx, id, table)
+	if err != nil {
+		return "", err
+	}
+	checks, err := c.scriptChecks(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	foreignKeys, err := c.scriptForeignKeys(ctx, id, table)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "CREATE TABLE %s (\n", table)
+	lines := append(append(columns, keys...), checks...)
+	for i, line := range lines {
+		b.WriteString("    " + line)
+		if i < len(lines)-1 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(");\nGO\n")
+	for _, stmt := range append(indexes, foreignKeys...) {
+		b.WriteString("\n" + stmt + ";\nGO\n")
+	}
+	return b.String(), nil
+}
+
+// scriptColumns 生成列定义：类型、排序规则（与数据库默认值不同时）、IDENTITY、NULL/NOT NULL 和默认值约束
+func (c *CLI) scriptColumns(ctx context.Context, id sql.NamedArg) ([]string, error) {
+	rows, err := c.conn.QueryContext(ctx, `SELECT c.name, t.name, t.is_user_defined, SCHEMA_NAME(t.schema_id),
+	c.max_length, c.precision, c.scale, c.is_nullable,
+	CASE WHEN c.collation_name COLLATE DATABASE_DEFAULT <> CAST(DATABASEPROPERTYEX(DB_NAME(), 'Collation') AS sysname) THEN c.collation_name END,
+	CAST(ic.seed_value AS varchar(40)), CAST(ic.increment_value AS varchar(40)),
+	dc.name, dc.definition, cc.definition, ISNULL(cc.is_persisted, 0)
+FROM sys.columns AS c
+JOIN sys.types AS t ON t.user_type_id = c.user_type_id
+LEFT JOIN sys.identity_columns AS ic ON ic.object_id = c.object_id AND ic.column_id = c.column_id
+LEFT JOIN sys.default_constraints AS dc ON dc.object_id = c.default_object_id
+LEFT JOIN sys.computed_columns AS cc ON cc.object_id = c.object_id AND cc.column_id = c.column_id
+WHERE c.object_id = @id
+ORDER BY c.column_id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lines []string
+	for rows.Next() {
+		var col ddlColumn
+		if err := rows.Scan(&col.name, &col.typeName, &col.userDefined, &col.typeSchema, &col.maxLength, &col.precision, &col.scale, &col.nullable,
+			&col.collation, &col.seed, &col.increment, &col.defaultName, &col.defaultDef, &col.computed, &col.persisted); err != nil {
+			return nil, err
+		}
+		lines = append(lines, col.definition())
+	}
+	return lines, rows.Err()
+}
+
+// ddlColumn 从 sys.columns 及相关目录视图读取的一列，collation 只在与数据库默认值不同时有值
+type ddlColumn struct {
+	name, typeName, typeSchema        string
+	userDefined, nullable, persisted  bool
+	maxLength, precision, scale       int
+	collation, seed, increment        sql.NullString
+	defaultName, defaultDef, computed sql.NullString
+}
+
+// definition 渲染 CREATE TABLE 中的列定义
+func (col ddlColumn) definition() string {
+	line := quoteIdent(col.name)
+	if col.computed.Valid {
+		line += " AS " + col.computed.String
+		if col.persisted {
+			line += " PERSISTED"
+		}
+		return line
+	}
+	if col.userDefined {
+		line += " " + quoteIdent(col.typeSchema) + "." + quoteIdent(col.typeName)
+	} else {
+		line += " " + ddlTypeName(col.typeName, col.maxLength, col.precision, col.scale)
+	}
+	if col.collation.Valid {
+		line += " COLLATE " + col.collation.String
+	}
+	if col.seed.Valid {
+		line += " IDENTITY(" + col.seed.String + ", " + col.increment.String + ")"
+	}
+	if col.nullable {
+		line += " NULL"
+	} else {
+		line += " NOT NULL"
+	}
+	if col.defaultDef.Valid {
+		line += " CONSTRAINT " + quoteIdent(col.defaultName.String) + " DEFAULT " + col.defaultDef.String
+	}
+	return line
+}
+
+// ddlTypeName 渲染系统类型，如 varchar(max)、nvarchar(100)、decimal(18, 2)、datetime2(3)
+func ddlTypeName(typeName string, maxLength, precision, scale int) string {
+	length := func(n int) string {
+		if maxLength == -1 {
+			return "max"
+		}
+		return strconv.Itoa(n)
+	}
+	switch typeName {
+	case "varchar", "char", "varbinary", "binary":
+		return typeName + "(" + length(maxLength) + ")"
+	case "nvarchar", "nchar":
+		return typeName + "(" + length(maxLength/2) + ")"
+	case "decimal", "numeric":
+		return fmt.Sprintf("%s(%d, %d)", typeName, precision, scale)
+	case "datetime2", "time", "datetimeoffset":
+		return fmt.Sprintf("%s(%d)", typeName, scale)
+	}
+	return typeName
+}
+
+// scriptIndexes 生成表内的主键和唯一约束，以及其余索引的 CREATE INDEX 语句
+func (c *CLI) scriptIndexes(ctx context.Context, id sql.NamedArg, table string) (keys, indexes []string, err error) {
+	rows, err := c.conn.QueryContext(ctx, `SELECT i.index_id, i.name, i.type, i.is_unique, i.is_primary_key,
+	i.is_unique_constraint, i.filter_definition, col.name, ic.is_included_column, ic.is_descending_key
+FROM sys.indexes AS i
+LEFT JOIN sys.index_columns AS ic ON ic.object_id = i.object_id AND ic.index_id = i.index_id
+LEFT JOIN sys.columns AS col ON col.object_id = ic.object_id AND col.column_id = ic.column_id
+WHERE i.object_id = @id AND i.index_id > 0 AND i.is_hypothetical = 0
+ORDER BY i.index_id, ic.is_included_column, ic.key_ordinal, ic.index_column_id`, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	type index struct {
+		name                      string
+		typ                       int
+		unique, primary, isUnique bool
+		filter                    sql.NullString
+		keys, included            []string
+	}
+	var list []*index
+	lastID := -1
+	for rows.Next() {
+		var indexID, typ int
+		var name string
+		var unique, primary, uniqueConstraint bool
+		var filter, column sql.NullString
+		var included, descending sql.NullBool
+		if err := rows.Scan(&indexID, &name, &typ, &unique, &primary, &uniqueConstraint, &filter, &column, &included, &descending); err != nil {
+			return nil, nil, err
+		}
+		if indexID != lastID {
+			lastID = indexID
+			list = append(list, &index{name: name, typ: typ, unique: unique, primary: primary, isUnique: uniqueConstraint, filter: filter})
+		}
+		ix := list[len(list)-1]
+		switch {
+		case !column.Valid:
+		case included.Bool:
+			ix.included = append(ix.included, quoteIdent(column.String))
+		case descending.Bool:
+			ix.keys = append(ix.keys, quoteIdent(column.String)+" DESC")
+		default:
+			ix.keys = append(ix.keys, quoteIdent(column.String))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	for _, ix := range list {
+		kind := "NONCLUSTERED"
+		if ix.typ == 1 || ix.typ == 5 {
+			kind = "CLUSTERED"
+		}
+		switch {
+		case ix.primary || ix.isUnique:
+			constraint := "UNIQUE"
+			if ix.primary {
+				constraint = "PRIMARY KEY"
+			}
+			keys = append(keys, fmt.Sprintf("CONSTRAINT %s %s %s (%s)", quoteIdent(ix.name), constraint, kind, strings.Join(ix.keys, ", ")))
+		case ix.typ == 5:
+			indexes = append(indexes, fmt.Sprintf("CREATE CLUSTERED COLUMNSTORE INDEX %s ON %s", quoteIdent(ix.name), table))
+		case ix.typ == 6:
+			// 非聚集列存储索引的列都记为包含列
+			cols := append(ix.keys, ix.included...)
+			indexes = append(indexes, fmt.Sprintf("CREATE NONCLUSTERED COLUMNSTORE INDEX %s ON %s (%s)", quoteIdent(ix.name), table, strings.Join(cols, ", ")))
+		case ix.typ == 1 || ix.typ == 2:
+			stmt := "CREATE "
+			if ix.unique {
+				stmt += "UNIQUE "
+			}
+			stmt += fmt.Sprintf("%s INDEX %s ON %s (%s)", kind, quoteIdent(ix.name), table, strings.Join(ix.keys, ", "))
+			if len(ix.included) > 0 {
+				stmt += " INCLUDE (" + strings.Join(ix.included, ", ") + ")"
+			}
+			if ix.filter.Valid {
+				stmt += " WHERE " + ix.filter.String
+			}
+			indexes = append(indexes, stmt)
+		default:
+			// XML、空间等索引需要额外的选项，只给出提示
+			indexes = append(indexes, fmt.Sprintf("-- index %s (type %d) is not scripted", quoteIdent(ix.name), ix.typ))
+		}
+	}
+	return keys, indexes, nil
+}
+
+// scriptChecks 生成表内的检查约束
+func (c *CLI) scriptChecks(ctx context.Context, id sql.NamedArg) ([]string, error) {
+	data, err := c.queryValues(ctx, `SELECT name, definition FROM sys.check_constraints
+WHERE parent_object_id = @id ORDER BY name`, id)
+	if err != nil {
+		return nil, err
+	}
+	lines := make([]string, len(data))
+	for i, row := range data {
+		lines[i] = fmt.Sprintf("CONSTRAINT %s CHECK %v", quoteIdent(fmt.Sprint(row[0])), row[1])
+	}
+	return lines, nil
+}
+
+// scriptForeignKeys 生成表引用其他表的 ALTER TABLE ... FOREIGN KEY 语句
+func (c *CLI) scriptForeignKeys(ctx context.Context, id sql.NamedArg, table string) ([]string, error) {
+	rows, err := c.conn.QueryContext(ctx, `SELECT fk.object_id, fk.name,
+	OBJECT_SCHEMA_NAME(fk.referenced_object_id), OBJECT_NAME(fk.referenced_object_id),
+	pc.name, rc.name, fk.delete_referential_action_desc, fk.update_referential_action_desc
+FROM sys.foreign_keys AS fk
+JOIN sys.foreign_key_columns AS fkc ON fkc.constraint_object_id = fk.object_id
+JOIN sys.columns AS pc ON pc.object_id = fkc.parent_object_id AND pc.column_id = fkc.parent_column_id
+JOIN sys.columns AS rc ON rc.object_id = fkc.referenced_object_id AND rc.column_id = fkc.referenced_column_id
+WHERE fk.parent_object_id = @id
+ORDER BY fk.name, fkc.constraint_column_id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type foreignKey struct {
+		name, refTable, onDelete, onUpdate string
+		columns, refColumns                []string
+	}
+	var list []*foreignKey
+	lastID := int64(-1)
+	for rows.Next() {
+		var fkID int64
+		var name, refSchema, refName, column, refColumn, onDelete, onUpdate string
+		if err := rows.Scan(&fkID, &name, &refSchema, &refName, &column, &refColumn, &onDelete, &onUpdate); err != nil {
+			return nil, err
+		}
+		if fkID != lastID {
+			lastID = fkID
+			list = append(list, &foreignKey{
+				name:     name,
+				refTable: quoteIdent(refSchema) + "." + quoteIdent(refName),
+				onDelete: strings.ReplaceAll(onDelete, "_", " "),
+				onUpdate: strings.ReplaceAll(onUpdate, "_", " "),
+			})
+		}
+		fk := list[len(list)-1]
+		fk.columns = append(fk.columns, quoteIdent(column))
+		fk.refColumns = append(fk.refColumns, quoteIdent(refColumn))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	stmts := make([]string, len(list))
+	for i, fk := range list {
+		stmt := fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s (%s)",
+			table, quoteIdent(fk.name), strings.Join(fk.columns, ", "), fk.refTable, strings.Join(fk.refColumns, ", "))
+		if fk.onDelete != "NO ACTION" {
+			stmt += " ON DELETE " + fk.onDelete
+		}
+		if fk.onUpdate != "NO ACTION" {
+			stmt += " ON UPDATE " + fk.onUpdate
+		}
+		stmts[i] = stmt
+	}
+	return stmts, nil
+}
@@ -0,0 +1,101 @@
+package mssql
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func TestDDLTypeName(t *testing.T) {
+	tests := []struct {
+		typeName                    string
+		maxLength, precision, scale int
+		want                        string
+	}{
+		{typeName: "varchar", maxLength: -1, want: "varchar(max)"},
+		{typeName: "varchar", maxLength: 50, want: "varchar(50)"},
+		{typeName: "char", maxLength: 10, want: "char(10)"},
+		{typeName: "nvarchar", maxLength: -1, want: "nvarchar(max)"},
+		{typeName: "nvarchar", maxLength: 200, want: "nvarchar(100)"},
+		{typeName: "nchar", maxLength: 2, want: "nchar(1)"},
+		{typeName: "varbinary", maxLength: -1, want: "varbinary(max)"},
+		{typeName: "binary", maxLength: 16, want: "binary(16)"},
+		{typeName: "decimal", maxLength: 9, precision: 18, scale: 2, want: "decimal(18, 2)"},
+		{typeName: "numeric", maxLength: 5, precision: 5, scale: 0, want: "numeric(5, 0)"},
+		{typeName: "datetime2", maxLength: 7, precision: 23, scale: 3, want: "datetime2(3)"},
+		{typeName: "datetime2", maxLength: 8, precision: 27, scale: 7, want: "datetime2(7)"},
+		{typeName: "time", maxLength: 3, precision: 8, scale: 0, want: "time(0)"},
+		{typeName: "datetimeoffset", maxLength: 10, precision: 34, scale: 7, want: "datetimeoffset(7)"},
+		{typeName: "int", maxLength: 4, precision: 10, want: "int"},
+		{typeName: "datetime", maxLength: 8, precision: 23, scale: 3, want: "datetime"},
+		{typeName: "uniqueidentifier", maxLength: 16, want: "uniqueidentifier"},
+		{typeName: "xml", maxLength: -1, want: "xml"},
+	}
+	for _, tt := range tests {
+		if got := ddlTypeName(tt.typeName, tt.maxLength, tt.precision, tt.scale); got != tt.want {
+			t.Errorf("ddlTypeName(%s, %d, %d, %d) = %q, want %q", tt.typeName, tt.maxLength, tt.precision, tt.scale, got, tt.want)
+		}
+	}
+}
+
+func TestQuoteIdent(t *testing.T) {
+	for name, want := range map[string]string{
+		"Orders":        "[Orders]",
+		"Order Details": "[Order Details]",
+		"a]b":           "[a]]b]",
+		"[x]":           "[[x]]]",
+		"":              "[]",
+		"表":             "[表]",
+	} {
+		if got := quoteIdent(name); got != want {
+			t.Errorf("quoteIdent(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestDDLColumnDefinition(t *testing.T) {
+	valid := func(s string) sql.NullString { return sql.NullString{String: s, Valid: true} }
+	tests := []struct {
+		name string
+		col  ddlColumn
+		want string
+	}{
+		{
+			name: "identity key",
+			col:  ddlColumn{name: "id", typeName: "int", maxLength: 4, precision: 10, seed: valid("1"), increment: valid("1")},
+			want: "[id] int IDENTITY(1, 1) NOT NULL",
+		},
+		{
+			name: "collation",
+			col:  ddlColumn{name: "code", typeName: "varchar", maxLength: 20, nullable: true, collation: valid("Latin1_General_BIN2")},
+			want: "[code] varchar(20) COLLATE Latin1_General_BIN2 NULL",
+		},
+		{
+			name: "max with default",
+			col: ddlColumn{name: "notes", typeName: "nvarchar", maxLength: -1, nullable: true,
+				defaultName: valid("DF_Orders]notes"), defaultDef: valid("(N'')")},
+			want: "[notes] nvarchar(max) NULL CONSTRAINT [DF_Orders]]notes] DEFAULT (N'')",
+		},
+		{
+			name: "decimal",
+			col:  ddlColumn{name: "Unit Price", typeName: "decimal", maxLength: 9, precision: 10, scale: 4},
+			want: "[Unit Price] decimal(10, 4) NOT NULL",
+		},
+		{
+			name: "user-defined type",
+			col:  ddlColumn{name: "phone", typeName: "Phone", typeSchema: "dbo", userDefined: true, nullable: true},
+			want: "[phone] [dbo].[Phone] NULL",
+		},
+		{
+			name: "persisted computed column",
+			col:  ddlColumn{name: "total", typeName: "decimal", computed: valid("([qty]*[price])"), persisted: true},
+			want: "[total] AS ([qty]*[price]) PERSISTED",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.col.definition(); got != tt.want {
+				t.Errorf("definition() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
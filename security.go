@@ -0,0 +1,97 @@
+package mssql
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// aggregateLast 将按第一列排序的结果中第一列相同的行合并为一行，最后一列的非空值合并为逗号分隔的列表
+// STRING_AGG 从 SQL Server 2017 才提供，因此在客户端合并
+func aggregateLast(data [][]interface{}) [][]interface{} {
+	var result [][]interface{}
+	var items []string
+	flush := func() {
+		if len(result) > 0 {
+			result[len(result)-1][len(result[len(result)-1])-1] = strings.Join(items, ", ")
+		}
+		items = nil
+	}
+	for _, row := range data {
+		if len(result) == 0 || fmt.Sprint(result[len(result)-1][0]) != fmt.Sprint(row[0]) {
+			flush()
+			result = append(result, append([]interface{}(nil), row...))
+		}
+		if last := row[len(row)-1]; last != nil {
+			items = append(items, fmt.Sprint(last))
+		}
+	}
+	flush()
+	return result
+}
+
+// runAggregated 执行查询，按第一列合并最后一列后输出
+func (c *CLI) runAggregated(cols []string, query string) {
+	startTime := time.Now()
+	ctx, cancel := c.statementContext()
+	defer cancel()
+	defer c.flushOutput()
+
+	data, err := c.queryValues(ctx, query)
+	if err != nil {
+		c.printError(err)
+		return
+	}
+	c.renderValues(cols, aggregateLast(data), startTime)
+}
+
+// listUsers 处理 users 命令，列出当前数据库的用户、类型、默认架构和所属角色
+func (c *CLI) listUsers(arg string) {
+	c.runAggregated([]string{"name", "type", "default_schema", "roles"}, `SELECT p.name,
+	CASE
+		WHEN p.type = 'S' AND p.authentication_type = 0 THEN 'without login'
+		WHEN p.type = 'S' AND p.authentication_type = 2 THEN 'contained SQL user'
+		WHEN p.type = 'S' THEN 'SQL user'
+		WHEN p.type = 'U' THEN 'Windows user'
+		WHEN p.type = 'G' THEN 'Windows group'
+		WHEN p.type IN ('E', 'X') THEN 'external'
+		WHEN p.type = 'C' THEN 'certificate'
+		WHEN p.type = 'K' THEN 'asymmetric key'
+	END,
+	p.default_schema_name, r.name
+FROM sys.database_principals AS p
+LEFT JOIN sys.database_role_members AS m ON m.member_principal_id = p.principal_id
+LEFT JOIN sys.database_principals AS r ON r.principal_id = m.role_principal_id
+WHERE p.type IN ('S', 'U', 'G', 'E', 'X', 'C', 'K')
+	AND p.name NOT IN ('sys', 'INFORMATION_SCHEMA') AND p.name NOT LIKE '##%'
+ORDER BY p.name, r.name`)
+}
+
+// listRoles 处理 roles 命令，列出当前数据库的角色及其成员
+func (c *CLI) listRoles(arg string) {
+	c.runAggregated([]string{"name", "fixed", "members"}, `SELECT r.name,
+	CASE r.is_fixed_role WHEN 1 THEN 'YES' ELSE 'NO' END, mp.name
+FROM sys.database_principals AS r
+LEFT JOIN sys.database_role_members AS m ON m.role_principal_id = r.principal_id
+LEFT JOIN sys.database_principals AS mp ON mp.principal_id = m.member_principal_id
+WHERE r.type = 'R'
+ORDER BY r.name, mp.name`)
+}
+
+// listLogins 处理 logins 命令，列出服务器登录名的类型、停用和锁定状态及所属服务器角色；
+// 没有 sysadmin 或 VIEW ANY DEFINITION 权限时只能看到自己的登录名
+func (c *CLI) listLogins(arg string) {
+	c.runAggregated([]string{"name", "type", "status", "default_database", "created", "server_roles"}, `SELECT p.name,
+	p.type_desc,
+	CASE
+		WHEN p.is_disabled = 1 THEN 'DISABLED'
+		WHEN LOGINPROPERTY(p.name, 'IsLocked') = 1 THEN 'LOCKED'
+		ELSE 'enabled'
+	END,
+	p.default_database_name, p.create_date, r.name
+FROM sys.server_principals AS p
+LEFT JOIN sys.server_role_members AS m ON m.member_principal_id = p.principal_id
+LEFT JOIN sys.server_principals AS r ON r.principal_id = m.role_principal_id
+WHERE p.type IN ('S', 'U', 'G', 'E', 'X') AND p.name NOT LIKE '##%'
+ORDER BY p.name, r.name`)
+}
@@ -0,0 +1,64 @@
+package mssql
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// usePattern 匹配以 USE 开头的语句
+var usePattern = regexp.MustCompile(`(?i)^\s*USE\b`)
+
+// setPattern 匹配单条会话级 SET 语句，捕获选项名称；SET @变量 不属于会话选项。
+// STATISTICS IO、STATISTICS TIME 等是各自独立的选项，捕获完整的名称
+var setPattern = regexp.MustCompile(`(?i)^\s*SET[ \t]+((?:TRANSACTION[ \t]+ISOLATION[ \t]+LEVEL)|(?:STATISTICS[ \t]+(?:IO|TIME|XML|PROFILE))|SHOWPLAN_(?:TEXT|ALL|XML)|[A-Z_]+(?:[ \t]*,[ \t]*[A-Z_]+)*)\b[^;\n]*$`)
+
+// trackSession 记录语句对会话状态的修改，重新连接后据此恢复
+func (c *CLI) trackSession(sqlStr string) {
+	if usePattern.MatchString(sqlStr) {
+		var name, schema string
+		if err := c.conn.QueryRowContext(context.Background(), "SELECT DB_NAME(), ISNULL(SCHEMA_NAME(), '')").Scan(&name, &schema); err == nil {
+			c.database, c.defaultSchema = name, schema
+		}
+		return
+	}
+
+	m := setPattern.FindStringSubmatch(sqlStr)
+	if m == nil {
+		return
+	}
+	// IDENTITY_INSERT 等针对表的选项不在重连后重放
+	option := strings.ToUpper(strings.Join(strings.Fields(m[1]), " "))
+	if option == "IDENTITY_INSERT" {
+		return
+	}
+
+	// 同一选项只保留最后一次设置
+	kept := c.sessionSets[:0]
+	for _, stmt := range c.sessionSets {
+		if prev := setPattern.FindStringSubmatch(stmt); prev == nil ||
+			strings.ToUpper(strings.Join(strings.Fields(prev[1]), " ")) != option {
+			kept = append(kept, stmt)
+		}
+	}
+	c.sessionSets = append(kept, strings.TrimSpace(sqlStr))
+}
+
+// restoreSession 在新的会话连接上恢复当前数据库和 SET 选项
+// 临时表和未提交的事务无法恢复，存在时提示用户
+func (c *CLI) restoreSession() error {
+	ctx := context.Background()
+	if c.database != "" {
+		if _, err := c.conn.ExecContext(ctx, fmt.Sprintf("USE [%s]", strings.ReplaceAll(c.database, "]", "]]"))); err != nil {
+			return err
+		}
+	}
+	for _, stmt := range c.sessionSets {
+		if _, err := c.conn.ExecContext(ctx, stmt); err != nil {
+			fmt.Fprintf(c.term, "Warning: could not restore '%s': %v\n", stmt, err)
+		}
+	}
+	fmt.Fprintf(c.term, "Note: temporary tables and open transactions from the previous session are lost.\n")
+	return nil
+}
@@ -0,0 +1,84 @@
+package mssql
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUseDatabaseRefreshesDefaultSchema(t *testing.T) {
+	term := &testTerm{}
+	c := NewCLIWithConfig(term, &Config{Host: "db1", Username: "sa", Password: "x", Database: "master"})
+	c.defaultSchema = "dbo"
+	fc := connectFake(t, c, []fakeColumn{
+		{name: "db", typeName: "NVARCHAR"},
+		{name: "schema", typeName: "NVARCHAR"},
+	}, []driver.Value{"Sales]2024", "reporting"})
+
+	c.useDatabase("Sales]2024")
+
+	if len(fc.execs) != 1 || fc.execs[0] != "USE [Sales]]2024]" {
+		t.Errorf("executed %q, want USE [Sales]]2024]", fc.execs)
+	}
+	if c.database != "Sales]2024" || c.defaultSchema != "reporting" {
+		t.Errorf("database = %q, default schema = %q, want Sales]2024 and reporting", c.database, c.defaultSchema)
+	}
+	if !strings.Contains(term.String(), "Changed database context to 'Sales]2024'.") {
+		t.Errorf("unexpected output %q", term.String())
+	}
+}
+
+func TestTempTableSurvivesBetweenStatements(t *testing.T) {
+	c, term := connectTestServer(t, nil)
+
+	// 交互执行和 Query 使用同一个会话连接，前一条语句创建的临时表在后一条语句中可见
+	if err := c.runStatement("CREATE TABLE #session_test (id int, name nvarchar(20)); INSERT INTO #session_test VALUES (1, N'one'), (2, N'two')", time.Now(), false); err != nil {
+		t.Fatalf("create temp table: %v\n%s", err, term.String())
+	}
+	result, err := c.Query(context.Background(), "SELECT id, name FROM #session_test ORDER BY id")
+	if err != nil {
+		t.Fatalf("query temp table: %v", err)
+	}
+	if len(result.Rows) != 2 || result.Rows[1][1] != "two" {
+		t.Errorf("rows = %v, want the two inserted rows", result.Rows)
+	}
+	if _, err := c.Query(context.Background(), "DROP TABLE #session_test"); err != nil {
+		t.Errorf("drop temp table: %v", err)
+	}
+}
+
+func TestTrackSessionKeepsEachStatisticsOption(t *testing.T) {
+	c := NewCLIWithConfig(&testTerm{}, &Config{Host: "db1", Username: "sa", Password: "x"})
+	for _, stmt := range []string{
+		"SET STATISTICS IO ON",
+		"SET STATISTICS TIME ON",
+		"SET NOCOUNT ON",
+		"SET SHOWPLAN_TEXT OFF",
+		"SET SHOWPLAN_XML OFF",
+		"set statistics  io off", // 同一选项只保留最后一次设置
+	} {
+		c.trackSession(stmt)
+	}
+	want := []string{"SET STATISTICS TIME ON", "SET NOCOUNT ON", "SET SHOWPLAN_TEXT OFF", "SET SHOWPLAN_XML OFF", "set statistics  io off"}
+	if strings.Join(c.sessionSets, "\n") != strings.Join(want, "\n") {
+		t.Errorf("sessionSets = %q, want %q", c.sessionSets, want)
+	}
+}
+
+func TestRestoreSessionReplaysStatistics(t *testing.T) {
+	c := NewCLIWithConfig(&testTerm{}, &Config{Host: "db1", Username: "sa", Password: "x"})
+	c.database = "app"
+	c.trackSession("SET STATISTICS IO ON")
+	c.trackSession("SET STATISTICS TIME ON")
+	fc := connectFake(t, c, nil)
+
+	if err := c.restoreSession(); err != nil {
+		t.Fatalf("restoreSession: %v", err)
+	}
+	want := []string{"USE [app]", "SET STATISTICS IO ON", "SET STATISTICS TIME ON"}
+	if strings.Join(fc.execs, "\n") != strings.Join(want, "\n") {
+		t.Errorf("replayed %q, want %q", fc.execs, want)
+	}
+}
@@ -0,0 +1,263 @@
+package mssql
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultSessionName 添加第一个命名会话时，原有会话使用的名称
+const defaultSessionName = "default"
+
+// namedSession 未激活的命名会话，保存连接和会话级的显示设置
+type namedSession struct {
+	target        target
+	db            *sql.DB
+	conn          *sql.Conn
+	sessionSets   []string
+	serverInfo    ServerInfo
+	timingEnabled bool
+	maxRows       int
+	outputFormat  string
+	formatter     Formatter
+	display       displaySettings
+	expanded      bool
+	timeout       time.Duration
+	countLimit    int64
+	connectedAt   time.Time
+	defaultSchema string
+}
+
+// saveSession 取出当前激活会话的状态，连接交由返回值持有
+func (c *CLI) saveSession() *namedSession {
+	s := &namedSession{
+		target:        c.currentTarget(),
+		db:            c.db,
+		conn:          c.conn,
+		sessionSets:   c.sessionSets,
+		serverInfo:    c.serverInfo,
+		timingEnabled: c.timingEnabled,
+		maxRows:       c.maxRows,
+		outputFormat:  c.outputFormat,
+		formatter:     c.formatter,
+		display:       c.display,
+		expanded:      c.expanded,
+		timeout:       c.timeout,
+		countLimit:    c.countLimit,
+		connectedAt:   c.connectedAt,
+		defaultSchema: c.defaultSchema,
+	}
+	c.db, c.conn = nil, nil
+	return s
+}
+
+// loadSession 将会话状态设为当前激活会话
+func (c *CLI) loadSession(s *namedSession) {
+	c.setTarget(s.target)
+	c.db, c.conn = s.db, s.conn
+	c.sessionSets = s.sessionSets
+	c.serverInfo = s.serverInfo
+	c.timingEnabled = s.timingEnabled
+	c.maxRows = s.maxRows
+	c.outputFormat = s.outputFormat
+	c.formatter = s.formatter
+	c.display = s.display
+	c.expanded = s.expanded
+	c.timeout = s.timeout
+	c.countLimit = s.countLimit
+	c.connectedAt = s.connectedAt
+	c.defaultSchema = s.defaultSchema
+}
+
+// close 关闭会话的连接
+func (s *namedSession) close() {
+	if s.conn != nil {
+		s.conn.Close()
+	}
+	if s.db != nil {
+		s.db.Close()
+	}
+}
+
+// sessionCommand 处理 session add|list|use|close 命令
+func (c *CLI) sessionCommand(arg string) {
+	sub, rest, _ := strings.Cut(strings.TrimSpace(arg), " ")
+	rest = strings.TrimSpace(rest)
+	switch strings.ToLower(sub) {
+	case "add":
+		name, spec, _ := strings.Cut(rest, " ")
+		c.addSession(name, strings.TrimSpace(spec))
+	case "", "list":
+		c.listSessions()
+	case "use":
+		c.useSession(rest)
+	case "close":
+		c.closeSession(rest)
+	default:
+		fmt.Fprintf(c.term, "Usage: session add <name> host=...,port=...,user=...,password=...,database=...\n"+
+			"       session list | session use <name> | session close <name>\n")
+	}
+}
+
+// addSession 打开新的命名会话并切换到该会话，新会话沿用当前的显示设置
+func (c *CLI) addSession(name, spec string) {
+	if name == "" || spec == "" {
+		fmt.Fprintf(c.term, "Usage: session add <name> host=...,port=...,user=...,password=...,database=...\n")
+		return
+	}
+	if c.hasSession(name) {
+		fmt.Fprintf(c.term, "Session '%s' already exists\n", name)
+		return
+	}
+
+	config := c.config
+	config.ConnectionString = ""
+	config.Instance = ""
+	config.FailoverPartner, config.FailoverPort = "", 0
+	config.Host, config.Port = "", 0
+	config.Username, config.Password, config.Database = "", "", ""
+	config.DAC = false
+	hasPassword := false
+	for _, field := range strings.FieldsFunc(spec, func(r rune) bool { return r == ',' || r == ' ' }) {
+		key, value, _ := strings.Cut(field, "=")
+		switch strings.ToLower(key) {
+		case "host", "server":
+			config.Host = value
+		case "port":
+			port, err := strconv.Atoi(value)
+			if err != nil || port <= 0 || port > 65535 {
+				fmt.Fprintf(c.term, "Invalid port '%s'\n", value)
+				return
+			}
+			config.Port = port
+		case "user":
+			config.Username = value
+		case "password":
+			config.Password, hasPassword = value, true
+		case "database":
+			config.Database = value
+		default:
+			fmt.Fprintf(c.term, "Unknown session option '%s'\n", key)
+			return
+		}
+	}
+	if config.Host == "" {
+		fmt.Fprintf(c.term, "Session '%s' needs a host\n", name)
+		return
+	}
+	if config.Username != "" && !hasPassword {
+		password, err := c.reader.ReadPassword("Password: ")
+		if err != nil {
+			fmt.Fprintf(c.term, "-- session add cancelled\n")
+			return
+		}
+		config.Password = password
+	}
+
+	current := c.saveSession()
+	if err := c.switchTarget(&config); err != nil {
+		c.loadSession(current)
+		c.printError(err)
+		return
+	}
+	if c.sessions == nil {
+		c.sessions = make(map[string]*namedSession)
+	}
+	if c.sessionName == "" {
+		c.sessionName = defaultSessionName
+	}
+	c.sessions[c.sessionName] = current
+	c.sessionName = name
+	c.showWelcome()
+}
+
+// hasSession 判断会话名称是否已被使用
+func (c *CLI) hasSession(name string) bool {
+	if name == c.sessionName || (c.sessionName == "" && name == defaultSessionName) {
+		return true
+	}
+	_, ok := c.sessions[name]
+	return ok
+}
+
+// listSessions 列出所有会话，当前会话以 * 标记
+func (c *CLI) listSessions() {
+	startTime := time.Now()
+	active := c.sessionName
+	if active == "" {
+		active = defaultSessionName
+	}
+	rows := [][]interface{}{{"*", active, c.serverAddress(), c.database, c.serverInfo.Login}}
+	names := make([]string, 0, len(c.sessions))
+	for name := range c.sessions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		s := c.sessions[name]
+		address := s.target.host
+		if s.target.instance != "" {
+			address += `\` + s.target.instance
+		}
+		if s.target.port != 0 {
+			address += ":" + strconv.Itoa(s.target.port)
+		}
+		rows = append(rows, []interface{}{"", name, address, s.target.database, s.serverInfo.Login})
+	}
+	c.renderValues([]string{"active", "name", "server", "database", "login"}, rows, startTime)
+}
+
+// useSession 切换到另一个命名会话，当前会话有未提交的事务时给出警告
+func (c *CLI) useSession(name string) {
+	if name == "" {
+		fmt.Fprintf(c.term, "Usage: session use <name>\n")
+		return
+	}
+	if name == c.sessionName {
+		return
+	}
+	s, ok := c.sessions[name]
+	if !ok {
+		fmt.Fprintf(c.term, "No session named '%s'\n", name)
+		return
+	}
+	if count := c.openTransactions(); count > 0 {
+		fmt.Fprintf(c.term, "Warning: session '%s' has %d open transaction(s); they stay open until you switch back and commit or roll back\n", c.sessionName, count)
+	}
+	delete(c.sessions, name)
+	c.sessions[c.sessionName] = c.saveSession()
+	c.loadSession(s)
+	c.sessionName = name
+	fmt.Fprintf(c.term, "Using session '%s' (%s)\n", name, c.serverAddress())
+}
+
+// closeSession 关闭一个未激活的命名会话
+func (c *CLI) closeSession(name string) {
+	if name == "" {
+		fmt.Fprintf(c.term, "Usage: session close <name>\n")
+		return
+	}
+	if name == c.sessionName {
+		fmt.Fprintf(c.term, "Cannot close the active session; switch to another session first\n")
+		return
+	}
+	s, ok := c.sessions[name]
+	if !ok {
+		fmt.Fprintf(c.term, "No session named '%s'\n", name)
+		return
+	}
+	s.close()
+	delete(c.sessions, name)
+	fmt.Fprintf(c.term, "Session '%s' closed\n", name)
+}
+
+// closeSessions 关闭所有未激活的会话
+func (c *CLI) closeSessions() {
+	for name, s := range c.sessions {
+		s.close()
+		delete(c.sessions, name)
+	}
+}
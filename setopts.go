@@ -0,0 +1,99 @@
+package mssql
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// sessionOptionBits @@OPTIONS 中各位对应的 SET 选项，按位的顺序排列
+var sessionOptionBits = []struct {
+	bit  int
+	name string
+}{
+	{2, "IMPLICIT_TRANSACTIONS"},
+	{4, "CURSOR_CLOSE_ON_COMMIT"},
+	{8, "ANSI_WARNINGS"},
+	{16, "ANSI_PADDING"},
+	{32, "ANSI_NULLS"},
+	{64, "ARITHABORT"},
+	{128, "ARITHIGNORE"},
+	{256, "QUOTED_IDENTIFIER"},
+	{512, "NOCOUNT"},
+	{1024, "ANSI_NULL_DFLT_ON"},
+	{2048, "ANSI_NULL_DFLT_OFF"},
+	{4096, "CONCAT_NULL_YIELDS_NULL"},
+	{8192, "NUMERIC_ROUNDABORT"},
+	{16384, "XACT_ABORT"},
+}
+
+// isolationLevels sys.dm_exec_sessions.transaction_isolation_level 的取值
+var isolationLevels = []string{"UNSPECIFIED", "READ UNCOMMITTED", "READ COMMITTED", "REPEATABLE READ", "SERIALIZABLE", "SNAPSHOT"}
+
+// clientSetOptions 返回本会话中由 CLI 执行过的 SET 语句所设置的选项名称
+func (c *CLI) clientSetOptions() map[string]bool {
+	options := make(map[string]bool)
+	for _, stmt := range c.sessionSets {
+		m := setPattern.FindStringSubmatch(stmt)
+		if m == nil {
+			continue
+		}
+		for _, name := range strings.Split(m[1], ",") {
+			options[strings.ToUpper(strings.Join(strings.Fields(name), " "))] = true
+		}
+	}
+	return options
+}
+
+// showSetOptions 处理 setopts 命令，解码 @@OPTIONS 和 sys.dm_exec_sessions 中当前会话的设置，
+// 通过 SET 语句在本 CLI 中修改过的选项标记为 set by client
+func (c *CLI) showSetOptions(arg string) {
+	if arg != "" {
+		fmt.Fprintf(c.term, "Usage: setopts\n")
+		return
+	}
+	startTime := time.Now()
+	ctx, cancel := c.statementContext()
+	defer cancel()
+	defer c.flushOutput()
+
+	var options, isolation, lockTimeout, deadlockPriority, dateFirst, textSize int
+	var dateFormat, language string
+	err := c.conn.QueryRowContext(ctx, `SELECT @@OPTIONS, s.transaction_isolation_level, s.lock_timeout,
+	s.deadlock_priority, s.date_first, s.text_size, s.date_format, s.language
+FROM sys.dm_exec_sessions AS s
+WHERE s.session_id = @@SPID`).Scan(&options, &isolation, &lockTimeout, &deadlockPriority, &dateFirst, &textSize, &dateFormat, &language)
+	if err != nil {
+		c.printError(err)
+		return
+	}
+
+	setByClient := c.clientSetOptions()
+	var data [][]interface{}
+	add := func(name string, value interface{}) {
+		var source interface{}
+		if setByClient[name] {
+			source = "set by client"
+		}
+		data = append(data, []interface{}{name, value, source})
+	}
+	for _, opt := range sessionOptionBits {
+		add(opt.name, onOff(options&opt.bit != 0))
+	}
+	level := fmt.Sprint(isolation)
+	if isolation >= 0 && isolation < len(isolationLevels) {
+		level = isolationLevels[isolation]
+	}
+	add("TRANSACTION ISOLATION LEVEL", level)
+	timeout := fmt.Sprintf("%d ms", lockTimeout)
+	if lockTimeout < 0 {
+		timeout = "-1 (wait indefinitely)"
+	}
+	add("LOCK_TIMEOUT", timeout)
+	add("DEADLOCK_PRIORITY", deadlockPriority)
+	add("DATEFORMAT", dateFormat)
+	add("DATEFIRST", dateFirst)
+	add("LANGUAGE", language)
+	add("TEXTSIZE", textSize)
+	c.renderValues([]string{"option", "value", "source"}, data, startTime)
+}
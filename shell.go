@@ -0,0 +1,72 @@
+package mssql
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+)
+
+// shellCommand 处理 !<command> 和 :!! <command> 命令：通过系统 shell 执行命令，输出直接写到终端，结束后报告退出状态；
+// 没有命令时启动交互式 shell，exit 后回到命令行。脚本文件和 RunScript 中只有设置了 Config.AllowScriptShell 才执行
+func (c *CLI) shellCommand(arg string) {
+	if (c.includeDepth > 0 || c.nonInteractive) && !c.config.AllowScriptShell {
+		c.printError(errors.New("shell commands are disabled in scripts (set Config.AllowScriptShell to allow them)"))
+		return
+	}
+
+	tty, isTTY := terminalFile(c.term)
+	var cmd *exec.Cmd
+	switch {
+	case arg != "":
+		if runtime.GOOS == "windows" {
+			cmd = exec.Command("cmd", "/C", arg)
+		} else {
+			cmd = exec.Command("sh", "-c", arg)
+		}
+	case !isTTY:
+		fmt.Fprintf(c.term, "An interactive shell needs a local terminal; use !<command>\n")
+		return
+	default:
+		cmd = exec.Command(interactiveShell())
+		fmt.Fprintf(c.term, "%s\n", c.display.colorize("Starting "+cmd.Path+"; type exit to return", ansiDim))
+	}
+	// readline 只在读取一行时读取输入，命令运行期间终端交给子进程；不是本地终端时子进程没有输入
+	if isTTY {
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, tty, tty
+	} else {
+		cmd.Stdout, cmd.Stderr = c.term, c.term
+	}
+
+	// 命令运行期间 Ctrl+C 只作用于子进程，不结束当前会话
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+	defer signal.Stop(sigs)
+
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		fmt.Fprintf(c.term, "%s\n", c.display.colorize("-- exit status 0", ansiDim))
+	case errors.As(err, &exitErr):
+		fmt.Fprintf(c.term, "%s\n", c.display.colorize(fmt.Sprintf("-- exit status %d", exitErr.ExitCode()), ansiRed))
+	default:
+		c.printError(err)
+	}
+}
+
+// interactiveShell 返回交互式 shell：$SHELL（Windows 上为 %COMSPEC%），未设置时使用 sh 或 cmd
+func interactiveShell() string {
+	if runtime.GOOS == "windows" {
+		if shell := os.Getenv("COMSPEC"); shell != "" {
+			return shell
+		}
+		return "cmd"
+	}
+	if shell := os.Getenv("SHELL"); shell != "" {
+		return shell
+	}
+	return "sh"
+}
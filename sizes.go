@@ -0,0 +1,290 @@
+package mssql
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// tableSize 一张表的空间占用，单位为 8KB 页
+type tableSize struct {
+	name     string
+	rows     int64
+	reserved int64
+	data     int64
+	used     int64
+}
+
+// formatSize 将 KB 数显示为 KB、MB 或 GB
+func formatSize(kb int64) string {
+	switch {
+	case kb >= 1024*1024:
+		return fmt.Sprintf("%.2f GB", float64(kb)/(1024*1024))
+	case kb >= 1024:
+		return fmt.Sprintf("%.1f MB", float64(kb)/1024)
+	}
+	return fmt.Sprintf("%d KB", kb)
+}
+
+// listSizes 处理 sizes [[schema.]pattern] 命令，按 sp_spaceused 的算法报告每张表的保留、数据、
+// 索引和未使用空间及行数；分区级的行在客户端汇总为每表一行，按总大小降序排列
+func (c *CLI) listSizes(arg string) {
+	_, rest := catalogArgs(arg)
+	if len(rest) > 1 {
+		fmt.Fprintf(c.term, "Usage: sizes [[schema.]pattern]\n")
+		return
+	}
+	schema, name := namePattern(strings.Join(rest, ""))
+
+	startTime := time.Now()
+	ctx, cancel := c.statementContext()
+	defer cancel()
+	defer c.flushOutput()
+
+	rows, err := c.conn.QueryContext(ctx, `SELECT s.name + '.' + o.name, ps.index_id, ps.row_count,
+	ps.reserved_page_count, ps.used_page_count,
+	ps.in_row_data_page_count + ps.lob_used_page_count + ps.row_overflow_used_page_count
+FROM sys.dm_db_partition_stats AS ps
+JOIN sys.objects AS o ON o.object_id = ps.object_id
+JOIN sys.schemas AS s ON s.schema_id = o.schema_id
+WHERE o.type = 'U' AND o.is_ms_shipped = 0
+	AND LOWER(s.name) LIKE LOWER(@schema) AND LOWER(o.name) LIKE LOWER(@name)`,
+		sql.Named("schema", schema), sql.Named("name", name))
+	if err != nil {
+		c.printError(err)
+		return
+	}
+	defer rows.Close()
+
+	tables := make(map[string]*tableSize)
+	for rows.Next() {
+		var table string
+		var indexID int
+		var rowCount, reserved, used, data int64
+		if err := rows.Scan(&table, &indexID, &rowCount, &reserved, &used, &data); err != nil {
+			c.printError(err)
+			return
+		}
+		t, ok := tables[table]
+		if !ok {
+			t = &tableSize{name: table}
+			tables[table] = t
+		}
+		t.reserved += reserved
+		t.used += used
+		// 行数和数据页只计堆或聚集索引，其余索引的页计入索引空间
+		if indexID < 2 {
+			t.rows += rowCount
+			t.data += data
+		}
+	}
+	if err := rows.Err(); err != nil {
+		c.printError(err)
+		return
+	}
+
+	list := make([]*tableSize, 0, len(tables))
+	for _, t := range tables {
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].reserved != list[j].reserved {
+			return list[i].reserved > list[j].reserved
+		}
+		return list[i].name < list[j].name
+	})
+
+	var total tableSize
+	data := make([][]interface{}, len(list))
+	for i, t := range list {
+		data[i] = []interface{}{t.name, t.rows, formatSize(t.reserved * 8), formatSize(t.data * 8),
+			formatSize((t.used - t.data) * 8), formatSize((t.reserved - t.used) * 8)}
+		total.rows += t.rows
+		total.reserved += t.reserved
+		total.data += t.data
+		total.used += t.used
+	}
+	c.renderValues([]string{"table", "rows", "reserved", "data", "index", "unused"}, data, startTime)
+
+	if c.showInfo() && len(list) > 0 {
+		fmt.Fprintf(c.out, "Total: %s tables, %s rows, reserved %s, data %s, index %s, unused %s\n\n",
+			groupDigits(strconv.Itoa(len(list))), groupDigits(strconv.FormatInt(total.rows, 10)),
+			formatSize(total.reserved*8), formatSize(total.data*8),
+			formatSize((total.used-total.data)*8), formatSize((total.reserved-total.used)*8))
+	}
+}
+
+// databaseFilesQuery 在目标数据库的上下文中查询文件信息，FILEPROPERTY 只对当前数据库有效
+const databaseFilesQuery = `SELECT f.type_desc, f.name, f.physical_name,
+	CAST(f.size AS bigint), CAST(FILEPROPERTY(f.name, 'SpaceUsed') AS bigint),
+	f.max_size, f.growth, f.is_percent_growth
+FROM sys.database_files AS f
+ORDER BY f.file_id`
+
+// dbsizeColumns dbsize 命令输出的列
+var dbsizeColumns = []string{"database", "type", "logical_name", "path", "size", "used", "used_pct", "max_size", "autogrowth"}
+
+// showDatabaseSize 处理 dbsize [-a] [database] 命令，显示数据库文件的大小、已用空间、最大大小和自动增长设置，
+// 日志文件的使用率优先取自 sys.dm_db_log_space_usage；-a 报告所有联机数据库，无权访问的数据库跳过
+func (c *CLI) showDatabaseSize(arg string) {
+	flags, rest := catalogArgs(arg)
+	if len(rest) > 1 || (flags["a"] && len(rest) > 0) {
+		fmt.Fprintf(c.term, "Usage: dbsize [-a | <database>]\n")
+		return
+	}
+
+	startTime := time.Now()
+	ctx, cancel := c.statementContext()
+	defer cancel()
+	defer c.flushOutput()
+
+	databases := rest
+	if len(rest) == 0 {
+		databases = []string{c.database}
+	}
+	if flags["a"] {
+		names, err := c.queryValues(ctx, "SELECT name FROM sys.databases WHERE state_desc = 'ONLINE' ORDER BY name")
+		if err != nil {
+			c.printError(err)
+			return
+		}
+		databases = databases[:0]
+		for _, row := range names {
+			databases = append(databases, fmt.Sprint(row[0]))
+		}
+	}
+
+	var data [][]interface{}
+	var skipped []string
+	for _, db := range databases {
+		rows, err := c.databaseFiles(db)
+		if err != nil {
+			if len(databases) == 1 {
+				c.printError(err)
+				return
+			}
+			skipped = append(skipped, db)
+			continue
+		}
+		data = append(data, rows...)
+	}
+	c.renderValues(dbsizeColumns, data, startTime)
+	if len(skipped) > 0 && c.showInfo() {
+		fmt.Fprintf(c.out, "%s\n\n", c.display.colorize("Skipped (not accessible): "+strings.Join(skipped, ", "), ansiDim))
+	}
+}
+
+// databaseFiles 返回一个数据库的文件行，查询通过 db.sys.sp_executesql 在该数据库中执行
+func (c *CLI) databaseFiles(db string) ([][]interface{}, error) {
+	ctx, cancel := c.statementContext()
+	defer cancel()
+	exec := quoteIdent(db) + ".sys.sp_executesql"
+
+	rows, err := c.conn.QueryContext(ctx, "EXEC "+exec+" @stmt", sql.Named("stmt", databaseFilesQuery))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var data [][]interface{}
+	for rows.Next() {
+		var typeDesc, name, path string
+		var size, maxSize, growth int64
+		var used sql.NullInt64
+		var percentGrowth bool
+		if err := rows.Scan(&typeDesc, &name, &path, &size, &used, &maxSize, &growth, &percentGrowth); err != nil {
+			return nil, err
+		}
+
+		var usedText, pct interface{}
+		if used.Valid {
+			usedText = formatSize(used.Int64 * 8)
+			if size > 0 {
+				pct = fmt.Sprintf("%.1f", float64(used.Int64)*100/float64(size))
+			}
+		}
+		maxText := formatSize(maxSize * 8)
+		switch maxSize {
+		case -1:
+			maxText = "unlimited"
+		case 0:
+			maxText = "no growth"
+		}
+		growthText := formatSize(growth * 8)
+		switch {
+		case growth == 0:
+			growthText = "off"
+		case percentGrowth:
+			growthText = fmt.Sprintf("%d%%", growth)
+		}
+		data = append(data, []interface{}{db, typeDesc, name, path, formatSize(size * 8), usedText, pct, maxText, growthText})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// sys.dm_db_log_space_usage（SQL Server 2012 起）给出准确的日志使用率，不可用时保留 FILEPROPERTY 的值
+	var logPct sql.NullFloat64
+	if err := c.conn.QueryRowContext(ctx, "EXEC "+exec+" N'SELECT CAST(used_log_space_in_percent AS float) FROM sys.dm_db_log_space_usage'").Scan(&logPct); err == nil && logPct.Valid {
+		for _, row := range data {
+			if row[1] == "LOG" {
+				row[6] = fmt.Sprintf("%.1f", logPct.Float64)
+			}
+		}
+	}
+	return data, nil
+}
+
+// dbinfoQuery 数据库属性和最近的备份时间，从未备份时显示 never
+const dbinfoQuery = `SELECT d.name AS [database], SUSER_SNAME(d.owner_sid) AS [owner], d.state_desc AS [state],
+	d.recovery_model_desc AS [recovery_model], d.compatibility_level, d.collation_name AS [collation],
+	d.containment_desc AS [containment], d.snapshot_isolation_state_desc AS [snapshot_isolation],
+	CASE d.is_read_committed_snapshot_on WHEN 1 THEN 'ON' ELSE 'OFF' END AS [read_committed_snapshot],
+	CASE d.is_auto_shrink_on WHEN 1 THEN 'ON' ELSE 'OFF' END AS [auto_shrink],
+	CASE d.is_auto_create_stats_on WHEN 1 THEN 'ON' ELSE 'OFF' END AS [auto_create_stats],
+	CASE d.is_auto_update_stats_on WHEN 1 THEN 'ON' ELSE 'OFF' END AS [auto_update_stats],
+	CASE d.is_auto_update_stats_async_on WHEN 1 THEN 'ON' ELSE 'OFF' END AS [auto_update_stats_async],
+	d.log_reuse_wait_desc AS [log_reuse_wait], d.create_date AS [created],
+	COALESCE(CONVERT(varchar(19), b.last_full, 120), 'never') AS [last_full_backup],
+	COALESCE(CONVERT(varchar(19), b.last_diff, 120), 'never') AS [last_diff_backup],
+	COALESCE(CONVERT(varchar(19), b.last_log, 120), 'never') AS [last_log_backup]
+FROM sys.databases AS d
+OUTER APPLY (SELECT MAX(CASE bs.type WHEN 'D' THEN bs.backup_finish_date END) AS last_full,
+		MAX(CASE bs.type WHEN 'I' THEN bs.backup_finish_date END) AS last_diff,
+		MAX(CASE bs.type WHEN 'L' THEN bs.backup_finish_date END) AS last_log
+	FROM msdb.dbo.backupset AS bs WHERE bs.database_name = d.name) AS b
+WHERE d.name = @name`
+
+// showDatabaseInfo 处理 dbinfo [database] 命令，以纵向布局显示数据库的所有者、恢复模式、兼容级别、排序规则、
+// 快照隔离设置、自动收缩和统计信息选项、日志重用等待原因以及最近的完整、差异和日志备份时间
+func (c *CLI) showDatabaseInfo(arg string) {
+	_, rest := catalogArgs(arg)
+	if len(rest) > 1 {
+		fmt.Fprintf(c.term, "Usage: dbinfo [database]\n")
+		return
+	}
+	name := c.database
+	if len(rest) == 1 {
+		name = rest[0]
+	}
+
+	startTime := time.Now()
+	ctx, cancel := c.statementContext()
+	defer cancel()
+	defer c.flushOutput()
+
+	var exists bool
+	if err := c.conn.QueryRowContext(ctx, "SELECT CASE WHEN DB_ID(@name) IS NULL THEN 0 ELSE 1 END",
+		sql.Named("name", name)).Scan(&exists); err != nil {
+		c.printError(err)
+		return
+	}
+	if !exists {
+		c.printError(fmt.Errorf("database '%s' does not exist", name))
+		return
+	}
+	c.executeQuery(ctx, dbinfoQuery, startTime, true, sql.Named("name", name))
+}
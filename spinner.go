@@ -0,0 +1,60 @@
+package mssql
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// spinnerDelay 语句执行超过这个时间仍未返回时才显示等待状态，快速的查询不受影响
+const spinnerDelay = 2 * time.Second
+
+// startSpinner 在终端上显示语句已执行的时间（Running... 00:01:23），每秒用 \r 原地刷新；
+// 返回的函数停止显示并清除该行，必须在输出结果之前调用。输出不是终端或安静模式时不显示
+func (c *CLI) startSpinner(startTime time.Time) (stop func()) {
+	if c.display.quiet || c.nonInteractive || !isTerminal(c.term) {
+		return func() {}
+	}
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		timer := time.NewTimer(spinnerDelay - time.Since(startTime))
+		defer timer.Stop()
+		select {
+		case <-done:
+			return
+		case <-timer.C:
+		}
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			elapsed := time.Since(startTime)
+			c.outMu.Lock()
+			fmt.Fprintf(c.term, "\rRunning... %02d:%02d:%02d", int(elapsed.Hours()), int(elapsed.Minutes())%60, int(elapsed.Seconds())%60)
+			c.spinnerShown = true
+			c.outMu.Unlock()
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		wg.Wait()
+		c.outMu.Lock()
+		defer c.outMu.Unlock()
+		c.clearSpinner()
+	}
+}
+
+// clearSpinner 清除终端上的等待状态行，调用方须持有 outMu
+func (c *CLI) clearSpinner() {
+	if c.spinnerShown {
+		fmt.Fprint(c.term, "\r\033[K")
+		c.spinnerShown = false
+	}
+}
@@ -0,0 +1,226 @@
+package mssql
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// statsSinkKey 在 context 中保存接收 STATISTICS IO / TIME 消息的函数，设置后这些消息不直接输出
+type statsSinkKey struct{}
+
+// statsIOPattern 匹配 STATISTICS IO 的消息：Table 'name'. Scan count 1, logical reads 3, ...
+var statsIOPattern = regexp.MustCompile(`^Table '(.+)'\. (.*?)\.?$`)
+
+// statsCounterPattern 匹配 STATISTICS IO 消息中的一项计数
+var statsCounterPattern = regexp.MustCompile(`^([A-Za-z][A-Za-z -]*?) (\d+)$`)
+
+// statsTimePattern 匹配 STATISTICS TIME 的消息，第一组区分编译和执行
+var statsTimePattern = regexp.MustCompile(`(?s)(parse and compile time|Execution Times).*?CPU time = (\d+) ms,\s*elapsed time = (\d+) ms`)
+
+// isStatisticsMessage 判断服务器消息是否来自 STATISTICS IO 或 STATISTICS TIME
+func isStatisticsMessage(msg string) bool {
+	return strings.HasPrefix(msg, "Table '") && strings.Contains(msg, "Scan count") ||
+		strings.Contains(msg, "SQL Server parse and compile time") || strings.Contains(msg, "SQL Server Execution Times")
+}
+
+// statsCollector 收集一条语句执行期间的统计消息，消息在驱动处理 token 时到达
+type statsCollector struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (s *statsCollector) add(msg string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, msg)
+}
+
+// withStatistics 返回收集统计消息的 context
+func (s *statsCollector) withStatistics(ctx context.Context) context.Context {
+	return context.WithValue(ctx, statsSinkKey{}, s.add)
+}
+
+// statsIORow 一条 STATISTICS IO 消息
+type statsIORow struct {
+	table    string
+	counters map[string]int64
+}
+
+// parseStatsIO 解析 STATISTICS IO 消息，返回按首次出现顺序排列的计数名称；无法解析时 ok 为 false
+func parseStatsIO(msg string, names []string) (row statsIORow, _ []string, ok bool) {
+	m := statsIOPattern.FindStringSubmatch(strings.TrimSpace(msg))
+	if m == nil {
+		return row, names, false
+	}
+	row = statsIORow{table: m[1], counters: make(map[string]int64)}
+	for _, part := range strings.Split(m[2], ",") {
+		c := statsCounterPattern.FindStringSubmatch(strings.TrimSpace(part))
+		if c == nil {
+			return row, names, false
+		}
+		n, err := strconv.ParseInt(c[2], 10, 64)
+		if err != nil {
+			return row, names, false
+		}
+		if _, seen := row.counters[c[1]]; !seen && !containsString(names, c[1]) {
+			names = append(names, c[1])
+		}
+		row.counters[c[1]] += n
+	}
+	return row, names, true
+}
+
+// containsString 判断切片中是否包含指定字符串
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// printStatistics 将收集到的统计消息整理为表格输出：每条 IO 消息一行，只列出 scan count、logical reads
+// 和有非零值的计数，多于一行时附加合计；CPU 和耗时按编译和执行分别合计。无法解析的消息按原文输出
+func (c *CLI) printStatistics(s *statsCollector) {
+	s.mu.Lock()
+	messages := s.messages
+	s.messages = nil
+	s.mu.Unlock()
+	if len(messages) == 0 || c.display.quiet {
+		return
+	}
+
+	var ioRows []statsIORow
+	var names, raw []string
+	var times [2][2]int64 // [编译, 执行][CPU, 耗时]
+	hasTime := false
+	for _, msg := range messages {
+		if strings.HasPrefix(strings.TrimSpace(msg), "Table '") {
+			row, updated, ok := parseStatsIO(msg, names)
+			if ok {
+				ioRows = append(ioRows, row)
+				names = updated
+				continue
+			}
+		} else if m := statsTimePattern.FindStringSubmatch(msg); m != nil {
+			phase := 1
+			if m[1] == "parse and compile time" {
+				phase = 0
+			}
+			cpu, _ := strconv.ParseInt(m[2], 10, 64)
+			elapsed, _ := strconv.ParseInt(m[3], 10, 64)
+			times[phase][0] += cpu
+			times[phase][1] += elapsed
+			hasTime = true
+			continue
+		}
+		raw = append(raw, msg)
+	}
+
+	c.outMu.Lock()
+	defer c.outMu.Unlock()
+	if len(ioRows) > 0 {
+		var cols []string
+		for _, name := range names {
+			keep := name == "Scan count" || name == "logical reads"
+			for _, row := range ioRows {
+				keep = keep || row.counters[name] != 0
+			}
+			if keep {
+				cols = append(cols, name)
+			}
+		}
+		total := make([]int64, len(cols))
+		header := []string{"Table"}
+		for _, name := range cols {
+			header = append(header, strings.ToUpper(name[:1])+name[1:])
+		}
+		table := [][]string{header}
+		for _, row := range ioRows {
+			line := []string{row.table}
+			for i, name := range cols {
+				line = append(line, groupDigits(strconv.FormatInt(row.counters[name], 10)))
+				total[i] += row.counters[name]
+			}
+			table = append(table, line)
+		}
+		if len(ioRows) > 1 {
+			line := []string{"Total"}
+			for _, n := range total {
+				line = append(line, groupDigits(strconv.FormatInt(n, 10)))
+			}
+			table = append(table, line)
+		}
+		c.writeAligned(c.out, table)
+	}
+	if hasTime {
+		c.writeAligned(c.out, [][]string{
+			{"Time", "CPU ms", "Elapsed ms"},
+			{"Parse and compile", groupDigits(strconv.FormatInt(times[0][0], 10)), groupDigits(strconv.FormatInt(times[0][1], 10))},
+			{"Execution", groupDigits(strconv.FormatInt(times[1][0], 10)), groupDigits(strconv.FormatInt(times[1][1], 10))},
+		})
+	}
+	for _, msg := range raw {
+		fmt.Fprintf(c.out, "%s\n", c.display.colorize("-- "+msg, ansiDim))
+	}
+	fmt.Fprintf(c.out, "\n")
+}
+
+// writeAligned 输出对齐的表格，第一行为表头，第一列左对齐，其余列右对齐
+func (c *CLI) writeAligned(w io.Writer, table [][]string) {
+	widths := make([]int, len(table[0]))
+	for _, row := range table {
+		for i, cell := range row {
+			widths[i] = max(widths[i], utf8.RuneCountInString(cell))
+		}
+	}
+	for r, row := range table {
+		var b strings.Builder
+		for i, cell := range row {
+			pad := strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell))
+			if i == 0 {
+				b.WriteString(cell + pad)
+			} else {
+				b.WriteString("  " + pad + cell)
+			}
+		}
+		line := b.String()
+		if r == 0 {
+			line = c.display.colorize(line, ansiDim)
+		}
+		fmt.Fprintf(w, "%s\n", line)
+	}
+}
+
+// setStats 处理 stats on|off 命令，打开或关闭会话的 STATISTICS IO 和 STATISTICS TIME，
+// 每条语句的结果之后以表格显示各表的读取次数和 CPU、耗时
+func (c *CLI) setStats(arg string) {
+	switch strings.ToLower(arg) {
+	case "":
+		fmt.Fprintf(c.term, "Statistics: %s\n", onOff(c.statsEnabled))
+		return
+	case "on", "off":
+	default:
+		fmt.Fprintf(c.term, "Usage: stats on|off\n")
+		return
+	}
+	value := strings.ToUpper(arg)
+	for _, option := range []string{"IO", "TIME"} {
+		stmt := "SET STATISTICS " + option + " " + value
+		if _, err := c.conn.ExecContext(context.Background(), stmt); err != nil {
+			c.printError(err)
+			return
+		}
+		// 重新连接后恢复
+		c.trackSession(stmt)
+	}
+	c.statsEnabled = value == "ON"
+	fmt.Fprintf(c.term, "Statistics: %s\n", onOff(c.statsEnabled))
+}
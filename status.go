@@ -0,0 +1,85 @@
+package mssql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// sessionStatus 一次查询取得的服务器端会话状态
+type sessionStatus struct {
+	database  string
+	spid      int
+	tranCount int
+	login     string
+	program   string
+	host      string
+}
+
+// fetchSessionStatus 在会话连接上查询当前数据库、SPID、事务数以及服务器看到的应用名称和主机名
+func (c *CLI) fetchSessionStatus() (sessionStatus, error) {
+	var s sessionStatus
+	if c.conn == nil {
+		return s, fmt.Errorf("not connected")
+	}
+	err := c.conn.QueryRowContext(context.Background(), `SELECT DB_NAME(), @@SPID, @@TRANCOUNT, SUSER_SNAME(),
+	ISNULL(s.program_name, ''), ISNULL(s.host_name, '')
+FROM (SELECT 1 AS x) AS one
+LEFT JOIN sys.dm_exec_sessions AS s ON s.session_id = @@SPID`).Scan(
+		&s.database, &s.spid, &s.tranCount, &s.login, &s.program, &s.host)
+	return s, err
+}
+
+// showStatus 显示连接和会话信息：服务器信息使用连接时缓存的值，会话状态在一次查询中取得，
+// 查询失败时仍显示客户端的设置
+func (c *CLI) showStatus() {
+	fmt.Fprintf(c.term, "Server:       %s\n", c.serverAddress())
+	if c.serverInfo.ServerName != "" {
+		fmt.Fprintf(c.term, "Server name:  %s\n", c.serverInfo.ServerName)
+	}
+	if version, _, _ := strings.Cut(c.serverInfo.Version, "\n"); version != "" {
+		fmt.Fprintf(c.term, "Version:      %s\n", strings.TrimSpace(version))
+	}
+	if c.serverInfo.Edition != "" {
+		fmt.Fprintf(c.term, "Edition:      %s %s\n", c.serverInfo.Edition, c.serverInfo.ProductLevel)
+	}
+	if c.config.ReadOnly {
+		fmt.Fprintf(c.term, "Intent:       read-only\n")
+	}
+	if c.dac {
+		fmt.Fprintf(c.term, "Connection:   dedicated admin (port %d)\n", c.dacPort)
+	}
+	if c.serverInfo.Encrypted != "" {
+		fmt.Fprintf(c.term, "Encrypted:    %s\n", c.serverInfo.Encrypted)
+	}
+
+	login := c.username
+	if auth := c.authDescription(); auth != "" {
+		login = auth
+	}
+	s, err := c.fetchSessionStatus()
+	if err != nil {
+		s = sessionStatus{database: c.database, login: c.serverInfo.Login, program: c.appName(), host: c.workstationID()}
+	}
+	fmt.Fprintf(c.term, "Login:        %s (%s)\n", s.login, login)
+	fmt.Fprintf(c.term, "Database:     %s\n", s.database)
+	if err == nil {
+		fmt.Fprintf(c.term, "SPID:         %d\n", s.spid)
+		fmt.Fprintf(c.term, "Transactions: %d open\n", s.tranCount)
+	}
+	if !c.connectedAt.IsZero() {
+		fmt.Fprintf(c.term, "Uptime:       %s\n", time.Since(c.connectedAt).Round(time.Second))
+	}
+
+	fmt.Fprintf(c.term, "Timeout:      %s\n", c.timeoutText())
+	fmt.Fprintf(c.term, "Timing:       %s\n", onOff(c.timingEnabled))
+	fmt.Fprintf(c.term, "Format:       %s\n", c.outputFormat)
+	fmt.Fprintf(c.term, "Max rows:     %d\n", c.maxRows)
+	fmt.Fprintf(c.term, "Program name: %s\n", s.program)
+	fmt.Fprintf(c.term, "Host name:    %s\n", s.host)
+	if err != nil {
+		fmt.Fprintf(c.term, "(session details unavailable: %v)\n", err)
+	}
+	fmt.Fprintf(c.term, "\n")
+}
@@ -0,0 +1,231 @@
+package mssql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// target 描述当前连接的目标，切换服务器失败时用于恢复
+type target struct {
+	config   Config
+	host     string
+	port     int
+	username string
+	password string
+	database string
+	instance string
+	dac      bool
+	dacPort  int
+}
+
+// currentTarget 返回当前连接的目标
+func (c *CLI) currentTarget() target {
+	return target{
+		config:   c.config,
+		host:     c.host,
+		port:     c.port,
+		username: c.username,
+		password: c.password,
+		database: c.database,
+		instance: c.instance,
+		dac:      c.dac,
+		dacPort:  c.dacPort,
+	}
+}
+
+// setTarget 恢复连接目标
+func (c *CLI) setTarget(t target) {
+	c.config = t.config
+	c.host, c.port = t.host, t.port
+	c.username, c.password = t.username, t.password
+	c.database, c.instance = t.database, t.instance
+	c.dac, c.dacPort = t.dac, t.dacPort
+}
+
+// setConnect 处理 connect <host[,port]> [user] [database] 命令，连接到新的服务器
+// 指定用户时提示输入密码；新连接失败时保留原来的连接
+func (c *CLI) setConnect(arg string) {
+	fields := strings.Fields(arg)
+	if len(fields) == 0 || len(fields) > 3 {
+		fmt.Fprintf(c.term, "Usage: connect <host[,port]> [user] [database]\n")
+		return
+	}
+
+	config := c.config
+	config.ConnectionString = ""
+	config.Instance = ""
+	config.FailoverPartner, config.FailoverPort = "", 0
+	config.Username, config.Password = c.username, c.password
+	config.Database = ""
+
+	host, portText, ok := strings.Cut(fields[0], ",")
+	config.Host, config.Port = host, 0
+	if ok {
+		port, err := strconv.Atoi(strings.TrimSpace(portText))
+		if err != nil || port <= 0 || port > 65535 {
+			fmt.Fprintf(c.term, "Invalid port '%s'\n", portText)
+			return
+		}
+		config.Port = port
+	}
+	if len(fields) > 1 {
+		config.Username = fields[1]
+		password, err := c.reader.ReadPassword("Password: ")
+		if err != nil {
+			fmt.Fprintf(c.term, "-- connect cancelled\n")
+			return
+		}
+		config.Password = password
+	}
+	if len(fields) > 2 {
+		config.Database = fields[2]
+	}
+
+	if !c.resolveOpenTransaction() {
+		return
+	}
+	if err := c.switchTarget(&config); err != nil {
+		c.printError(err)
+		fmt.Fprintf(c.term, "Still connected to %s\n", c.serverAddress())
+		return
+	}
+	c.showWelcome()
+}
+
+// switchTarget 连接到配置描述的新目标，成功后替换当前连接，失败时恢复原来的目标
+func (c *CLI) switchTarget(config *Config) error {
+	old := c.currentTarget()
+
+	c.config = *config
+	c.host, c.port = config.Host, config.Port
+	c.username, c.password = config.Username, config.Password
+	c.database, c.instance = config.Database, config.Instance
+	c.host, c.dac = parseAdminHost(c.host)
+	c.dac = c.dac || config.DAC
+	if host, instance, ok := strings.Cut(c.host, `\`); ok && c.instance == "" {
+		c.host, c.instance = host, instance
+	}
+	if c.isLocalDB() && c.instance == "" {
+		c.instance = defaultLocalDBInstance
+	}
+
+	err := c.openTarget()
+	if err != nil {
+		c.setTarget(old)
+		return err
+	}
+
+	if c.dac {
+		c.applyDACDefaults()
+	}
+	c.serverInfo = ServerInfo{}
+	c.sessionSets = nil
+	c.fetchServerInfo()
+	if c.database == "" {
+		c.conn.QueryRowContext(context.Background(), "SELECT DB_NAME()").Scan(&c.database)
+	}
+	return nil
+}
+
+// openTarget 检查当前目标的选项并打开连接
+func (c *CLI) openTarget() error {
+	for _, check := range []func() error{c.checkEncrypt, c.checkReadOnly, c.checkLocalDB} {
+		if err := check(); err != nil {
+			return err
+		}
+	}
+	if c.dac {
+		port, err := c.resolveDACPort()
+		if err != nil {
+			return err
+		}
+		c.dacPort = port
+	}
+	return c.openDB(context.Background())
+}
+
+// reconnectCommand 处理 reconnect 命令，重新连接当前目标并恢复会话状态
+func (c *CLI) reconnectCommand() {
+	if !c.resolveOpenTransaction() {
+		return
+	}
+	if c.dac {
+		// 每个实例只允许一个 DAC 会话，先释放原来的连接
+		c.closeDB()
+	}
+	if err := c.openDB(context.Background()); err != nil {
+		c.printError(err)
+		return
+	}
+	if err := c.restoreSession(); err != nil {
+		c.printError(err)
+	}
+	c.fetchServerInfo()
+	fmt.Fprintf(c.term, "Reconnected to %s\n", c.serverAddress())
+}
+
+// openTransactions 返回当前会话中未提交的事务数，连接不可用时返回 0
+func (c *CLI) openTransactions() int {
+	if c.conn == nil {
+		return 0
+	}
+	var count int
+	if err := c.conn.QueryRowContext(context.Background(), "SELECT @@TRANCOUNT").Scan(&count); err != nil {
+		return 0
+	}
+	return count
+}
+
+// resolveOpenTransaction 当前会话有未提交的事务时询问提交、回滚还是取消，返回是否继续原来的操作；
+// 非交互执行时无法询问，按取消处理，事务保持不变
+func (c *CLI) resolveOpenTransaction() bool {
+	if c.openTransactions() == 0 {
+		return true
+	}
+	if c.nonInteractive {
+		c.printError(errors.New("there is an open transaction; COMMIT or ROLLBACK it before this command"))
+		return false
+	}
+	c.reader.SetPrompt("You have an open transaction. Commit, rollback, or cancel? [c/r/x] ")
+	line, err := c.reader.ReadLine()
+	if err != nil {
+		return false
+	}
+	var stmt string
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "c", "commit":
+		stmt = "WHILE @@TRANCOUNT > 0 COMMIT TRANSACTION"
+	case "r", "rollback":
+		stmt = "IF @@TRANCOUNT > 0 ROLLBACK TRANSACTION"
+	default:
+		return false
+	}
+	_, err = c.conn.ExecContext(context.Background(), stmt)
+	c.tranCount = c.openTransactions()
+	if err != nil {
+		c.printError(err)
+		return false
+	}
+	return true
+}
+
+// confirm 显示问题并读取 y/N 回答
+func (c *CLI) confirm(question string) bool {
+	// 非交互执行时无法确认，按取消处理
+	if c.nonInteractive {
+		return false
+	}
+	c.reader.SetPrompt(question + " [y/N] ")
+	line, err := c.reader.ReadLine()
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,37 @@
+package mssql
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+)
+
+func TestResolveOpenTransactionNonInteractive(t *testing.T) {
+	term := &testTerm{}
+	c := NewCLIWithConfig(term, &Config{Host: "db1", Username: "sa", Password: "x"})
+	// SELECT @@TRANCOUNT 返回 1
+	fc := connectFake(t, c, []fakeColumn{{name: "", typeName: "INT"}}, []driver.Value{int64(1)})
+	c.nonInteractive = true
+	c.reader = nil // 不能读取输入
+
+	if c.resolveOpenTransaction() {
+		t.Fatal("resolveOpenTransaction() = true with an open transaction in a script")
+	}
+	if len(fc.execs) != 0 {
+		t.Errorf("executed %q, the transaction should be left alone", fc.execs)
+	}
+	if !strings.Contains(term.String(), "open transaction") {
+		t.Errorf("output %q does not explain why the command was cancelled", term.String())
+	}
+}
+
+func TestResolveOpenTransactionNoTransaction(t *testing.T) {
+	c := NewCLIWithConfig(&testTerm{}, &Config{Host: "db1", Username: "sa", Password: "x"})
+	connectFake(t, c, []fakeColumn{{name: "", typeName: "INT"}}, []driver.Value{int64(0)})
+	c.nonInteractive = true
+	c.reader = nil
+
+	if !c.resolveOpenTransaction() {
+		t.Fatal("resolveOpenTransaction() = false without an open transaction")
+	}
+}
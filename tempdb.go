@@ -0,0 +1,141 @@
+package mssql
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// tempTablesQuery 列出 tempdb 中的临时表：本地临时表的名称被补齐下划线并附加 12 位后缀，
+// 还原出原名后用 OBJECT_ID 判断是否属于当前会话；全局临时表对所有会话可见
+var tempTablesQuery = `SELECT n.name, CASE WHEN t.name LIKE '##%' THEN 'global' ELSE 'local' END,
+	COALESCE(p.row_count, 0), COALESCE(p.reserved_kb, 0), t.create_date,
+	c.name + ' ' + ` + sqlTypeExpr("c.user_type_id", "c.max_length", "c.precision", "c.scale") + `
+FROM tempdb.sys.tables AS t
+CROSS APPLY (SELECT CASE WHEN t.name LIKE '##%' OR LEN(t.name) <= 12 THEN t.name
+	ELSE LEFT(t.name, LEN(t.name) - 12 - PATINDEX('%[^_]%', REVERSE(LEFT(t.name, LEN(t.name) - 12))) + 1) END AS name) AS n
+OUTER APPLY (SELECT SUM(CASE WHEN ps.index_id < 2 THEN ps.row_count END) AS row_count,
+		SUM(ps.reserved_page_count) * 8 AS reserved_kb
+	FROM tempdb.sys.dm_db_partition_stats AS ps WHERE ps.object_id = t.object_id) AS p
+JOIN tempdb.sys.columns AS c ON c.object_id = t.object_id
+WHERE t.name LIKE '##%' OR (t.name LIKE '#%' AND OBJECT_ID('tempdb..' + QUOTENAME(n.name)) = t.object_id)
+ORDER BY 2 DESC, 1, c.column_id`
+
+// listTempTables 处理 temptables 命令，列出当前会话创建的本地临时表及全局临时表的列、行数和大小；
+// 本地临时表只存在于创建它的连接上，CLI 的所有语句都在同一个会话连接上执行
+func (c *CLI) listTempTables(arg string) {
+	if arg != "" {
+		fmt.Fprintf(c.term, "Usage: temptables\n")
+		return
+	}
+	startTime := time.Now()
+	ctx, cancel := c.statementContext()
+	defer cancel()
+	defer c.flushOutput()
+
+	data, err := c.queryValues(ctx, tempTablesQuery)
+	if err != nil {
+		c.printError(err)
+		return
+	}
+	var local, global [][]interface{}
+	for _, row := range aggregateLast(data) {
+		kind := row[1]
+		if size, ok := row[3].(int64); ok {
+			row[3] = formatSize(size)
+		}
+		row = append(row[:1], row[2:]...)
+		if kind == "global" {
+			global = append(global, row)
+		} else {
+			local = append(local, row)
+		}
+	}
+	cols := []string{"name", "rows", "reserved", "created", "columns"}
+	c.section("Local temporary tables (this session)")
+	c.renderValues(cols, local, startTime)
+	c.section("Global temporary tables")
+	c.renderValues(cols, global, startTime)
+}
+
+// tempdbReport tempdb 命令的各个部分：查询返回的页数列在客户端换算为大小
+var tempdbReport = []struct {
+	title    string
+	cols     []string
+	pageCols []int
+	query    string
+}{
+	{
+		title:    "Files",
+		cols:     []string{"name", "type", "size", "free", "user_objects", "internal_objects", "version_store"},
+		pageCols: []int{2, 3, 4, 5, 6},
+		query: `SELECT f.name, f.type_desc, CAST(f.size AS bigint), u.unallocated_extent_page_count,
+	u.user_object_reserved_page_count, u.internal_object_reserved_page_count, u.version_store_reserved_page_count
+FROM tempdb.sys.database_files AS f
+LEFT JOIN tempdb.sys.dm_db_file_space_usage AS u ON u.file_id = f.file_id
+ORDER BY f.file_id`,
+	},
+	{
+		title:    "Usage",
+		cols:     []string{"user_objects", "internal_objects", "version_store", "mixed_extents", "free"},
+		pageCols: []int{0, 1, 2, 3, 4},
+		query: `SELECT SUM(user_object_reserved_page_count), SUM(internal_object_reserved_page_count),
+	SUM(version_store_reserved_page_count), SUM(mixed_extent_page_count), SUM(unallocated_extent_page_count)
+FROM tempdb.sys.dm_db_file_space_usage`,
+	},
+	{
+		title:    "Top sessions by tempdb allocation",
+		cols:     []string{"spid", "login", "program", "user_objects", "internal_objects", "total"},
+		pageCols: []int{3, 4, 5},
+		query: `WITH alloc AS (
+	SELECT session_id, user_objects_alloc_page_count - user_objects_dealloc_page_count AS user_pages,
+		internal_objects_alloc_page_count - internal_objects_dealloc_page_count AS internal_pages
+	FROM sys.dm_db_session_space_usage
+	UNION ALL
+	SELECT session_id, user_objects_alloc_page_count - user_objects_dealloc_page_count,
+		internal_objects_alloc_page_count - internal_objects_dealloc_page_count
+	FROM sys.dm_db_task_space_usage
+)
+SELECT TOP 10 a.session_id, s.login_name, s.program_name,
+	SUM(a.user_pages), SUM(a.internal_pages), SUM(a.user_pages + a.internal_pages)
+FROM alloc AS a
+JOIN sys.dm_exec_sessions AS s ON s.session_id = a.session_id
+GROUP BY a.session_id, s.login_name, s.program_name
+HAVING SUM(a.user_pages + a.internal_pages) > 0
+ORDER BY SUM(a.user_pages + a.internal_pages) DESC`,
+	},
+}
+
+// showTempdb 处理 tempdb 命令，报告 tempdb 的文件大小和可用空间、用户对象、内部对象和版本存储的占用，
+// 以及分配 tempdb 最多的会话；某部分因权限不足失败时跳过并在最后说明，其余部分照常输出
+func (c *CLI) showTempdb(arg string) {
+	if arg != "" {
+		fmt.Fprintf(c.term, "Usage: tempdb\n")
+		return
+	}
+	ctx, cancel := c.statementContext()
+	defer cancel()
+	defer c.flushOutput()
+
+	var notes []string
+	for _, part := range tempdbReport {
+		startTime := time.Now()
+		data, err := c.queryValues(ctx, part.query)
+		if err != nil {
+			notes = append(notes, fmt.Sprintf("%s: %v", part.title, err))
+			continue
+		}
+		for _, row := range data {
+			for _, i := range part.pageCols {
+				if pages, ok := row[i].(int64); ok {
+					row[i] = formatSize(pages * 8)
+				}
+			}
+		}
+		c.section(part.title)
+		c.renderValues(part.cols, data, startTime)
+	}
+	if len(notes) > 0 {
+		fmt.Fprintf(c.out, "%s\n\n", c.display.colorize("Partial report, not available:\n  "+strings.Join(notes, "\n  "), ansiDim))
+	}
+}
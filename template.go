@@ -0,0 +1,117 @@
+package mssql
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// templateFuncs 行模板中可用的辅助函数
+var templateFuncs = template.FuncMap{
+	"quote": func(v interface{}) string {
+		return strconv.Quote(templateString(v))
+	},
+	"csv": func(vals ...interface{}) string {
+		record := make([]string, len(vals))
+		for i, v := range vals {
+			record[i] = templateString(v)
+		}
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		w.Write(record)
+		w.Flush()
+		return strings.TrimSuffix(buf.String(), "\n")
+	},
+	"default": func(def, v interface{}) interface{} {
+		if v == nil || templateString(v) == "" {
+			return def
+		}
+		return v
+	},
+}
+
+// templateString 将模板中的值转换为字符串，NULL 为空串
+func templateString(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// parseRowTemplate 解析行模板，参数为已存在的文件路径时读取文件，否则作为内联模板
+func parseRowTemplate(arg string) (*template.Template, error) {
+	text := arg
+	if info, err := os.Stat(arg); err == nil && info.Mode().IsRegular() {
+		data, err := os.ReadFile(arg)
+		if err != nil {
+			return nil, err
+		}
+		text = string(data)
+	} else if !strings.HasSuffix(text, "\n") {
+		// 内联模板每行结果自动换行
+		text += "\n"
+	}
+	return template.New("row").Funcs(templateFuncs).Option("missingkey=zero").Parse(text)
+}
+
+// templateFormatter 对每一行结果执行用户模板，列可通过列名访问，无名列使用 colN
+type templateFormatter struct {
+	displayBase
+	w    io.Writer
+	tmpl *template.Template
+	keys []string
+}
+
+// newTemplateFormatter 创建模板格式，模板解析错误在设置格式时立即返回
+func newTemplateFormatter(w io.Writer, arg string) (Formatter, error) {
+	if arg == "" {
+		return nil, fmt.Errorf("usage: format template <file-or-inline-template>")
+	}
+	tmpl, err := parseRowTemplate(arg)
+	if err != nil {
+		return nil, err
+	}
+	return &templateFormatter{w: w, tmpl: tmpl}, nil
+}
+
+func (f *templateFormatter) BeginResult(cols []string, colTypes []*sql.ColumnType) error {
+	f.setTypes(colTypes)
+	f.keys = make([]string, len(cols))
+	for i, col := range cols {
+		if col == "" {
+			f.keys[i] = "col" + strconv.Itoa(i+1)
+		} else {
+			f.keys[i] = col
+		}
+	}
+	return nil
+}
+
+func (f *templateFormatter) WriteRow(values []interface{}) error {
+	data := make(map[string]interface{}, len(values))
+	for i, v := range values {
+		if v == nil {
+			data[f.keys[i]] = nil
+		} else {
+			data[f.keys[i]] = f.cell(i, v)
+		}
+	}
+
+	// 先渲染到缓冲区，出错时不输出半行内容
+	var buf bytes.Buffer
+	if err := f.tmpl.Execute(&buf, data); err != nil {
+		return fmt.Errorf("template: %v", err)
+	}
+	_, err := f.w.Write(buf.Bytes())
+	return err
+}
+
+func (f *templateFormatter) EndResult(summary ResultSummary) error {
+	return nil
+}
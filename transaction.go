@@ -0,0 +1,66 @@
+package mssql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// autocommitEnabled 判断会话是否处于自动提交模式，即 IMPLICIT_TRANSACTIONS 未开启（@@OPTIONS 第 2 位）
+func (c *CLI) autocommitEnabled() bool {
+	var options int
+	if err := c.conn.QueryRowContext(context.Background(), "SELECT @@OPTIONS").Scan(&options); err != nil {
+		return true
+	}
+	return options&2 == 0
+}
+
+// setAutocommit 处理 autocommit on|off 命令：off 开启 IMPLICIT_TRANSACTIONS，之后每条 DML 语句都加入
+// 一个需要显式 COMMIT 的事务；切回 on 之前必须先提交或回滚未完成的事务
+func (c *CLI) setAutocommit(arg string) {
+	var stmt string
+	switch strings.ToLower(arg) {
+	case "":
+		fmt.Fprintf(c.term, "Autocommit: %s\n", onOff(c.autocommitEnabled()))
+		return
+	case "on":
+		if !c.resolveOpenTransaction() {
+			return
+		}
+		stmt = "SET IMPLICIT_TRANSACTIONS OFF"
+	case "off":
+		stmt = "SET IMPLICIT_TRANSACTIONS ON"
+	default:
+		fmt.Fprintf(c.term, "Usage: autocommit on|off\n")
+		return
+	}
+	if _, err := c.conn.ExecContext(context.Background(), stmt); err != nil {
+		c.printError(err)
+		return
+	}
+	// 作为会话选项记录，重新连接后重放
+	c.trackSession(stmt)
+	fmt.Fprintf(c.term, "Autocommit %s\n", strings.ToLower(arg))
+}
+
+// endTransaction 处理 commit 和 rollback 命令，结束当前事务并报告剩余的嵌套层数
+func (c *CLI) endTransaction(commit bool) {
+	if c.openTransactions() == 0 {
+		fmt.Fprintf(c.term, "No open transaction\n")
+		return
+	}
+	stmt, done := "ROLLBACK TRANSACTION", "Rolled back"
+	if commit {
+		stmt, done = "COMMIT TRANSACTION", "Committed"
+	}
+	if _, err := c.conn.ExecContext(context.Background(), stmt); err != nil {
+		c.printError(err)
+		return
+	}
+	c.tranCount = c.openTransactions()
+	if c.tranCount > 0 {
+		fmt.Fprintf(c.term, "%s (%d transaction level(s) still open)\n", done, c.tranCount)
+		return
+	}
+	fmt.Fprintf(c.term, "%s\n", done)
+}
@@ -0,0 +1,82 @@
+package mssql
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// triggerColumns triggers 命令输出的列
+var triggerColumns = []string{"name", "parent", "timing", "status", "created", "modified", "events"}
+
+// triggersFilter 选择触发器的条件：@id 为 NULL 时列出所有 DML 触发器和数据库级 DDL 触发器
+const triggersFilter = `t.is_ms_shipped = 0 AND (@id IS NULL OR (t.parent_class = 1 AND t.parent_id = @id))`
+
+// listTriggers 处理 triggers [-d] [table] 命令，列出表上的 DML 触发器，不指定表时同时列出数据库级 DDL 触发器；
+// 停用的触发器在结果后给出提示，-d 同时输出触发器定义
+func (c *CLI) listTriggers(arg string) {
+	flags, rest := catalogArgs(arg)
+	if len(rest) > 1 {
+		fmt.Fprintf(c.term, "Usage: triggers [-d] [[schema.]table]\n")
+		return
+	}
+	startTime := time.Now()
+	ctx, cancel := c.statementContext()
+	defer cancel()
+	defer c.flushOutput()
+
+	var id interface{}
+	if len(rest) == 1 {
+		obj, err := c.resolveObject(ctx, rest[0])
+		if err != nil {
+			c.printError(err)
+			return
+		}
+		id = obj.id
+	}
+
+	data, err := c.queryValues(ctx, `SELECT CASE t.parent_class WHEN 0 THEN t.name ELSE OBJECT_SCHEMA_NAME(t.object_id) + '.' + t.name END,
+	CASE t.parent_class WHEN 0 THEN '(database)' ELSE OBJECT_SCHEMA_NAME(t.parent_id) + '.' + OBJECT_NAME(t.parent_id) END,
+	CASE t.is_instead_of_trigger WHEN 1 THEN 'INSTEAD OF' ELSE 'AFTER' END,
+	CASE t.is_disabled WHEN 1 THEN 'DISABLED' ELSE 'enabled' END,
+	t.create_date, t.modify_date, e.type_desc
+FROM sys.triggers AS t
+LEFT JOIN sys.trigger_events AS e ON e.object_id = t.object_id
+WHERE `+triggersFilter+`
+ORDER BY t.parent_class DESC, 1, e.type`, sql.Named("id", id))
+	if err != nil {
+		c.printError(err)
+		return
+	}
+	data = aggregateLast(data)
+	c.renderValues(triggerColumns, data, startTime)
+
+	var disabled []string
+	for _, row := range data {
+		if row[3] == "DISABLED" {
+			disabled = append(disabled, fmt.Sprint(row[0]))
+		}
+	}
+	if len(disabled) > 0 {
+		fmt.Fprintf(c.out, "%s\n\n", c.display.colorize(fmt.Sprintf(
+			"Warning: %d trigger(s) disabled and will not fire: %s", len(disabled), strings.Join(disabled, ", ")), ansiRed))
+	}
+	if !flags["d"] {
+		return
+	}
+
+	definitions, err := c.queryValues(ctx, `SELECT CASE t.parent_class WHEN 0 THEN t.name ELSE OBJECT_SCHEMA_NAME(t.object_id) + '.' + t.name END,
+	m.definition
+FROM sys.triggers AS t
+LEFT JOIN sys.sql_modules AS m ON m.object_id = t.object_id
+WHERE `+triggersFilter+`
+ORDER BY t.parent_class DESC, 1`, sql.Named("id", id))
+	if err != nil {
+		c.printError(err)
+		return
+	}
+	for _, row := range definitions {
+		c.printDefinition(fmt.Sprint(row[0]), row[1], false)
+	}
+}
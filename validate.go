@@ -0,0 +1,147 @@
+package mssql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	mssqldb "github.com/microsoft/go-mssqldb"
+)
+
+// deferredNameNote 提醒 NOEXEC 检查不到延迟名称解析的对象
+const deferredNameNote = "Note: deferred name resolution means references to tables that do not exist yet " +
+	"(including inside procedures, functions and triggers) are not reported."
+
+// validateResult 校验的批处理数和出错的批处理数
+type validateResult struct {
+	batches int
+	failed  int
+}
+
+// validateCommand 处理 validate <statement> 和 validate :r <file> 命令：在 SET NOEXEC ON 下将语句或脚本的每个
+// 批处理发送给服务器，只解析和编译而不执行，报告语法和名称解析错误及其行号。无论是否出错，最后都会关闭 NOEXEC
+func (c *CLI) validateCommand(arg string) {
+	name, path := splitCommand(arg)
+	isFile := name == ":r"
+	if arg == "" || isFile && path == "" {
+		fmt.Fprintf(c.term, "Usage: validate <statement> | validate :r <file>\n")
+		return
+	}
+
+	ctx, cancel := c.statementContext()
+	defer cancel()
+	defer c.flushOutput()
+	if _, err := c.conn.ExecContext(ctx, "SET NOEXEC ON"); err != nil {
+		c.printError(err)
+		return
+	}
+	defer c.setOptionOff("NOEXEC")
+
+	var result validateResult
+	if isFile {
+		if err := c.validateFile(path, &result); err != nil {
+			c.printError(err)
+			return
+		}
+	} else {
+		sqlStr, err := c.expandVariables(arg)
+		if err != nil {
+			c.printError(err)
+			return
+		}
+		c.validateBatch(sqlStr, "", 0, 1, &result)
+	}
+
+	summary := fmt.Sprintf("%d batches OK, %d with errors", result.batches-result.failed, result.failed)
+	color := ansiGreen
+	if result.failed > 0 {
+		color = ansiRed
+	}
+	fmt.Fprintf(c.out, "%s\n", c.display.colorize(summary, color))
+	fmt.Fprintf(c.out, "%s\n\n", c.display.colorize(deferredNameNote, ansiDim))
+}
+
+// validateFile 校验脚本文件中的所有批处理；:setvar 照常执行以便展开变量，:r 递归校验被包含的文件，其余命令忽略
+func (c *CLI) validateFile(arg string, result *validateResult) error {
+	if c.includeDepth >= maxIncludeDepth {
+		return fmt.Errorf(":r nested more than %d levels deep", maxIncludeDepth)
+	}
+	path := c.resolvePath(arg)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	text, err := decodeScript(data)
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	units, err := splitScript(text)
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+
+	c.includeDepth++
+	defer func() { c.includeDepth-- }()
+
+	name := filepath.Base(path)
+	batch := 0
+	for _, u := range units {
+		if u.command {
+			switch cmd, rest := splitCommand(u.text); cmd {
+			case ":r":
+				if err := c.validateFile(rest, result); err != nil {
+					return err
+				}
+			case ":setvar":
+				c.handleSpecialCommand(u.text)
+			}
+			continue
+		}
+		batch++
+		sqlStr, err := c.expandVariables(u.text)
+		if err != nil {
+			result.batches++
+			result.failed++
+			fmt.Fprintf(c.out, "%s\n", c.display.colorize(fmt.Sprintf("%s, batch %d (line %d): %v", name, batch, u.line, err), ansiRed))
+			continue
+		}
+		c.validateBatch(sqlStr, name, batch, u.line, result)
+	}
+	return nil
+}
+
+// validateBatch 在 NOEXEC 下编译一个批处理，错误的行号换算为文件中的行号；name 为空表示单条语句
+func (c *CLI) validateBatch(sqlStr, name string, batch, line int, result *validateResult) {
+	ctx, cancel := c.statementContext()
+	defer cancel()
+
+	result.batches++
+	_, err := c.conn.ExecContext(ctx, sqlStr)
+	if err == nil {
+		return
+	}
+	result.failed++
+
+	where := "Statement"
+	if name != "" {
+		where = fmt.Sprintf("%s, batch %d (line %d)", name, batch, line)
+	}
+	var sqlErr mssqldb.Error
+	if !errors.As(err, &sqlErr) {
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("cancelled after %s", c.timeoutText())
+		}
+		fmt.Fprintf(c.out, "%s\n", c.display.colorize(fmt.Sprintf("%s: %v", where, err), ansiRed))
+		return
+	}
+	all := sqlErr.All
+	if len(all) == 0 {
+		all = []mssqldb.Error{sqlErr}
+	}
+	for _, e := range all {
+		fmt.Fprintf(c.out, "%s\n", c.display.colorize(fmt.Sprintf("%s: Msg %d, Level %d, State %d, Line %d\n  %s",
+			where, e.Number, e.Class, e.State, line+int(e.LineNo)-1, e.Message), ansiRed))
+	}
+}
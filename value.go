@@ -0,0 +1,281 @@
+package mssql
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	mssqldb "github.com/microsoft/go-mssqldb"
+)
+
+// dateLayouts dateformat 命令支持的快捷名称
+var dateLayouts = map[string]string{
+	"iso8601": "2006-01-02T15:04:05.9999999",
+	"full":    "2006-01-02 15:04:05.0000000",
+	"short":   "2006-01-02 15:04:05",
+}
+
+// defaultTimeScale 列类型没有报告小数秒位数时使用的位数，与 time、datetime2 和 datetimeoffset 的默认精度相同
+const defaultTimeScale = 7
+
+// defaultTimeLayout 按列类型返回默认的时间格式，小数秒按列的位数 scale 输出（与 SSMS 一致，保留末尾的 0），
+// scale 为 -1 表示未知；datetime 固定为 3 位
+func defaultTimeLayout(typeName string, scale int) string {
+	if scale < 0 {
+		scale = defaultTimeScale
+	}
+	switch typeName {
+	case "DATE":
+		return "2006-01-02"
+	case "TIME":
+		return "15:04:05" + fractionLayout(scale)
+	case "SMALLDATETIME":
+		return "2006-01-02 15:04:05"
+	case "DATETIME":
+		return "2006-01-02 15:04:05" + fractionLayout(3)
+	case "DATETIMEOFFSET":
+		return "2006-01-02 15:04:05" + fractionLayout(scale) + " -07:00"
+	default:
+		return "2006-01-02 15:04:05" + fractionLayout(scale)
+	}
+}
+
+// fractionLayout 返回 n 位小数秒的格式，如 .000；n 为 0 时不输出小数秒
+func fractionLayout(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return "." + strings.Repeat("0", min(n, 9))
+}
+
+// hasZone 判断时间格式中是否包含时区信息
+func hasZone(layout string) bool {
+	return strings.Contains(layout, "-07") || strings.Contains(layout, "Z07") || strings.Contains(layout, "MST")
+}
+
+// formatTime 按会话设置的格式输出时间，scale 为列的小数秒位数，-1 表示未知
+// datetimeoffset 的值保留其原始偏移量，自定义格式中缺少时区时自动追加
+func (d *displaySettings) formatTime(t time.Time, typeName string, scale int) string {
+	if d == nil || d.dateLayout == "" {
+		return t.Format(defaultTimeLayout(typeName, scale))
+	}
+	layout := d.dateLayout
+	if typeName == "DATETIMEOFFSET" && !hasZone(layout) {
+		layout += " -07:00"
+	}
+	return t.Format(layout)
+}
+
+// formatCell 将非 NULL 值按列类型和显示设置转换为字符串，scale 为时间列的小数秒位数，-1 表示未知
+func (d *displaySettings) formatCell(v interface{}, typeName string, scale int) string {
+	switch val := v.(type) {
+	case time.Time:
+		return d.formatTime(val, typeName, scale)
+	case []byte:
+		if isBinaryType(typeName) {
+			return formatHex(val)
+		}
+		if typeName == "UNIQUEIDENTIFIER" {
+			return formatGUID(val)
+		}
+		return string(val)
+	default:
+		return formatValue(v)
+	}
+}
+
+// numberFormat 数值显示设置，仅用于面向阅读的格式（table、plain、vertical）
+type numberFormat struct {
+	grouping       bool // 千位分隔符
+	moneyDecimals  int  // money/smallmoney 保留的小数位，-1 表示原样显示
+	floatPrecision int  // float/real 的有效数字位数，-1 表示原样显示
+}
+
+// formatNumber 按数值显示设置格式化数值列，非数值列返回 false
+func (d *displaySettings) formatNumber(v interface{}, typeName string) (string, bool) {
+	if d == nil || !isNumericType(typeName) || typeName == "BIT" {
+		return "", false
+	}
+	nf := d.numbers
+
+	var s string
+	switch val := v.(type) {
+	case int64:
+		s = strconv.FormatInt(val, 10)
+	case float64:
+		s = formatFloat(val, 64, nf.floatPrecision)
+	case float32:
+		s = formatFloat(float64(val), 32, nf.floatPrecision)
+	case []byte:
+		s = string(val)
+		if (typeName == "MONEY" || typeName == "SMALLMONEY") && nf.moneyDecimals >= 0 {
+			if r, ok := new(big.Rat).SetString(s); ok {
+				s = r.FloatString(nf.moneyDecimals)
+			}
+		}
+	default:
+		return "", false
+	}
+
+	if nf.grouping {
+		s = groupNumber(s)
+	}
+	return s, true
+}
+
+// formatFloat 按有效数字位数格式化浮点数，precision 为 -1 时使用最短表示
+func formatFloat(f float64, bitSize, precision int) string {
+	if precision < 0 {
+		return strconv.FormatFloat(f, 'g', -1, bitSize)
+	}
+	return strconv.FormatFloat(f, 'g', precision, bitSize)
+}
+
+// groupNumber 为数值字符串的整数部分添加千位分隔符，科学计数法保持不变
+func groupNumber(s string) string {
+	if strings.ContainsAny(s, "eE") {
+		return s
+	}
+	intPart, frac, hasFrac := strings.Cut(s, ".")
+	intPart = groupDigits(intPart)
+	if hasFrac {
+		return intPart + "." + frac
+	}
+	return intPart
+}
+
+// formatHex 将二进制值输出为 0x 开头的大写十六进制
+func formatHex(b []byte) string {
+	return "0x" + strings.ToUpper(hex.EncodeToString(b))
+}
+
+// formatGUID 将 uniqueidentifier 的原始字节转换为 8-4-4-4-12 格式
+// SQL Server 中前三段按小端序存储，与 SSMS 显示保持一致需要调整字节序；长度不是 16 字节时按十六进制输出
+func formatGUID(b []byte) string {
+	var u mssqldb.UniqueIdentifier
+	if err := u.Scan(b); err != nil {
+		return formatHex(b)
+	}
+	return u.String()
+}
+
+// truncateHex 将过长的十六进制值截断到 width 以内并附加字节数，如 0x4D5A... (2,048 bytes)
+func truncateHex(s string, width int) string {
+	suffix := fmt.Sprintf("... (%s bytes)", groupDigits(strconv.Itoa((len(s)-2)/2)))
+	keep := width - len(suffix)
+	// 保留完整的字节
+	keep -= (keep - 2) % 2
+	if keep < 2 {
+		keep = 2
+	}
+	return s[:keep] + suffix
+}
+
+// groupDigits 为整数部分添加千位分隔符
+func groupDigits(s string) string {
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	if len(s) <= 3 {
+		return sign + s
+	}
+	var b strings.Builder
+	head := len(s) % 3
+	if head > 0 {
+		b.WriteString(s[:head])
+	}
+	for i := head; i < len(s); i += 3 {
+		if b.Len() > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(s[i : i+3])
+	}
+	return sign + b.String()
+}
+
+// setDateFormat 设置时间显示格式
+func (c *CLI) setDateFormat(arg string) {
+	switch strings.ToLower(arg) {
+	case "":
+		if c.display.dateLayout == "" {
+			fmt.Fprintf(c.term, "Date format: default\n")
+		} else {
+			fmt.Fprintf(c.term, "Date format: %s\n", c.display.dateLayout)
+		}
+		return
+	case "default":
+		c.display.dateLayout = ""
+		fmt.Fprintf(c.term, "Date format reset to default\n")
+		return
+	}
+
+	layout, ok := dateLayouts[strings.ToLower(arg)]
+	if !ok {
+		layout = arg
+	}
+	c.display.dateLayout = layout
+	fmt.Fprintf(c.term, "Date format set to %s (e.g. %s)\n", layout, time.Now().Format(layout))
+}
+
+// setFullHex 设置是否完整显示二进制值
+func (c *CLI) setFullHex(on bool) {
+	c.display.fullHex = on
+	if on {
+		fmt.Fprintf(c.term, "Binary values will be shown in full\n")
+	} else {
+		fmt.Fprintf(c.term, "Long binary values will be truncated\n")
+	}
+}
+
+// setNumberFormat 设置数值显示格式
+func (c *CLI) setNumberFormat(args string) {
+	fields := strings.Fields(strings.ToLower(args))
+	nf := &c.display.numbers
+
+	switch {
+	case len(fields) == 0:
+	case len(fields) == 1 && fields[0] == "off":
+		*nf = numberFormat{moneyDecimals: -1, floatPrecision: -1}
+	case len(fields) == 2 && fields[0] == "grouping" && (fields[1] == "on" || fields[1] == "off"):
+		nf.grouping = fields[1] == "on"
+	case len(fields) == 2 && (fields[0] == "money" || fields[0] == "float"):
+		n := -1
+		if fields[1] != "off" {
+			var err error
+			n, err = strconv.Atoi(fields[1])
+			if err != nil || n < 0 || (fields[0] == "float" && n == 0) {
+				fmt.Fprintf(c.term, "Invalid value '%s'\n", fields[1])
+				return
+			}
+		}
+		if fields[0] == "money" {
+			nf.moneyDecimals = n
+		} else {
+			nf.floatPrecision = n
+		}
+	default:
+		fmt.Fprintf(c.term, "Usage: numformat [off | grouping on|off | money <decimals>|off | float <digits>|off]\n")
+		return
+	}
+
+	money, float := "raw", "raw"
+	if nf.moneyDecimals >= 0 {
+		money = fmt.Sprintf("%d decimals", nf.moneyDecimals)
+	}
+	if nf.floatPrecision >= 0 {
+		float = fmt.Sprintf("%d digits", nf.floatPrecision)
+	}
+	fmt.Fprintf(c.term, "Number format: grouping %s, money %s, float %s\n", onOff(nf.grouping), money, float)
+}
+
+// onOff 将布尔值显示为 on/off
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
@@ -0,0 +1,110 @@
+package mssql
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormatGUID(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []byte
+		want string
+	}{
+		{
+			name: "mixed endian",
+			in: []byte{
+				0xFF, 0x19, 0x96, 0x6F, // 前三段按小端序存储
+				0x86, 0x8B,
+				0x11, 0xD0,
+				0xB4, 0x2D, 0x00, 0xC0, 0x4F, 0xC9, 0x64, 0xFF,
+			},
+			want: "6F9619FF-8B86-D011-B42D-00C04FC964FF",
+		},
+		{
+			name: "all zero",
+			in:   make([]byte, 16),
+			want: "00000000-0000-0000-0000-000000000000",
+		},
+		{
+			name: "nil",
+			in:   nil,
+			want: "0x",
+		},
+		{
+			name: "too short",
+			in:   []byte{0xFF, 0x19, 0x96},
+			want: "0xFF1996",
+		},
+		{
+			name: "too long",
+			in:   make([]byte, 17),
+			want: "0x0000000000000000000000000000000000",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatGUID(tt.in); got != tt.want {
+				t.Errorf("formatGUID(% X) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultTimeFormat(t *testing.T) {
+	ts := time.Date(2024, 3, 9, 14, 5, 7, 500_000_000, time.FixedZone("", 8*60*60))
+	tests := []struct {
+		name     string
+		typeName string
+		scale    int
+		want     string
+	}{
+		{name: "date", typeName: "DATE", scale: -1, want: "2024-03-09"},
+		{name: "time(7)", typeName: "TIME", scale: 7, want: "14:05:07.5000000"},
+		{name: "time(0)", typeName: "TIME", scale: 0, want: "14:05:07"},
+		{name: "time unknown scale", typeName: "TIME", scale: -1, want: "14:05:07.5000000"},
+		{name: "smalldatetime", typeName: "SMALLDATETIME", scale: -1, want: "2024-03-09 14:05:07"},
+		{name: "datetime", typeName: "DATETIME", scale: -1, want: "2024-03-09 14:05:07.500"},
+		{name: "datetime2(3)", typeName: "DATETIME2", scale: 3, want: "2024-03-09 14:05:07.500"},
+		{name: "datetime2(0)", typeName: "DATETIME2", scale: 0, want: "2024-03-09 14:05:07"},
+		{name: "datetime2(7)", typeName: "DATETIME2", scale: 7, want: "2024-03-09 14:05:07.5000000"},
+		{name: "datetimeoffset(2)", typeName: "DATETIMEOFFSET", scale: 2, want: "2024-03-09 14:05:07.50 +08:00"},
+		{name: "datetimeoffset(7)", typeName: "DATETIMEOFFSET", scale: 7, want: "2024-03-09 14:05:07.5000000 +08:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			display := newDisplaySettings()
+			if got := display.formatCell(ts, tt.typeName, tt.scale); got != tt.want {
+				t.Errorf("formatCell(%s, %d) = %q, want %q", tt.typeName, tt.scale, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimeScaleFromColumnType(t *testing.T) {
+	cols := []fakeColumn{
+		{name: "d", typeName: "DATE"},
+		{name: "dt", typeName: "DATETIME"},
+		{name: "dt2", typeName: "DATETIME2", precision: 23, scale: 3},
+		{name: "t", typeName: "TIME", precision: 16, scale: 7},
+		{name: "dto", typeName: "DATETIMEOFFSET", precision: 26, scale: 0},
+	}
+	var b displayBase
+	display := newDisplaySettings()
+	b.setDisplay(&display)
+	b.setTypes(fakeColumnTypes(t, cols...))
+
+	ts := time.Date(2024, 3, 9, 14, 5, 7, 500_000_000, time.UTC)
+	want := []string{
+		"2024-03-09",
+		"2024-03-09 14:05:07.500",
+		"2024-03-09 14:05:07.500",
+		"14:05:07.5000000",
+		"2024-03-09 14:05:07 +00:00",
+	}
+	for i, w := range want {
+		if got := b.cell(i, ts); got != w {
+			t.Errorf("column %s = %q, want %q", cols[i].name, got, w)
+		}
+	}
+}
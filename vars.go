@@ -0,0 +1,122 @@
+package mssql
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// scriptVar 一个 :setvar 定义的脚本变量，保留定义时的名称大小写用于显示
+type scriptVar struct {
+	name  string
+	value string
+}
+
+// varNamePattern 合法的脚本变量名
+var varNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// setVariable 定义或修改脚本变量，变量名不区分大小写
+func (c *CLI) setVariable(name, value string) {
+	if c.variables == nil {
+		c.variables = make(map[string]scriptVar)
+	}
+	c.variables[strings.ToLower(name)] = scriptVar{name: name, value: value}
+}
+
+// lookupVariable 查找 $(name) 的值：先查 :setvar 定义的变量，未定义时读取同名环境变量；
+// StrictVariables 打开时不读取环境变量，未定义的变量直接报错
+func (c *CLI) lookupVariable(name string) (string, error) {
+	if v, ok := c.variables[strings.ToLower(name)]; ok {
+		return v.value, nil
+	}
+	if !c.config.StrictVariables {
+		if value, ok := lookupEnv(name); ok {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("'%s' scripting variable not defined", name)
+}
+
+// expandVariables 将文本中的 $(name) 替换为变量的值，$\( 转义为字面的 $(；
+// 与 sqlcmd 一致，字符串和带引号的标识符中的引用也会替换，只有注释中的保持原样。
+// 替换后的值不会再次展开
+func (c *CLI) expandVariables(text string) (string, error) {
+	if !strings.Contains(text, "$(") && !strings.Contains(text, `$\(`) {
+		return text, nil
+	}
+	var b strings.Builder
+	var lexer sqlLexer
+	lines := strings.SplitAfter(text, "\n")
+	for _, line := range lines {
+		// 词法扫描把注释替换为空格，字符串保留引号，据此区分两者
+		code := lexer.scanComments(line)
+		for i := 0; i < len(line); i++ {
+			if line[i] != '$' || code[i] == ' ' {
+				b.WriteByte(line[i])
+				continue
+			}
+			rest := line[i:]
+			if strings.HasPrefix(rest, `$\(`) {
+				b.WriteString("$(")
+				i += 2
+				continue
+			}
+			end := strings.IndexByte(rest, ')')
+			if !strings.HasPrefix(rest, "$(") || end < 0 || !varNamePattern.MatchString(rest[2:end]) {
+				b.WriteByte('$')
+				continue
+			}
+			value, err := c.lookupVariable(rest[2:end])
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(value)
+			i += end
+		}
+	}
+	return b.String(), nil
+}
+
+// setVarCommand 处理 :setvar <name> [value] 命令；值可以用双引号括起，其中的 "" 表示一个双引号；
+// 省略值时删除该变量
+func (c *CLI) setVarCommand(arg string) {
+	name, value := arg, ""
+	if i := strings.IndexAny(arg, " \t"); i >= 0 {
+		name, value = arg[:i], strings.TrimSpace(arg[i+1:])
+	}
+	if !varNamePattern.MatchString(name) {
+		fmt.Fprintf(c.term, "Usage: :setvar <name> [value]\n")
+		return
+	}
+	if value == "" {
+		delete(c.variables, strings.ToLower(name))
+		return
+	}
+	if strings.HasPrefix(value, `"`) {
+		if len(value) < 2 || !strings.HasSuffix(value, `"`) {
+			fmt.Fprintf(c.term, "Unterminated quoted value for %s\n", name)
+			return
+		}
+		value = strings.ReplaceAll(value[1:len(value)-1], `""`, `"`)
+	}
+	c.setVariable(name, value)
+}
+
+// listVariables 处理 :listvar 命令，按名称列出所有脚本变量
+func (c *CLI) listVariables() {
+	vars := make([]scriptVar, 0, len(c.variables))
+	for _, v := range c.variables {
+		vars = append(vars, v)
+	}
+	sort.Slice(vars, func(i, j int) bool {
+		return strings.ToLower(vars[i].name) < strings.ToLower(vars[j].name)
+	})
+	if len(vars) == 0 {
+		fmt.Fprintf(c.term, "No scripting variables defined.\n")
+		return
+	}
+	for _, v := range vars {
+		fmt.Fprintf(c.term, "%s = \"%s\"\n", v.name, v.value)
+	}
+}
@@ -0,0 +1,131 @@
+package mssql
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExpandVariables(t *testing.T) {
+	tests := []struct {
+		name    string
+		vars    map[string]string
+		env     map[string]string
+		strict  bool
+		text    string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "defined variable",
+			vars: map[string]string{"DbName": "sales"},
+			text: "USE $(DbName)",
+			want: "USE sales",
+		},
+		{
+			name: "names are case insensitive",
+			vars: map[string]string{"DbName": "sales"},
+			text: "USE $(dbname)",
+			want: "USE sales",
+		},
+		{
+			name: "defined variable wins over the environment",
+			vars: map[string]string{"Region": "eu"},
+			env:  map[string]string{"Region": "us"},
+			text: "SELECT '$(Region)'",
+			want: "SELECT 'eu'",
+		},
+		{
+			name: "environment fallback",
+			env:  map[string]string{"Region": "us"},
+			text: "SELECT '$(Region)'",
+			want: "SELECT 'us'",
+		},
+		{
+			name:    "undefined variable",
+			env:     map[string]string{},
+			text:    "SELECT $(Missing)",
+			wantErr: "'Missing' scripting variable not defined",
+		},
+		{
+			name:    "strict ignores the environment",
+			env:     map[string]string{"Region": "us"},
+			strict:  true,
+			text:    "SELECT '$(Region)'",
+			wantErr: "'Region' scripting variable not defined",
+		},
+		{
+			name:   "strict still uses defined variables",
+			vars:   map[string]string{"Region": "eu"},
+			strict: true,
+			text:   "SELECT '$(Region)'",
+			want:   "SELECT 'eu'",
+		},
+		{
+			name: "escaped reference",
+			vars: map[string]string{"Region": "eu"},
+			text: `SELECT '$\(Region)', '$(Region)'`,
+			want: "SELECT '$(Region)', 'eu'",
+		},
+		{
+			name: "escape needs no definition",
+			env:  map[string]string{},
+			text: `PRINT '$\(Undefined)'`,
+			want: "PRINT '$(Undefined)'",
+		},
+		{
+			name: "inside string",
+			vars: map[string]string{"Name": "O'Brien"},
+			text: "SELECT N'$(Name)', [$(Name)], \"$(Name)\"",
+			want: "SELECT N'O'Brien', [O'Brien], \"O'Brien\"",
+		},
+		{
+			name: "inside line comment",
+			env:  map[string]string{},
+			text: "SELECT 1 -- $(Undefined)\nSELECT 2",
+			want: "SELECT 1 -- $(Undefined)\nSELECT 2",
+		},
+		{
+			name: "inside block comment across lines",
+			vars: map[string]string{"Region": "eu"},
+			text: "/* uses\n$(Undefined) */ SELECT '$(Region)'",
+			want: "/* uses\n$(Undefined) */ SELECT 'eu'",
+		},
+		{
+			name: "comment markers inside a string",
+			vars: map[string]string{"Region": "eu"},
+			text: "SELECT '-- $(Region)', '/* $(Region) */'",
+			want: "SELECT '-- eu', '/* eu */'",
+		},
+		{
+			name: "values are not expanded again",
+			vars: map[string]string{"A": "$(B)", "B": "b"},
+			text: "SELECT '$(A)'",
+			want: "SELECT '$(B)'",
+		},
+		{
+			name: "not a reference",
+			env:  map[string]string{},
+			text: "SELECT '$5', '$(not a name)', '$(unterminated'",
+			want: "SELECT '$5', '$(not a name)', '$(unterminated'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeEnv(t, tt.env)
+			c := NewCLIWithConfig(&testTerm{}, &Config{Host: "db1", Username: "sa", Password: "x", Variables: tt.vars, StrictVariables: tt.strict})
+			got, err := c.expandVariables(tt.text)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expandVariables() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expandVariables() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expandVariables() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package mssql
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// watchUsage watch 命令的用法
+const watchUsage = "Usage: watch [--no-clear] <seconds> <statement | !!>\n"
+
+// watchCommand 处理 watch [--no-clear] <seconds> <statement> 命令：每隔指定秒数重新执行语句，每次先清屏并输出
+// 带时间的标题，--no-clear 不清屏而是依次追加；!! 表示上一条执行的语句。
+// 单次执行失败时显示错误并继续，语句本身的耗时从等待时间中扣除，Ctrl+C 停止
+func (c *CLI) watchCommand(arg string) {
+	clear := true
+	if rest, ok := strings.CutPrefix(arg, "--no-clear"); ok {
+		clear = false
+		arg = strings.TrimSpace(rest)
+	}
+	secondsText, sqlStr, _ := strings.Cut(arg, " ")
+	seconds, err := strconv.ParseFloat(secondsText, 64)
+	sqlStr = strings.TrimSpace(sqlStr)
+	if err != nil || seconds <= 0 || sqlStr == "" {
+		fmt.Fprint(c.term, watchUsage)
+		return
+	}
+	if sqlStr == "!!" {
+		if c.lastStatement == "" {
+			fmt.Fprintf(c.term, "No previous statement to watch.\n")
+			return
+		}
+		sqlStr = c.lastStatement
+	}
+	c.lastStatement = sqlStr
+	interval := time.Duration(seconds * float64(time.Second))
+
+	sqlStr, err = c.expandVariables(sqlStr)
+	if err != nil {
+		c.printError(err)
+		return
+	}
+	vertical := c.expanded
+	if strings.HasSuffix(sqlStr, `\G`) {
+		sqlStr = strings.TrimSpace(strings.TrimSuffix(sqlStr, `\G`))
+		vertical = true
+	}
+	// 参数值只输入一次，每次执行使用相同的值
+	params, ok := c.bindParams(sqlStr)
+	if !ok {
+		return
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+	defer signal.Stop(sigs)
+
+	title := strings.TrimSpace(strings.SplitN(sqlStr, "\n", 2)[0])
+	if strings.Contains(sqlStr, "\n") {
+		title += " ..."
+	}
+	for {
+		startTime := time.Now()
+		if clear {
+			fmt.Fprintf(c.term, "\033[2J\033[H")
+		}
+		header := fmt.Sprintf("Every %ss: %s", strconv.FormatFloat(seconds, 'f', -1, 64), title)
+		fmt.Fprintf(c.out, "%s  %s\n\n", c.display.colorize(header, ansiBold), startTime.Format("2006-01-02 15:04:05"))
+
+		err := c.runStatement(sqlStr, startTime, vertical, params...)
+		c.flushOutput()
+		if err != nil && isConnectionError(err) {
+			fmt.Fprintf(c.term, "Connection lost. Reconnecting...\n")
+			if err := c.reconnect(); err != nil {
+				fmt.Fprintf(c.term, "Reconnect failed: %v\n\n", err)
+				return
+			}
+		}
+
+		wait := interval - time.Since(startTime)
+		if wait < 0 {
+			wait = 0
+		}
+		select {
+		case <-sigs:
+			fmt.Fprintf(c.term, "\nWatch stopped.\n\n")
+			return
+		case <-time.After(wait):
+		}
+	}
+}